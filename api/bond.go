@@ -26,11 +26,11 @@ type AddBondRequest struct {
 func AddBondHandler(c *gin.Context) {
 	var req AddBondRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddBond(req.Bridge, req.BondName, req.Slaves, req.BondMode, req.Lacp, req.OtherOptions); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddBond(c.Request.Context(), req.Bridge, req.BondName, req.Slaves, req.BondMode, req.Lacp, req.OtherOptions); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -54,11 +54,11 @@ type SetBondRequest struct {
 func SetBondHandler(c *gin.Context) {
 	var req SetBondRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetBond(req.BondName, req.BondMode, req.Lacp, req.OtherOptions); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetBond(c.Request.Context(), req.BondName, req.BondMode, req.Lacp, req.OtherOptions); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -79,12 +79,12 @@ type ShowBondRequest struct {
 func ShowBondHandler(c *gin.Context) {
 	var req ShowBondRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	bondShow, lacpShow, portInfo, err := service.ShowBond(req.BondName)
+	bondShow, lacpShow, portInfo, err := service.ShowBond(c.Request.Context(), req.BondName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"bondShow": bondShow, "lacpShow": lacpShow, "portInfo": portInfo})
@@ -99,9 +99,9 @@ func ShowBondHandler(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Router /api/ovs/bond/show [post]
 func ListBondsHandler(c *gin.Context) {
-	bonds, err := service.ListBonds()
+	bonds, err := service.ListBonds(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"bonds": bonds})
@@ -123,11 +123,11 @@ type DeleteBondRequest struct {
 func DeleteBondHandler(c *gin.Context) {
 	var req DeleteBondRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeleteBond(req.Bridge, req.BondName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeleteBond(c.Request.Context(), req.Bridge, req.BondName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
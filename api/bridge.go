@@ -15,9 +15,9 @@ import (
 // @Success 200 {object} map[string]interface{}
 // @Router /api/ovs/bridge/list [post]
 func ListBridgesHandler(c *gin.Context) {
-	bridges, err := service.ListBridges()
+	bridges, err := service.ListBridges(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"bridges": bridges})
@@ -39,11 +39,11 @@ type AddBridgeRequest struct {
 func AddBridgeHandler(c *gin.Context) {
 	var req AddBridgeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddBridge(req.Name); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddBridge(c.Request.Context(), req.Name); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -65,11 +65,11 @@ type DeleteBridgeRequest struct {
 func DeleteBridgeHandler(c *gin.Context) {
 	var req DeleteBridgeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeleteBridge(req.Name); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeleteBridge(c.Request.Context(), req.Name); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -92,11 +92,11 @@ type SetNetFlowRequest struct {
 func SetNetFlowHandler(c *gin.Context) {
 	var req SetNetFlowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetNetFlow(req.Bridge, req.Target, req.EngineID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetNetFlow(c.Request.Context(), req.Bridge, req.Target, req.EngineID); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -117,12 +117,12 @@ type GetNetFlowRequest struct {
 func GetNetFlowHandler(c *gin.Context) {
 	var req GetNetFlowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	config, err := service.GetNetFlow(req.BridgeName)
+	config, err := service.GetNetFlow(c.Request.Context(), req.BridgeName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"config": config})
@@ -148,11 +148,11 @@ type SetSFlowRequest struct {
 func SetSFlowHandler(c *gin.Context) {
 	var req SetSFlowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetSFlow(req.Bridge, req.Targets, req.Sampling, req.Header, req.Polling, req.Agent); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetSFlow(c.Request.Context(), req.Bridge, req.Targets, req.Sampling, req.Header, req.Polling, req.Agent); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -173,12 +173,12 @@ type GetSFlowRequest struct {
 func GetSFlowHandler(c *gin.Context) {
 	var req GetSFlowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	config, err := service.GetSFlow(req.BridgeName)
+	config, err := service.GetSFlow(c.Request.Context(), req.BridgeName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"config": config})
@@ -200,11 +200,11 @@ type SetStpRequest struct {
 func SetStpHandler(c *gin.Context) {
 	var req SetStpRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetStp(req.Bridge, req.Enable); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetStp(c.Request.Context(), req.Bridge, req.Enable); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -225,12 +225,12 @@ type GetStpRequest struct {
 func GetStpHandler(c *gin.Context) {
 	var req GetStpRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	config, err := service.GetStp(req.BridgeName)
+	config, err := service.GetStp(c.Request.Context(), req.BridgeName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"config": config})
@@ -254,11 +254,11 @@ type SetQosRequest struct {
 func SetQosHandler(c *gin.Context) {
 	var req SetQosRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetQos(req.PortName, req.Type, req.MaxRate, req.Queues); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetQos(c.Request.Context(), req.PortName, req.Type, req.MaxRate, req.Queues); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -266,7 +266,7 @@ func SetQosHandler(c *gin.Context) {
 
 // GetQosRequest 获取 QoS 配置请求结构体
 // @Summary 获取 QoS 配置
-// @Description 获取端口的 QoS 配置
+// @Description 获取端口实际的 QoS 配置：类型、other_config 以及各队列的 min-rate、max-rate、burst、priority、dscp
 // @Tags OVS-Bridge
 // @Accept json
 // @Produce json
@@ -280,12 +280,12 @@ type GetQosRequest struct {
 func GetQosHandler(c *gin.Context) {
 	var req GetQosRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	config, err := service.GetQos(req.BridgeName, req.PortName)
+	config, err := service.GetQos(c.Request.Context(), req.BridgeName, req.PortName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"config": config})
@@ -307,11 +307,11 @@ type SetRstpRequest struct {
 func SetRstpHandler(c *gin.Context) {
 	var req SetRstpRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetRstp(req.Bridge, req.Enable); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetRstp(c.Request.Context(), req.Bridge, req.Enable); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -332,12 +332,12 @@ type GetRstpRequest struct {
 func GetRstpHandler(c *gin.Context) {
 	var req GetRstpRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	config, err := service.GetRstp(req.BridgeName)
+	config, err := service.GetRstp(c.Request.Context(), req.BridgeName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"config": config})
@@ -362,11 +362,11 @@ type SetIpfixRequest struct {
 func SetIpfixHandler(c *gin.Context) {
 	var req SetIpfixRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetIpfix(req.Bridge, req.Targets, req.Sampling, req.ObsDomainID, req.ObsPointID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetIpfix(c.Request.Context(), req.Bridge, req.Targets, req.Sampling, req.ObsDomainID, req.ObsPointID); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -387,12 +387,12 @@ type GetIpfixRequest struct {
 func GetIpfixHandler(c *gin.Context) {
 	var req GetIpfixRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	config, err := service.GetIpfix(req.BridgeName)
+	config, err := service.GetIpfix(c.Request.Context(), req.BridgeName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"config": config})
@@ -413,13 +413,13 @@ type DumpFlowsRequest struct {
 func DumpFlowsHandler(c *gin.Context) {
 	var req DumpFlowsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	output, err := service.DumpFlows(req.Bridge)
+	flows, err := service.DumpFlows(c.Request.Context(), req.Bridge)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"output": output})
+	c.JSON(http.StatusOK, gin.H{"flows": flows})
 } 
\ No newline at end of file
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"ovs-manager/service"
+	"github.com/gin-gonic/gin"
+)
+
+// causeStatus 错误分类对应的 HTTP 状态码
+var causeStatus = map[service.ErrorCause]int{
+	service.CauseNotFound:         http.StatusNotFound,
+	service.CauseAlreadyExists:    http.StatusConflict,
+	service.CauseConflict:         http.StatusConflict,
+	service.CauseInvalidArgument:  http.StatusBadRequest,
+	service.CauseOVSDBUnreachable: http.StatusServiceUnavailable,
+	service.CauseTimeout:          http.StatusGatewayTimeout,
+}
+
+// ErrorResponse 统一的错误响应体
+// Error: 错误信息
+// Cause: 错误分类（not-found、already-exists、conflict、invalid-argument、ovsdb-unreachable、timeout、unknown）
+// Command/Args/ExitCode/Stderr: 外部命令失败时的详细信息
+// Completed: 超时时本次请求中已经执行成功的命令，便于判断操作执行到哪一步
+// Failed: 超时时被终止或执行失败的命令
+// Details: 附加的结构化信息，如流表的字段级校验错误、导入冲突报告
+type ErrorResponse struct {
+	Error     string      `json:"error"`
+	Cause     string      `json:"cause"`
+	Command   string      `json:"command,omitempty"`
+	Args      []string    `json:"args,omitempty"`
+	ExitCode  *int        `json:"exitCode,omitempty"`
+	Stderr    string      `json:"stderr,omitempty"`
+	Completed []string    `json:"completed,omitempty"`
+	Failed    string      `json:"failed,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// newErrorResponse 由 service 错误构造响应体
+func newErrorResponse(err error) ErrorResponse {
+	resp := ErrorResponse{Error: err.Error(), Cause: string(service.CauseOf(err))}
+	var ce *service.CommandError
+	if errors.As(err, &ce) {
+		code := ce.ExitCode
+		resp.Command = ce.Command
+		resp.Args = ce.Args
+		resp.ExitCode = &code
+		resp.Stderr = ce.Stderr
+	}
+	return resp
+}
+
+// causeHTTPStatus 错误分类对应的状态码，未分类的错误返回 500
+func causeHTTPStatus(cause service.ErrorCause) int {
+	if status, ok := causeStatus[cause]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// respondError 按错误分类返回 404/409/400/503/504，未分类的错误返回 500
+func respondError(c *gin.Context, err error) {
+	cause := service.CauseOf(err)
+	status := causeHTTPStatus(cause)
+	resp := newErrorResponse(err)
+	if v, ok := c.Get(commandLogKey); ok && cause == service.CauseTimeout {
+		log := v.(*service.CommandLog)
+		resp.Completed = log.Completed()
+		resp.Failed = log.Failed()
+	}
+	c.JSON(status, resp)
+}
+
+// respondErrorDetails 返回带 Details 的错误响应，状态码按 cause 映射，未分类的错误返回 500
+func respondErrorDetails(c *gin.Context, cause service.ErrorCause, msg string, details interface{}) {
+	c.JSON(causeHTTPStatus(cause), ErrorResponse{Error: msg, Cause: string(cause), Details: details})
+}
+
+// respondBindError 请求参数解析失败
+func respondBindError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error(), Cause: string(service.CauseInvalidArgument)})
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"io"
+	"ovs-manager/service"
+	"strings"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+
+// EventsPath 事件流路由；长连接，不设置请求超时，也不计入请求耗时
+const EventsPath = "/api/ovs/events"
+
+// eventKeepalive SSE 心跳间隔，防止代理因空闲断开连接
+const eventKeepalive = 15 * time.Second
+
+// EventsHandler OVSDB 变更事件流接口
+// @Summary 订阅 OVSDB 变更事件（SSE）
+// @Description 以 Server-Sent Events 推送网桥/端口增删、接口链路状态变化、bond 成员启用/禁用、镜像增删改事件，事件名为事件类型，data 为 JSON；连接建立后先推送 ready 事件。客户端消费过慢时连接会被关闭，EventSource 会自动重连
+// @Tags OVS-Events
+// @Produce text/event-stream
+// @Param bridge query string false "只接收这些网桥的事件，逗号分隔"
+// @Param table query string false "只接收这些表的事件（Bridge/Port/Interface/Mirror），逗号分隔"
+// @Success 200 {object} service.Event
+// @Router /api/ovs/events [get]
+func EventsHandler(c *gin.Context) {
+	filter := service.EventFilter{Bridges: queryList(c, "bridge"), Tables: queryList(c, "table")}
+	sub, err := service.SubscribeEvents(filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer sub.Close()
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ready", gin.H{"bridges": filter.Bridges, "tables": filter.Tables})
+	c.Writer.Flush()
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case ev, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(ev.Type, ev)
+			return true
+		case <-keepalive.C:
+			io.WriteString(w, ": keepalive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// queryList 读取可重复、逗号分隔的查询参数
+func queryList(c *gin.Context, key string) []string {
+	var res []string
+	for _, v := range c.QueryArray(key) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+	}
+	return res
+}
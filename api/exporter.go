@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"ovs-manager/service"
+	"github.com/gin-gonic/gin"
+)
+
+// DisableExporterRequest 关闭流量导出请求结构体
+// @Summary 关闭 NetFlow/sFlow/IPFIX
+// @Description 清空网桥的 netflow/sflow/ipfix 列并删除对应的导出行，未配置时不做修改；关闭 IPFIX 不影响 Flow_Sample_Collector_Set
+// @Tags OVS-Bridge
+// @Accept json
+// @Produce json
+// @Param data body DisableExporterRequest true "网桥名称"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/bridge/disable-netflow [post]
+// @Router /api/ovs/bridge/disable-sflow [post]
+// @Router /api/ovs/bridge/disable-ipfix [post]
+type DisableExporterRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+}
+func DisableNetFlowHandler(c *gin.Context) {
+	var req DisableExporterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DisableNetFlow(c.Request.Context(), req.Bridge); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+func DisableSFlowHandler(c *gin.Context) {
+	var req DisableExporterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DisableSFlow(c.Request.Context(), req.Bridge); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+func DisableIpfixHandler(c *gin.Context) {
+	var req DisableExporterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DisableIpfix(c.Request.Context(), req.Bridge); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// ListCollectorSetsRequest 查询按流 IPFIX 收集器集合请求结构体
+// @Summary 查询 Flow_Sample_Collector_Set
+// @Description 查询按流 IPFIX 采样的收集器集合及其 IPFIX 导出配置，bridge 为空时返回所有网桥的
+// @Tags OVS-Bridge
+// @Accept json
+// @Produce json
+// @Param data body ListCollectorSetsRequest true "网桥名称（可选）"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/bridge/collector-set/list [post]
+type ListCollectorSetsRequest struct {
+	Bridge string `json:"bridge"`
+}
+func ListCollectorSetsHandler(c *gin.Context) {
+	var req ListCollectorSetsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+	}
+	sets, err := service.ListCollectorSets(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"collectorSets": sets})
+}
+
+// SetCollectorSetRequest 设置按流 IPFIX 收集器集合请求结构体
+// @Summary 设置 Flow_Sample_Collector_Set
+// @Description 创建或原地修改网桥上指定 ID 的收集器集合及其 IPFIX 导出目标；流表通过 sample(probability=N,collector_set_id=ID,...) 动作引用
+// @Tags OVS-Bridge
+// @Accept json
+// @Produce json
+// @Param data body SetCollectorSetRequest true "网桥、ID、IPFIX 目标等"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/bridge/collector-set/set [post]
+type SetCollectorSetRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	ID int `json:"id"`
+	Targets []string `json:"targets" binding:"required"`
+	ObsDomainID int `json:"obsDomainID"`
+	ObsPointID int `json:"obsPointID"`
+	CacheActiveTimeout int `json:"cacheActiveTimeout"`
+	CacheMaxFlows int `json:"cacheMaxFlows"`
+	OtherConfig map[string]string `json:"otherConfig"`
+}
+func SetCollectorSetHandler(c *gin.Context) {
+	var req SetCollectorSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	spec := service.CollectorSetSpec{
+		Targets: req.Targets,
+		ObsDomainID: req.ObsDomainID,
+		ObsPointID: req.ObsPointID,
+		CacheActiveTimeout: req.CacheActiveTimeout,
+		CacheMaxFlows: req.CacheMaxFlows,
+		OtherConfig: req.OtherConfig,
+	}
+	if err := service.SetCollectorSet(c.Request.Context(), req.Bridge, req.ID, spec); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// DeleteCollectorSetRequest 删除按流 IPFIX 收集器集合请求结构体
+// @Summary 删除 Flow_Sample_Collector_Set
+// @Description 删除网桥上指定 ID 的收集器集合，其 IPFIX 行随之回收
+// @Tags OVS-Bridge
+// @Accept json
+// @Produce json
+// @Param data body DeleteCollectorSetRequest true "网桥、ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/bridge/collector-set/delete [post]
+type DeleteCollectorSetRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	ID int `json:"id"`
+}
+func DeleteCollectorSetHandler(c *gin.Context) {
+	var req DeleteCollectorSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DeleteCollectorSet(c.Request.Context(), req.Bridge, req.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
@@ -1,40 +1,62 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"time"
 	"ovs-manager/service"
 	"github.com/gin-gonic/gin"
 )
 
 // ListFlowsV2Handler 查询流表规则接口
 // @Summary 查询流表规则
-// @Description 查询指定 bridge 的所有流表规则
+// @Description 查询指定 bridge 的流表规则，返回解析后的结构；可按 table、cookie/cookieMask、优先级范围、匹配字段过滤，按 packets/bytes/priority 排序（默认降序）
 // @Tags OVS-Flow
 // @Accept json
 // @Produce json
-// @Param data body ListFlowsV2Request true "网桥名称"
+// @Param data body ListFlowsV2Request true "网桥名称及过滤条件"
 // @Success 200 {object} map[string]interface{}
 // @Router /api/ovs/flow/list-v2 [post]
 type ListFlowsV2Request struct {
 	Bridge string `json:"bridge" binding:"required"`
+	Table *int `json:"table"`
+	Cookie *service.FlowCookie `json:"cookie"`
+	CookieMask *service.FlowCookie `json:"cookieMask"`
+	PriorityMin *int `json:"priorityMin"`
+	PriorityMax *int `json:"priorityMax"`
+	MatchField string `json:"matchField"`
+	MatchValue string `json:"matchValue"`
+	SortBy string `json:"sortBy" binding:"omitempty,oneof=packets bytes priority"`
+	Ascending bool `json:"ascending"`
 }
 func ListFlowsV2Handler(c *gin.Context) {
 	var req ListFlowsV2Request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	output, err := service.ListFlowsV2(req.Bridge)
+	filter := service.FlowFilter{
+		Table: req.Table,
+		Cookie: req.Cookie,
+		CookieMask: req.CookieMask,
+		PriorityMin: req.PriorityMin,
+		PriorityMax: req.PriorityMax,
+		MatchField: req.MatchField,
+		MatchValue: req.MatchValue,
+		SortBy: req.SortBy,
+		Ascending: req.Ascending,
+	}
+	flows, err := service.ListFlowsV2(c.Request.Context(), req.Bridge, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"output": output})
+	c.JSON(http.StatusOK, gin.H{"flows": flows})
 }
 
 // AddFlowV2Request 添加流表规则请求结构体
 // @Summary 添加流表规则
-// @Description 添加流表规则，flow 字符串为完整表达式
+// @Description 添加流表规则，flow 字符串为完整表达式；安装前先校验，校验失败返回 400，字段级错误在 details 中，dryRun 为 true 时只校验不安装
 // @Tags OVS-Flow
 // @Accept json
 // @Produce json
@@ -44,20 +66,61 @@ func ListFlowsV2Handler(c *gin.Context) {
 type AddFlowV2Request struct {
 	Bridge string `json:"bridge" binding:"required"`
 	Flow string `json:"flow" binding:"required"`
+	DryRun bool `json:"dryRun"`
 }
 func AddFlowV2Handler(c *gin.Context) {
 	var req AddFlowV2Request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddFlowV2(req.Bridge, req.Flow); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	errs, err := service.ValidateFlow(c.Request.Context(), req.Bridge, req.Flow)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if len(errs) > 0 {
+		respondErrorDetails(c, service.CauseInvalidArgument, "invalid flow", errs)
+		return
+	}
+	if req.DryRun {
+		c.JSON(http.StatusOK, gin.H{"message": "valid", "dryRun": true})
+		return
+	}
+	if err := service.AddFlowV2(c.Request.Context(), req.Bridge, req.Flow); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
 }
 
+// ValidateFlowRequest 校验流表规则请求结构体
+// @Summary 校验流表规则
+// @Description 校验流表表达式的语法、表号、优先级、引用端口（端口名或 ofport）及协议前置条件（如 ip 字段需要 dl_type），不做任何安装
+// @Tags OVS-Flow
+// @Accept json
+// @Produce json
+// @Param data body ValidateFlowRequest true "网桥名称和流表表达式"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/flow/validate [post]
+type ValidateFlowRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	Flow string `json:"flow" binding:"required"`
+}
+func ValidateFlowHandler(c *gin.Context) {
+	var req ValidateFlowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	errs, err := service.ValidateFlow(c.Request.Context(), req.Bridge, req.Flow)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": len(errs) == 0, "errors": errs})
+}
+
 // DeleteFlowV2Request 删除流表规则请求结构体
 // @Summary 删除流表规则
 // @Description 删除流表规则，支持全删和条件删
@@ -74,12 +137,245 @@ type DeleteFlowV2Request struct {
 func DeleteFlowV2Handler(c *gin.Context) {
 	var req DeleteFlowV2Request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeleteFlowV2(req.Bridge, req.Match); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeleteFlowV2(c.Request.Context(), req.Bridge, req.Match); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
-} 
\ No newline at end of file
+} 
+
+// ReplaceFlowsRequest 声明式同步流表请求结构体
+// @Summary 声明式同步流表
+// @Description 传入网桥的期望流表列表，与已安装流表比较后返回 added/removed/modified 差异，并通过 OpenFlow bundle 原子下发，只变更有差异的流表；指定 cookie（可带 cookieMask）时只管理该 cookie 范围内的流表，dryRun 为 true 时只返回差异
+// @Tags OVS-Flow
+// @Accept json
+// @Produce json
+// @Param data body ReplaceFlowsRequest true "网桥名称和期望流表"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/flow/replace [post]
+type ReplaceFlowsRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	Flows []string `json:"flows"`
+	Cookie *service.FlowCookie `json:"cookie"`
+	CookieMask *service.FlowCookie `json:"cookieMask"`
+	DryRun bool `json:"dryRun"`
+}
+func ReplaceFlowsHandler(c *gin.Context) {
+	var req ReplaceFlowsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	opts := service.FlowSyncOptions{Cookie: req.Cookie, CookieMask: req.CookieMask, DryRun: req.DryRun}
+	diff, err := service.ReplaceFlows(c.Request.Context(), req.Bridge, req.Flows, opts)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"diff": diff, "dryRun": req.DryRun})
+}
+
+// TraceFlowRequest 报文跟踪请求结构体
+// @Summary 报文跟踪
+// @Description 以 ovs-appctl ofproto/trace 跟踪报文在流表中的处理过程；inPort 为端口名或 ofport，fields 为 ovs-ofctl 匹配语法的报文字段（如 tcp,nw_dst=10.0.0.2,tp_dst=80），packet 为十六进制以太网帧，二者互斥；返回经过的流表、命中的流表项及动作、经 patch 端口进入的网桥、最终 datapath 动作和丢包原因
+// @Tags OVS-Flow
+// @Accept json
+// @Produce json
+// @Param data body TraceFlowRequest true "网桥、入端口及报文"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/flow/trace [post]
+type TraceFlowRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	InPort string `json:"inPort" binding:"required"`
+	Fields string `json:"fields"`
+	Packet string `json:"packet"`
+}
+func TraceFlowHandler(c *gin.Context) {
+	var req TraceFlowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	trace, err := service.TraceFlow(c.Request.Context(), req.Bridge, service.TracePacket{InPort: req.InPort, Fields: req.Fields, Packet: req.Packet})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"trace": trace})
+}
+
+// LintFlowsRequest 流表检查请求结构体
+// @Summary 流表检查
+// @Description 对 bridge 上的流表做静态分析：被同表更高优先级流表完全覆盖（shadowed）、重复（duplicate）、所在表无法从表 0 经 resubmit/goto_table 到达（unreachable_table）、引用不存在的端口（missing_port）、存活超过 staleAfter 秒仍为零报文（stale，默认 86400，0 表示不检查）
+// @Tags OVS-Flow
+// @Accept json
+// @Produce json
+// @Param data body LintFlowsRequest true "网桥名称及陈旧阈值"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/flow/lint [post]
+type LintFlowsRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	StaleAfter *int `json:"staleAfter" binding:"omitempty,min=0"`
+}
+func LintFlowsHandler(c *gin.Context) {
+	var req LintFlowsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	opts := service.FlowLintOptions{StaleAfter: service.DefaultFlowStaleAfter}
+	if req.StaleAfter != nil {
+		opts.StaleAfter = time.Duration(*req.StaleAfter) * time.Second
+	}
+	report, err := service.LintFlows(c.Request.Context(), req.Bridge, opts)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"lint": report})
+}
+
+// FlowStoreRequest 流表存储请求结构体
+// @Summary 流表存储
+// @Description 需以 -flow-store 启动。list 返回经 add-v2/replace 记录的流表（bridge 为空时返回全部网桥）；drift 比较存储与已安装流表，返回缺失、被修改及带 ovs-manager cookie 但未存储的流表；replay 立即重新下发缺失和被修改的流表
+// @Tags OVS-Flow
+// @Accept json
+// @Produce json
+// @Param data body FlowStoreRequest true "网桥名称"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/flow/store/list [post]
+// @Router /api/ovs/flow/store/drift [post]
+// @Router /api/ovs/flow/store/replay [post]
+type FlowStoreRequest struct {
+	Bridge string `json:"bridge"`
+}
+func ListStoredFlowsHandler(c *gin.Context) {
+	var req FlowStoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	flows, err := service.ListStoredFlows(req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bridges": flows})
+}
+
+// FlowStoreBridgeRequest 按网桥操作流表存储请求结构体
+type FlowStoreBridgeRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+}
+func FlowStoreDriftHandler(c *gin.Context) {
+	var req FlowStoreBridgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	drift, err := service.FlowStoreDrift(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"drift": drift})
+}
+
+func ReplayFlowStoreHandler(c *gin.Context) {
+	var req FlowStoreBridgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	drift, err := service.ReplayFlowStore(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "drift": drift})
+}
+
+// ExportFlowsRequest 导出流表请求结构体
+// @Summary 导出流表
+// @Description 导出指定 bridge 的流表、组表和计量器，ofport 换算为端口名；format 为 json（默认）时返回 bundle 对象，为 ofctl 时返回按 # [meters]、# [groups]、# [flows] 分节的纯文本，各节分别为 ovs-ofctl add-meter/add-group/add-flow 语法，需按分节拆分后才能交给 ovs-ofctl。网桥不支持 OpenFlow13 时只导出流表
+// @Tags OVS-Flow
+// @Accept json
+// @Produce json,plain
+// @Param data body ExportFlowsRequest true "网桥名称和导出格式"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/flow/export [post]
+type ExportFlowsRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	Format string `json:"format" binding:"omitempty,oneof=json ofctl"`
+}
+func ExportFlowsHandler(c *gin.Context) {
+	var req ExportFlowsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	bundle, err := service.ExportFlows(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if req.Format == "ofctl" {
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(bundle.Text()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bundle": bundle})
+}
+
+// ImportFlowsRequest 导入流表请求结构体
+// @Summary 导入流表
+// @Description 将导出的内容导入指定 bridge：format 为 json（默认）时读取 bundle，为 ofctl 时读取 text（也接受普通 add-flows 文件）。端口名先按 portMap 映射再换算为目标网桥的 ofport；端口不存在、校验失败或与已有的同 ID 组表/计量器、同匹配流表内容不同（未指定 overwrite）均视为冲突，有冲突时返回 409（报告在 details 中）且不下发任何内容；下发中途失败时撤销已下发的计量器和组表；dryRun 为 true 时只返回报告
+// @Tags OVS-Flow
+// @Accept json
+// @Produce json
+// @Param data body ImportFlowsRequest true "网桥名称和导入内容"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/flow/import [post]
+type ImportFlowsRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	Format string `json:"format" binding:"omitempty,oneof=json ofctl"`
+	Bundle *service.FlowBundle `json:"bundle"`
+	Text string `json:"text"`
+	PortMap map[string]string `json:"portMap"`
+	Overwrite bool `json:"overwrite"`
+	DryRun bool `json:"dryRun"`
+}
+func ImportFlowsHandler(c *gin.Context) {
+	var req ImportFlowsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	bundle := req.Bundle
+	if req.Format == "ofctl" {
+		parsed, err := service.ParseFlowBundleText(req.Text)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		bundle = parsed
+	}
+	if bundle == nil {
+		respondBindError(c, errors.New("bundle is required for json format"))
+		return
+	}
+	report, err := service.ImportFlows(c.Request.Context(), req.Bridge, bundle, service.FlowImportOptions{
+		PortMap: req.PortMap, Overwrite: req.Overwrite, DryRun: req.DryRun,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if len(report.Conflicts) > 0 {
+		respondErrorDetails(c, service.CauseConflict, "import conflicts", report)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "import": report})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+	"ovs-manager/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GroupRequest 新增/修改组表请求结构体
+// @Summary 新增/修改组表
+// @Description 以 OpenFlow13 新增（add）或整体替换（mod）组表项；type 为 all、select、indirect 或 fast_failover，bucket 的 weight 仅用于 select，watchPort/watchGroup 仅用于 fast_failover；watchPort 和 output 引用的端口必须存在
+// @Tags OVS-Group
+// @Accept json
+// @Produce json
+// @Param data body GroupRequest true "网桥及组表项"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/group/add [post]
+// @Router /api/ovs/group/mod [post]
+type GroupRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	service.Group
+}
+func AddGroupHandler(c *gin.Context) {
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.AddGroup(c.Request.Context(), req.Bridge, req.Group); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+func ModGroupHandler(c *gin.Context) {
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.ModGroup(c.Request.Context(), req.Bridge, req.Group); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// DeleteGroupRequest 删除组表请求结构体
+// @Summary 删除组表
+// @Description 删除组表项；仍被流表（group:N 动作）或其它组引用时返回 409，需先删除引用方
+// @Tags OVS-Group
+// @Accept json
+// @Produce json
+// @Param data body DeleteGroupRequest true "网桥及组 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/group/delete [post]
+type DeleteGroupRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	ID *int `json:"id" binding:"required"`
+}
+func DeleteGroupHandler(c *gin.Context) {
+	var req DeleteGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DeleteGroup(c.Request.Context(), req.Bridge, *req.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// BridgeGroupRequest 按网桥查询组表请求结构体
+// @Summary 查询组表配置/统计
+// @Description list 返回 dump-groups 解析后的组表项及桶，stats 返回 dump-group-stats 解析后的引用计数、报文/字节计数及各桶统计
+// @Tags OVS-Group
+// @Accept json
+// @Produce json
+// @Param data body BridgeGroupRequest true "网桥"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/group/list [post]
+// @Router /api/ovs/group/stats [post]
+type BridgeGroupRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+}
+func ListGroupsHandler(c *gin.Context) {
+	var req BridgeGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	groups, err := service.ListGroups(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+func GroupStatsHandler(c *gin.Context) {
+	var req BridgeGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	stats, err := service.GetGroupStats(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
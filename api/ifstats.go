@@ -0,0 +1,57 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ovs-manager/service"
+)
+
+// GetInterfaceStatsHandler 接口状态与统计接口
+// @Summary 查询接口状态与统计计数
+// @Description 返回接口的 admin_state、link_state、link_speed、duplex、mtu、mac_in_use、ofport、error 以及完整的 statistics 计数；bond 端口需使用成员接口名
+// @Tags OVS-Port
+// @Accept json
+// @Produce json
+// @Param data body InterfaceStatsRequest true "接口名"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/port/stats [post]
+type InterfaceStatsRequest struct {
+	PortName string `json:"portName" binding:"required"`
+}
+
+func GetInterfaceStatsHandler(c *gin.Context) {
+	var req InterfaceStatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	stats, err := service.GetInterfaceStats(c.Request.Context(), req.PortName)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// ListBridgeInterfaceStatsHandler 网桥接口状态与统计批量接口
+// @Summary 批量查询网桥上所有接口的状态与统计计数
+// @Description 一次查询返回网桥上所有端口的接口（含 bond 成员）状态和 statistics 计数
+// @Tags OVS-Port
+// @Accept json
+// @Produce json
+// @Param data body ListPortsRequest true "交换机名称"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/bridge/port-stats [post]
+func ListBridgeInterfaceStatsHandler(c *gin.Context) {
+	var req ListPortsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	stats, err := service.ListBridgeInterfaceStats(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"interfaces": stats})
+}
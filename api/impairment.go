@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"ovs-manager/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SetImpairmentHandler 设置端口网络损伤接口
+// @Summary 设置端口网络损伤
+// @Description 通过 linux-netem QoS 为端口注入时延、抖动、丢包、重复和乱序，替换端口原有 QoS；百分比取值 0-100，乱序和抖动需要 latencyMs > 0
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body SetImpairmentRequest true "端口和 netem 参数"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/port/impairment [post]
+type SetImpairmentRequest struct {
+	PortName string `json:"portName" binding:"required"`
+	service.Impairment
+}
+func SetImpairmentHandler(c *gin.Context) {
+	var req SetImpairmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.SetPortImpairment(c.Request.Context(), req.PortName, req.Impairment); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// ImpairmentQuery 端口网络损伤查询参数
+// @Summary 查询端口网络损伤
+// @Description 查询端口当前的 netem 参数，端口 QoS 不是 linux-netem 时 configured 为 false；OVS 不支持、由 tc 补充的参数（小数 loss、duplicate、reorder）在接口 qdisc 上丢失时由 drifted 列出对应接口，查询本身不修改配置
+// @Tags OVS-QoS
+// @Produce json
+// @Param portName query string true "端口名"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/port/impairment [get]
+type ImpairmentQuery struct {
+	PortName string `form:"portName" binding:"required"`
+}
+func GetImpairmentHandler(c *gin.Context) {
+	var req ImpairmentQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	impairment, err := service.GetPortImpairment(c.Request.Context(), req.PortName)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"impairment": impairment})
+}
+
+// ReapplyImpairmentHandler 重新下发端口网络损伤接口
+// @Summary 重新下发端口网络损伤
+// @Description 将端口 QoS 中记录、由 tc 补充的 netem 参数重新下发到参数已丢失的接口，reapplied 列出重新下发的接口；端口未配置 netem 时返回 404
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body ReapplyImpairmentRequest true "端口名"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/port/impairment/reapply [post]
+type ReapplyImpairmentRequest struct {
+	PortName string `json:"portName" binding:"required"`
+}
+func ReapplyImpairmentHandler(c *gin.Context) {
+	var req ReapplyImpairmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	reapplied, err := service.ReapplyPortImpairment(c.Request.Context(), req.PortName)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reapplied": reapplied})
+}
+
+// ClearImpairmentHandler 清除端口网络损伤接口
+// @Summary 清除端口网络损伤
+// @Description 移除端口上的 linux-netem QoS 并回收不再被引用的 QoS 行；端口未配置 netem 时不做修改
+// @Tags OVS-QoS
+// @Produce json
+// @Param portName query string true "端口名"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/port/impairment [delete]
+func ClearImpairmentHandler(c *gin.Context) {
+	var req ImpairmentQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.ClearPortImpairment(c.Request.Context(), req.PortName); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"ovs-manager/metrics"
+	"ovs-manager/service"
+	"strconv"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+
+// requestDuration API 请求耗时，按方法、路由模板和状态码区分
+var requestDuration = metrics.NewHistogramVec("ovs_manager_http_request_duration_seconds", "API 请求耗时", nil, "method", "route", "status")
+
+// RequestMetrics 记录每个已匹配路由的请求耗时，未匹配的路径（404）不记录，避免标签基数无限增长；
+// 事件流长连接的持续时间不是请求耗时，同样不记录
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" || route == EventsPath {
+			return
+		}
+		requestDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// MetricsHandler Prometheus 指标接口
+// @Summary Prometheus 指标
+// @Description 以 Prometheus 文本格式返回接口计数与链路状态、bond 成员状态、各流表流条目数、datapath hit/missed/lost、镜像统计，以及 ovs-manager 自身的 API 耗时和外部命令失败计数；OVS 指标按 -metrics-interval 缓存
+// @Tags OVS-Metrics
+// @Produce plain
+// @Success 200 {string} string "Prometheus 文本格式指标"
+// @Router /metrics [get]
+func MetricsHandler(c *gin.Context) {
+	families := append(service.Metrics(c.Request.Context()), requestDuration.Collect())
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", metrics.ContentType)
+	metrics.Write(c.Writer, families)
+}
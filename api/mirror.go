@@ -28,11 +28,11 @@ type AddMirrorRequest struct {
 func AddMirrorHandler(c *gin.Context) {
 	var req AddMirrorRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddMirror(req.Bridge, req.Name, req.SelectSrcPorts, req.SelectDstPorts, req.SelectVlan, req.OutputPort, req.OutputVlan, req.SelectAll); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddMirror(c.Request.Context(), req.Bridge, req.Name, req.SelectSrcPorts, req.SelectDstPorts, req.SelectVlan, req.OutputPort, req.OutputVlan, req.SelectAll); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -54,11 +54,51 @@ type DeleteMirrorRequest struct {
 func DeleteMirrorHandler(c *gin.Context) {
 	var req DeleteMirrorRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeleteMirror(req.Bridge, req.Name); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeleteMirror(c.Request.Context(), req.Bridge, req.Name); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// UpdateMirrorRequest 修改端口镜像请求结构体
+// @Summary 修改端口镜像
+// @Description 原地修改已有端口镜像的选择条件和输出，未传的字段保持不变；传空数组清空端口/VLAN 选择，outputPort 传空字符串或 outputVlan 传 0 清空输出
+// @Tags OVS-Mirror
+// @Accept json
+// @Produce json
+// @Param data body UpdateMirrorRequest true "端口镜像参数"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/mirror/update [post]
+type UpdateMirrorRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	Name string `json:"name" binding:"required"`
+	SelectAll *bool `json:"selectAll"`
+	SelectSrcPorts *[]string `json:"selectSrcPorts"`
+	SelectDstPorts *[]string `json:"selectDstPorts"`
+	SelectVlan *[]int `json:"selectVlan"`
+	OutputPort *string `json:"outputPort"`
+	OutputVlan *int `json:"outputVlan"`
+}
+func UpdateMirrorHandler(c *gin.Context) {
+	var req UpdateMirrorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	update := service.MirrorUpdate{
+		SelectAll: req.SelectAll,
+		SelectSrcPorts: req.SelectSrcPorts,
+		SelectDstPorts: req.SelectDstPorts,
+		SelectVlan: req.SelectVlan,
+		OutputPort: req.OutputPort,
+		OutputVlan: req.OutputVlan,
+	}
+	if err := service.UpdateMirror(c.Request.Context(), req.Bridge, req.Name, update); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -66,7 +106,7 @@ func DeleteMirrorHandler(c *gin.Context) {
 
 // ListMirrorsRequest 查询端口镜像请求结构体
 // @Summary 查询端口镜像
-// @Description 查询端口镜像
+// @Description 查询指定网桥上的端口镜像：名称、select_all、源/目的端口名、select_vlan、输出端口或输出 VLAN 以及 tx_packets/tx_bytes 统计
 // @Tags OVS-Mirror
 // @Accept json
 // @Produce json
@@ -79,13 +119,13 @@ type ListMirrorsRequest struct {
 func ListMirrorsHandler(c *gin.Context) {
 	var req ListMirrorsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	output, err := service.ListMirrors(req.Bridge)
+	mirrors, err := service.ListMirrors(c.Request.Context(), req.Bridge)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"output": output})
+	c.JSON(http.StatusOK, gin.H{"mirrors": mirrors})
 } 
\ No newline at end of file
@@ -21,11 +21,11 @@ type CreateNetnsRequest struct {
 func CreateNetnsHandler(c *gin.Context) {
 	var req CreateNetnsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.CreateNetns(req.Name); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.CreateNetns(c.Request.Context(), req.Name); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -46,11 +46,11 @@ type DeleteNetnsRequest struct {
 func DeleteNetnsHandler(c *gin.Context) {
 	var req DeleteNetnsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeleteNetns(req.Name); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeleteNetns(c.Request.Context(), req.Name); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -65,9 +65,9 @@ func DeleteNetnsHandler(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Router /api/netns/list [post]
 func ListNetnsHandler(c *gin.Context) {
-	netns, err := service.ListNetns()
+	netns, err := service.ListNetns(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"netns": netns})
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"net/http"
+	"ovs-manager/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SetIngressPolicingRequest 设置入方向限速请求结构体
+// @Summary 设置入方向限速
+// @Description 设置接口的 ingress_policing_rate（kbps）和 ingress_policing_burst（kb），rate 为 0 时关闭限速
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body SetIngressPolicingRequest true "接口、速率、突发"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/port/ingress-policing/set [post]
+type SetIngressPolicingRequest struct {
+	Interface string `json:"interface" binding:"required"`
+	Rate int `json:"rate"`
+	Burst int `json:"burst"`
+}
+func SetIngressPolicingHandler(c *gin.Context) {
+	var req SetIngressPolicingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.SetIngressPolicing(c.Request.Context(), req.Interface, req.Rate, req.Burst); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// GetIngressPolicingRequest 查询入方向限速请求结构体
+// @Summary 查询入方向限速
+// @Description 查询接口的 ingress_policing_rate/ingress_policing_burst
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body GetIngressPolicingRequest true "接口"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/port/ingress-policing/get [post]
+type GetIngressPolicingRequest struct {
+	Interface string `json:"interface" binding:"required"`
+}
+func GetIngressPolicingHandler(c *gin.Context) {
+	var req GetIngressPolicingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	policing, err := service.GetIngressPolicing(c.Request.Context(), req.Interface)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policing": policing})
+}
+
+// MeterRequest 新增/修改计量器请求结构体
+// @Summary 新增/修改计量器
+// @Description 以 OpenFlow13 新增（add）或修改（mod）计量器；unit 为 kbps 或 pktps，band type 为 drop 或 dscp_remark；网桥显式配置的 protocols 不含 OpenFlow13 时返回 400
+// @Tags OVS-Meter
+// @Accept json
+// @Produce json
+// @Param data body MeterRequest true "网桥及计量器"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/meter/add [post]
+// @Router /api/ovs/meter/mod [post]
+type MeterRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	service.Meter
+}
+func AddMeterHandler(c *gin.Context) {
+	var req MeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.AddMeter(c.Request.Context(), req.Bridge, req.Meter); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+func ModMeterHandler(c *gin.Context) {
+	var req MeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.ModMeter(c.Request.Context(), req.Bridge, req.Meter); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// DeleteMeterRequest 删除计量器请求结构体
+// @Summary 删除计量器
+// @Description 删除计量器，引用它的流表会被交换机一并删除
+// @Tags OVS-Meter
+// @Accept json
+// @Produce json
+// @Param data body DeleteMeterRequest true "网桥及计量器 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/meter/delete [post]
+type DeleteMeterRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	ID int `json:"id" binding:"required"`
+}
+func DeleteMeterHandler(c *gin.Context) {
+	var req DeleteMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DeleteMeter(c.Request.Context(), req.Bridge, req.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// BridgeMeterRequest 按网桥查询计量器请求结构体
+// @Summary 查询计量器配置/统计
+// @Description list 返回 dump-meters 解析后的计量器配置，stats 返回 meter-stats 解析后的 flow_count、报文/字节计数及各带统计
+// @Tags OVS-Meter
+// @Accept json
+// @Produce json
+// @Param data body BridgeMeterRequest true "网桥"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/meter/list [post]
+// @Router /api/ovs/meter/stats [post]
+type BridgeMeterRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+}
+func ListMetersHandler(c *gin.Context) {
+	var req BridgeMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	meters, err := service.ListMeters(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"meters": meters})
+}
+
+func MeterStatsHandler(c *gin.Context) {
+	var req BridgeMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	stats, err := service.GetMeterStats(c.Request.Context(), req.Bridge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// AddMeteredFlowRequest 添加限速流表请求结构体
+// @Summary 添加限速流表
+// @Description 在流表动作最前面插入 meter:N 指令后校验并以 OpenFlow13 下发，使匹配的流量经过计量器限速
+// @Tags OVS-Meter
+// @Accept json
+// @Produce json
+// @Param data body AddMeteredFlowRequest true "网桥、计量器 ID、流表"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/meter/add-flow [post]
+type AddMeteredFlowRequest struct {
+	Bridge string `json:"bridge" binding:"required"`
+	MeterID int `json:"meterId" binding:"required"`
+	Flow string `json:"flow" binding:"required"`
+}
+func AddMeteredFlowHandler(c *gin.Context) {
+	var req AddMeteredFlowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.AddMeteredFlow(c.Request.Context(), req.Bridge, req.MeterID, req.Flow); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
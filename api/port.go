@@ -22,12 +22,12 @@ type ListPortsRequest struct {
 func ListPortsHandler(c *gin.Context) {
 	var req ListPortsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	ports, err := service.ListPorts(req.Bridge)
+	ports, err := service.ListPorts(c.Request.Context(), req.Bridge)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ports": ports})
@@ -52,11 +52,11 @@ type AddPortRequest struct {
 func AddPortHandler(c *gin.Context) {
 	var req AddPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddPort(req.Bridge, req.PortName, req.Type, req.NicName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddPort(c.Request.Context(), req.Bridge, req.PortName, req.Type, req.NicName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -80,11 +80,11 @@ type AddNormalPortRequest struct {
 func AddNormalPortHandler(c *gin.Context) {
 	var req AddNormalPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddNormalPort(req.Bridge, req.PortName, req.NicName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddNormalPort(c.Request.Context(), req.Bridge, req.PortName, req.NicName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -107,11 +107,11 @@ type AddInternalPortRequest struct {
 func AddInternalPortHandler(c *gin.Context) {
 	var req AddInternalPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddInternalPort(req.Bridge, req.PortName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddInternalPort(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -134,11 +134,11 @@ type AddGrePortRequest struct {
 func AddGrePortHandler(c *gin.Context) {
 	var req AddGrePortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddGrePort(req.Bridge, req.PortName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddGrePort(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -161,11 +161,11 @@ type AddVxlanPortRequest struct {
 func AddVxlanPortHandler(c *gin.Context) {
 	var req AddVxlanPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddVxlanPort(req.Bridge, req.PortName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddVxlanPort(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -188,11 +188,11 @@ type AddBondPortRequest struct {
 func AddBondPortHandler(c *gin.Context) {
 	var req AddBondPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddBondPort(req.Bridge, req.PortName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddBondPort(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -215,11 +215,11 @@ type DeletePortRequest struct {
 func DeletePortHandler(c *gin.Context) {
 	var req DeletePortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeletePort(req.Bridge, req.PortName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeletePort(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -242,11 +242,11 @@ type BindPortToNetnsRequest struct {
 func BindPortToNetnsHandler(c *gin.Context) {
 	var req BindPortToNetnsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.BindPortToNetns(req.PortName, req.Netns); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.BindPortToNetns(c.Request.Context(), req.PortName, req.Netns); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -268,11 +268,11 @@ type UnbindPortFromNetnsRequest struct {
 func UnbindPortFromNetnsHandler(c *gin.Context) {
 	var req UnbindPortFromNetnsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.UnbindPortFromNetns(req.PortName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.UnbindPortFromNetns(c.Request.Context(), req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -295,11 +295,11 @@ type SetPortUpDownRequest struct {
 func SetPortUpDownHandler(c *gin.Context) {
 	var req SetPortUpDownRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPortUpDown(req.PortName, req.Up); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetPortUpDown(c.Request.Context(), req.PortName, req.Up); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -322,11 +322,11 @@ type SetPortAddrRequest struct {
 func SetPortAddrHandler(c *gin.Context) {
 	var req SetPortAddrRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPortAddr(req.PortName, req.IP); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetPortAddr(c.Request.Context(), req.PortName, req.IP); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -349,11 +349,11 @@ type SetPortVlanTagRequest struct {
 func SetPortVlanTagHandler(c *gin.Context) {
 	var req SetPortVlanTagRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPortVlanTag(req.PortName, req.Tag); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetPortVlanTag(c.Request.Context(), req.PortName, req.Tag); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -376,11 +376,11 @@ type SetPortVlanModeRequest struct {
 func SetPortVlanModeHandler(c *gin.Context) {
 	var req SetPortVlanModeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPortVlanMode(req.PortName, req.VlanMode); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetPortVlanMode(c.Request.Context(), req.PortName, req.VlanMode); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -403,11 +403,11 @@ type SetPortTrunksRequest struct {
 func SetPortTrunksHandler(c *gin.Context) {
 	var req SetPortTrunksRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPortTrunks(req.PortName, req.Trunks); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetPortTrunks(c.Request.Context(), req.PortName, req.Trunks); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -431,11 +431,11 @@ type RemovePortPropertyRequest struct {
 func RemovePortPropertyHandler(c *gin.Context) {
 	var req RemovePortPropertyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.RemovePortProperty(req.PortName, req.Property, req.Value); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.RemovePortProperty(c.Request.Context(), req.PortName, req.Property, req.Value); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -459,11 +459,11 @@ type AddPatchPortRequest struct {
 func AddPatchPortHandler(c *gin.Context) {
 	var req AddPatchPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddPatchPort(req.Bridge, req.PortName, req.Peer); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.AddPatchPort(c.Request.Context(), req.Bridge, req.PortName, req.Peer); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
@@ -486,11 +486,11 @@ type AddPatchPortWithoutPeerRequest struct {
 func AddPatchPortWithoutPeerHandler(c *gin.Context) {
 	var req AddPatchPortWithoutPeerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddPatchPortWithoutPeer(req.Bridge, req.PortName); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.AddPatchPortWithoutPeer(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
@@ -513,11 +513,11 @@ type SetPatchPortPeerRequest struct {
 func SetPatchPortPeerHandler(c *gin.Context) {
 	var req SetPatchPortPeerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPatchPortPeer(req.PortName, req.Peer); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.SetPatchPortPeer(c.Request.Context(), req.PortName, req.Peer); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
@@ -534,11 +534,11 @@ type AddPatchPortPairRequest struct {
 func AddPatchPortPairHandler(c *gin.Context) {
 	var req AddPatchPortPairRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddPatchPortPair(req.BridgeA, req.PortA, req.BridgeB, req.PortB); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.AddPatchPortPair(c.Request.Context(), req.BridgeA, req.PortA, req.BridgeB, req.PortB); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
@@ -555,11 +555,11 @@ type AddBondPortWithMembersRequest struct {
 func AddBondPortWithMembersHandler(c *gin.Context) {
 	var req AddBondPortWithMembersRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddBondPortWithMembers(req.Bridge, req.PortName, req.Members, req.Mode); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.AddBondPortWithMembers(c.Request.Context(), req.Bridge, req.PortName, req.Members, req.Mode); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
@@ -584,11 +584,11 @@ type AddTunnelPortRequest struct {
 func AddTunnelPortHandler(c *gin.Context) {
 	var req AddTunnelPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddTunnelPort(req.Bridge, req.PortName, req.Type, req.Options); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddTunnelPort(c.Request.Context(), req.Bridge, req.PortName, req.Type, req.Options); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -603,11 +603,11 @@ type AddTapPortRequest struct {
 func AddTapPortHandler(c *gin.Context) {
 	var req AddTapPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddTapPort(req.Bridge, req.PortName); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.AddTapPort(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
@@ -622,11 +622,11 @@ type AddTunPortRequest struct {
 func AddTunPortHandler(c *gin.Context) {
 	var req AddTunPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddTunPort(req.Bridge, req.PortName); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.AddTunPort(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
@@ -648,12 +648,12 @@ type PortInfoRequest struct {
 func PortInfoHandler(c *gin.Context) {
 	var req PortInfoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	info, err := service.PortInfo(req.PortName)
+	info, err := service.PortInfo(c.Request.Context(), req.PortName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"info": info})
@@ -676,11 +676,11 @@ type SetBfdRequest struct {
 func SetBfdHandler(c *gin.Context) {
 	var req SetBfdRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetBfd(req.PortName, req.Bfd); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetBfd(c.Request.Context(), req.PortName, req.Bfd); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -703,11 +703,11 @@ type SetCfmRequest struct {
 func SetCfmHandler(c *gin.Context) {
 	var req SetCfmRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetCfm(req.PortName, req.Cfm); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetCfm(c.Request.Context(), req.PortName, req.Cfm); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -730,11 +730,11 @@ type SetMcastSnoopingRequest struct {
 func SetMcastSnoopingHandler(c *gin.Context) {
 	var req SetMcastSnoopingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetMcastSnooping(req.Bridge, req.Enable); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetMcastSnooping(c.Request.Context(), req.Bridge, req.Enable); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -758,11 +758,11 @@ type SetHfscQosRequest struct {
 func SetHfscQosHandler(c *gin.Context) {
 	var req SetHfscQosRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetHfscQos(req.PortName, req.MaxRate, req.Queues); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetHfscQos(c.Request.Context(), req.PortName, req.MaxRate, req.Queues); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -785,11 +785,11 @@ type SetDatapathTypeRequest struct {
 func SetDatapathTypeHandler(c *gin.Context) {
 	var req SetDatapathTypeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetDatapathType(req.Bridge, req.DatapathType); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetDatapathType(c.Request.Context(), req.Bridge, req.DatapathType); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -805,20 +805,20 @@ type SetPortTypePeerRequest struct {
 func SetPortTypePeerHandler(c *gin.Context) {
 	var req SetPortTypePeerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPortTypePeer(req.Bridge, req.PortName, req.Type, req.Peer); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.SetPortTypePeer(c.Request.Context(), req.Bridge, req.PortName, req.Type, req.Peer); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
 }
 
 func ListAllPatchPortsHandler(c *gin.Context) {
-	patchPorts, err := service.ListAllPatchPorts()
+	patchPorts, err := service.ListAllPatchPorts(c.Request.Context())
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"patchPorts": patchPorts})
@@ -833,12 +833,12 @@ type GetPortAddrsRequest struct {
 func GetPortAddrsHandler(c *gin.Context) {
 	var req GetPortAddrsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	ips, err := service.GetPortAddrs(req.PortName)
+	ips, err := service.GetPortAddrs(c.Request.Context(), req.PortName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ips": ips})
@@ -854,11 +854,11 @@ type DeletePortAddrRequest struct {
 func DeletePortAddrHandler(c *gin.Context) {
 	var req DeletePortAddrRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeletePortAddr(req.PortName, req.IP); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeletePortAddr(c.Request.Context(), req.PortName, req.IP); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -881,11 +881,11 @@ type SetPortAliasRequest struct {
 func SetPortAliasHandler(c *gin.Context) {
 	var req SetPortAliasRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPortAlias(req.PortName, req.Alias); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := service.SetPortAlias(c.Request.Context(), req.PortName, req.Alias); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "success"})
@@ -901,11 +901,11 @@ type SetPortRouteRequest struct {
 func SetPortRouteHandler(c *gin.Context) {
 	var req SetPortRouteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.SetPortRoute(req.PortName, req.Destination, req.Gateway); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.SetPortRoute(c.Request.Context(), req.PortName, req.Destination, req.Gateway); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -921,11 +921,11 @@ type DeletePortRouteRequest struct {
 func DeletePortRouteHandler(c *gin.Context) {
 	var req DeletePortRouteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeletePortRoute(req.PortName, req.Destination, req.Gateway); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeletePortRoute(c.Request.Context(), req.PortName, req.Destination, req.Gateway); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -939,12 +939,12 @@ type GetPortRoutesRequest struct {
 func GetPortRoutesHandler(c *gin.Context) {
 	var req GetPortRoutesRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	routes, err := service.GetPortRoutes(req.PortName)
+	routes, err := service.GetPortRoutes(c.Request.Context(), req.PortName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"routes": routes})
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"net/http"
+	"ovs-manager/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ListQosHandler 查询 QoS 接口
+// @Summary 查询 QoS
+// @Description 列出所有 QoS 行：类型、other_config、解析后的队列，以及使用它的端口
+// @Tags OVS-QoS
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/qos/list [post]
+func ListQosHandler(c *gin.Context) {
+	qos, err := service.ListQos(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"qos": qos})
+}
+
+// CreateQosRequest 创建 QoS 请求结构体
+// @Summary 创建 QoS
+// @Description 创建独立的 QoS 行，queues 为队列号到已有 Queue UUID 的映射；返回新 QoS 的 UUID，可通过 attach 共享给多个端口
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body CreateQosRequest true "QoS 参数"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/qos/create [post]
+type CreateQosRequest struct {
+	Type string `json:"type" binding:"required"`
+	MaxRate string `json:"maxRate"`
+	OtherConfig map[string]string `json:"otherConfig"`
+	Queues map[string]string `json:"queues"`
+}
+func CreateQosHandler(c *gin.Context) {
+	var req CreateQosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	uuid, err := service.CreateQos(c.Request.Context(), service.QosSpec{Type: req.Type, MaxRate: req.MaxRate, OtherConfig: req.OtherConfig, Queues: req.Queues})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"uuid": uuid})
+}
+
+// UpdateQosRequest 修改 QoS 请求结构体
+// @Summary 修改 QoS
+// @Description 用请求内容整体替换 QoS 的类型、other_config 和队列映射，使用该 QoS 的端口立即生效
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body UpdateQosRequest true "QoS 参数"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/qos/update [post]
+type UpdateQosRequest struct {
+	UUID string `json:"uuid" binding:"required"`
+	Type string `json:"type" binding:"required"`
+	MaxRate string `json:"maxRate"`
+	OtherConfig map[string]string `json:"otherConfig"`
+	Queues map[string]string `json:"queues"`
+}
+func UpdateQosHandler(c *gin.Context) {
+	var req UpdateQosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.UpdateQos(c.Request.Context(), req.UUID, service.QosSpec{Type: req.Type, MaxRate: req.MaxRate, OtherConfig: req.OtherConfig, Queues: req.Queues}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// DeleteQosRequest 删除 QoS 请求结构体
+// @Summary 删除 QoS
+// @Description 删除 QoS 行，仍被端口使用时返回 409
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body DeleteQosRequest true "QoS UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/qos/delete [post]
+type DeleteQosRequest struct {
+	UUID string `json:"uuid" binding:"required"`
+}
+func DeleteQosHandler(c *gin.Context) {
+	var req DeleteQosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DeleteQos(c.Request.Context(), req.UUID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// AttachQosRequest 端口应用 QoS 请求结构体
+// @Summary 端口应用 QoS
+// @Description 将已有 QoS 应用到端口，同一个 QoS 可被多个端口共享
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body AttachQosRequest true "端口和 QoS UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/qos/attach [post]
+type AttachQosRequest struct {
+	PortName string `json:"portName" binding:"required"`
+	UUID string `json:"uuid" binding:"required"`
+}
+func AttachQosHandler(c *gin.Context) {
+	var req AttachQosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.AttachQos(c.Request.Context(), req.PortName, req.UUID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// DetachQosRequest 端口移除 QoS 请求结构体
+// @Summary 端口移除 QoS
+// @Description 清除端口的 qos 列，QoS 行本身保留
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body DetachQosRequest true "端口"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/qos/detach [post]
+type DetachQosRequest struct {
+	PortName string `json:"portName" binding:"required"`
+}
+func DetachQosHandler(c *gin.Context) {
+	var req DetachQosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DetachQos(c.Request.Context(), req.PortName); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// CleanupQosRequest 清理 QoS 请求结构体
+// @Summary 清理未引用的 QoS/Queue
+// @Description 删除未被任何端口使用的 QoS，以及未被剩余 QoS 引用的 Queue；默认只删除本服务创建的行（external_ids:ovs-manager=true），all 为 true 时也删除其它程序创建的行；dryRun 为 true 时只返回将被删除的 UUID
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body CleanupQosRequest true "清理参数"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/qos/cleanup [post]
+type CleanupQosRequest struct {
+	DryRun bool `json:"dryRun"`
+	All bool `json:"all"`
+}
+func CleanupQosHandler(c *gin.Context) {
+	var req CleanupQosRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+	}
+	res, err := service.CleanupQos(c.Request.Context(), req.DryRun, req.All)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": res, "dryRun": req.DryRun})
+}
+
+// ListQueuesHandler 查询队列接口
+// @Summary 查询队列
+// @Description 列出所有 Queue 行及引用它的 QoS UUID
+// @Tags OVS-QoS
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/queue/list [post]
+func ListQueuesHandler(c *gin.Context) {
+	queues, err := service.ListQueues(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"queues": queues})
+}
+
+// QueueRequest 队列参数，速率单位为 bit/s
+type QueueRequest struct {
+	MinRate string `json:"minRate"`
+	MaxRate string `json:"maxRate"`
+	Burst string `json:"burst"`
+	Priority string `json:"priority"`
+	DSCP *int `json:"dscp"`
+	OtherConfig map[string]string `json:"otherConfig"`
+}
+
+func (r QueueRequest) spec() service.QueueSpec {
+	return service.QueueSpec{MinRate: r.MinRate, MaxRate: r.MaxRate, Burst: r.Burst, Priority: r.Priority, DSCP: r.DSCP, OtherConfig: r.OtherConfig}
+}
+
+// CreateQueueHandler 创建队列接口
+// @Summary 创建队列
+// @Description 创建独立的 Queue 行，返回 UUID，供创建/修改 QoS 时引用
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body QueueRequest true "队列参数"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/queue/create [post]
+func CreateQueueHandler(c *gin.Context) {
+	var req QueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	uuid, err := service.CreateQueue(c.Request.Context(), req.spec())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"uuid": uuid})
+}
+
+// UpdateQueueRequest 修改队列请求结构体
+// @Summary 修改队列
+// @Description 用请求内容整体替换 Queue 的 other_config 和 dscp，引用它的 QoS 立即生效
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body UpdateQueueRequest true "队列参数"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/queue/update [post]
+type UpdateQueueRequest struct {
+	UUID string `json:"uuid" binding:"required"`
+	QueueRequest
+}
+func UpdateQueueHandler(c *gin.Context) {
+	var req UpdateQueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.UpdateQueue(c.Request.Context(), req.UUID, req.spec()); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// DeleteQueueRequest 删除队列请求结构体
+// @Summary 删除队列
+// @Description 删除 Queue 行，仍被 QoS 引用时返回 409
+// @Tags OVS-QoS
+// @Accept json
+// @Produce json
+// @Param data body DeleteQueueRequest true "队列 UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/queue/delete [post]
+type DeleteQueueRequest struct {
+	UUID string `json:"uuid" binding:"required"`
+}
+func DeleteQueueHandler(c *gin.Context) {
+	var req DeleteQueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := service.DeleteQueue(c.Request.Context(), req.UUID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
@@ -32,11 +32,13 @@ type ScenarioApplyRequest struct {
 // Action: 步骤类型
 // Success: 是否成功
 // Error: 错误信息（如有）
+// Cause: 错误分类（如有）
 // Output: 额外输出（如有）
 type ScenarioStepResult struct {
 	Action  string      `json:"action"`
 	Success bool        `json:"success"`
 	Error   string      `json:"error,omitempty"`
+	Cause   string      `json:"cause,omitempty"`
 	Output  interface{} `json:"output,omitempty"`
 }
 
@@ -95,14 +97,14 @@ func mergeParams(stepParams, userParams map[string]interface{}) map[string]inter
 func ScenarioApplyHandler(c *gin.Context) {
 	var req ScenarioApplyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 	steps := req.Steps
 	if len(steps) == 0 && req.Scenario != "" {
 		tpl, ok := scenarioTemplates[req.Scenario]
 		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scenario template"})
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "unknown scenario template", Cause: string(service.CauseInvalidArgument)})
 			return
 		}
 		// 合并 params 到每个模板步骤
@@ -118,10 +120,11 @@ func ScenarioApplyHandler(c *gin.Context) {
 	success := true
 	for _, step := range steps {
 		res := ScenarioStepResult{Action: step.Action}
-		err, output := service.ExecuteScenarioStep(step.Action, step.Params)
+		err, output := service.ExecuteScenarioStep(c.Request.Context(), step.Action, step.Params)
 		if err != nil {
 			res.Success = false
 			res.Error = err.Error()
+			res.Cause = string(service.CauseOf(err))
 			success = false
 		} else {
 			res.Success = true
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+	"ovs-manager/service"
+	"github.com/gin-gonic/gin"
+)
+
+// OVSShowHandler 交换机整体状态接口
+// @Summary 查询 OVS 整体状态
+// @Description 一次查询返回所有网桥、端口、接口、VLAN、Bond、QoS/队列、镜像、控制器及 NetFlow/sFlow/IPFIX 配置的结构化快照，无需参数
+// @Tags OVS-Show
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/show [post]
+func OVSShowHandler(c *gin.Context) {
+	snapshot, err := service.ShowOVS(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ovs": snapshot})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"ovs-manager/service"
+	"ovs-manager/telemetry"
+	"github.com/gin-gonic/gin"
+)
+
+// TelemetryQueryRequest 流量统计查询请求结构体
+// @Summary 流量统计查询
+// @Description 查询内嵌收集器（-collector 启用）最近 window 内的流量：top-talkers 按 groupBy（src/dst/pair/flow）聚合，ports 按入/出端口统计并解析接口名，vlans 按 VLAN 统计；orderBy 为 bytes 或 packets；未启用收集器时返回 404
+// @Tags OVS-Telemetry
+// @Accept json
+// @Produce json
+// @Param data body TelemetryQueryRequest false "统计窗口、条数、分组、排序"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/telemetry/top-talkers [post]
+// @Router /api/ovs/telemetry/ports [post]
+// @Router /api/ovs/telemetry/vlans [post]
+type TelemetryQueryRequest struct {
+	Window string `json:"window"`
+	Limit int `json:"limit"`
+	GroupBy string `json:"groupBy" binding:"omitempty,oneof=src dst pair flow"`
+	OrderBy string `json:"orderBy" binding:"omitempty,oneof=bytes packets"`
+}
+
+// bindTelemetryQuery 解析查询参数，请求体可为空
+func bindTelemetryQuery(c *gin.Context) (telemetry.Query, bool) {
+	var req TelemetryQueryRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return telemetry.Query{}, false
+		}
+	}
+	q := telemetry.Query{Limit: req.Limit, GroupBy: req.GroupBy, OrderBy: req.OrderBy}
+	if req.Window != "" {
+		d, err := time.ParseDuration(req.Window)
+		if err != nil || d <= 0 {
+			respondBindError(c, fmt.Errorf("invalid window %q", req.Window))
+			return telemetry.Query{}, false
+		}
+		q.Window = d
+	}
+	return q, true
+}
+
+func TopTalkersHandler(c *gin.Context) {
+	q, ok := bindTelemetryQuery(c)
+	if !ok {
+		return
+	}
+	talkers, err := service.TopTalkers(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"talkers": talkers})
+}
+
+func TrafficByPortHandler(c *gin.Context) {
+	q, ok := bindTelemetryQuery(c)
+	if !ok {
+		return
+	}
+	ports, err := service.TrafficByPort(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ports": ports})
+}
+
+func TrafficByVlanHandler(c *gin.Context) {
+	q, ok := bindTelemetryQuery(c)
+	if !ok {
+		return
+	}
+	vlans, err := service.TrafficByVlan(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"vlans": vlans})
+}
+
+// TelemetryStatsHandler 收集器状态接口
+// @Summary 收集器状态
+// @Description 返回内嵌收集器按协议统计的报文数、保留的流记录数、解码错误数和已学习的模板数
+// @Tags OVS-Telemetry
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/ovs/telemetry/stats [post]
+func TelemetryStatsHandler(c *gin.Context) {
+	stats, err := service.TelemetryStats(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
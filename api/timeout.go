@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"ovs-manager/service"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+
+// commandLogKey gin 上下文中命令日志的键
+const commandLogKey = "commandLog"
+
+// TimeoutHeader 单个请求自定义超时的请求头，值为 Go duration 格式，如 5s、500ms
+const TimeoutHeader = "X-Request-Timeout"
+
+// RequestTimeout 为每个请求设置截止时间（默认取 service.DefaultTimeout，可用 X-Request-Timeout 覆盖）
+// 并附加命令日志，请求内所有 service 调用共享该 context，客户端断开或超时后外部命令会被终止；
+// 事件流等长连接路由不设置超时
+func RequestTimeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() == EventsPath {
+			c.Next()
+			return
+		}
+		timeout := service.DefaultTimeout()
+		if v := c.GetHeader(TimeoutHeader); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Error: "invalid " + TimeoutHeader + ": " + v, Cause: string(service.CauseInvalidArgument)})
+				return
+			}
+			timeout = d
+		}
+		ctx := c.Request.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		ctx, log := service.WithCommandLog(ctx)
+		c.Set(commandLogKey, log)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -27,11 +27,11 @@ type AddVxlanPortCustomRequest struct {
 func AddVxlanPortCustomHandler(c *gin.Context) {
 	var req AddVxlanPortCustomRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.AddVxlanPortCustom(req.Bridge, req.PortName, req.RemoteIP, req.VNI, req.Key, req.LocalIP); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.AddVxlanPortCustom(c.Request.Context(), req.Bridge, req.PortName, req.RemoteIP, req.VNI, req.Key, req.LocalIP); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -54,11 +54,11 @@ type DeleteVxlanPortRequest struct {
 func DeleteVxlanPortHandler(c *gin.Context) {
 	var req DeleteVxlanPortRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-	if err := service.DeletePort(req.Bridge, req.PortName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := service.DeletePort(c.Request.Context(), req.Bridge, req.PortName); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
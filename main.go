@@ -4,14 +4,84 @@
 //
 // 启动命令：go run main.go
 //
+//	-ovsdb unix:/var/run/openvswitch/db.sock  通过 OVSDB JSON-RPC 直连数据库
+//	-fake-ovsdb                               使用进程内的内存版 OVSDB（无 OVS 环境联调）
+//	-record commands.jsonl                    录制所有外部命令及输出到 golden 文件
+//	-replay commands.jsonl                    按 golden 文件回放命令输出，无需真实 OVS
+//	-timeout 30s                              每个请求的默认超时，超时返回 504（0 表示不限制）
+//	-collector :6343,:2055,:4739              启动内嵌 sFlow/NetFlow/IPFIX 收集器（UDP 地址，逗号分隔）
+//	-collector-window 5m                      收集器保留流记录的时长
+//	-metrics-interval 15s                     /metrics 采集 OVS 指标的最小间隔，间隔内返回缓存
+//	-flow-store flows.json                    记录经 API 安装的流表，可通过 drift/replay 接口检查和重新下发
+//	-flow-store-interval 10s                  开启流表存储巡检，ovs-vswitchd 重启或网桥重建后自动重新下发（默认 0 不开启）
+//
 // 健康检查接口：GET /ping
+// Prometheus 指标：GET /metrics
 package main
 
 import (
+	"flag"
+	"log"
+	"net"
+	"strings"
+
+	"ovs-manager/ovsdb"
 	"ovs-manager/router"
+	"ovs-manager/service"
+	"ovs-manager/telemetry"
 )
 
 func main() {
+	ovsdbEndpoint := flag.String("ovsdb", "", "ovsdb-server 地址，如 unix:/var/run/openvswitch/db.sock，留空则调用 ovs-vsctl")
+	fakeOVSDB := flag.Bool("fake-ovsdb", false, "启动进程内的内存版 OVSDB 服务端，仅用于联调")
+	record := flag.String("record", "", "录制外部命令及输出的 golden 文件路径")
+	replay := flag.String("replay", "", "回放外部命令输出的 golden 文件路径")
+	timeout := flag.Duration("timeout", service.DefaultTimeout(), "每个请求及外部命令的默认超时，0 表示不限制")
+	collectorAddrs := flag.String("collector", "", "内嵌 sFlow/NetFlow/IPFIX 收集器监听的 UDP 地址，逗号分隔，留空不启用")
+	collectorWindow := flag.Duration("collector-window", telemetry.DefaultWindow, "收集器保留流记录的时长")
+	metricsInterval := flag.Duration("metrics-interval", service.DefaultMetricsInterval, "/metrics 采集 OVS 指标的最小间隔，0 表示每次抓取都采集")
+	flowStorePath := flag.String("flow-store", "", "流表存储文件路径，留空不启用")
+	flowStoreInterval := flag.Duration("flow-store-interval", 0, "流表存储巡检网桥的间隔（如 10s），0 表示不自动重新下发")
+	flag.Parse()
+
+	service.SetDefaultTimeout(*timeout)
+	service.SetMetricsInterval(*metricsInterval)
+
+	switch {
+	case *replay != "":
+		fake, err := service.LoadReplayRunner(*replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+		service.SetRunner(fake)
+	case *record != "":
+		service.SetRunner(service.NewRecordingRunner(nil, *record))
+	}
+
+	if *fakeOVSDB {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv := ovsdb.NewServer(ovsdb.OpenvSwitchSchema())
+		go srv.Serve(ln)
+		*ovsdbEndpoint = "tcp:" + ln.Addr().String()
+		service.SetOVSDBNoWait(true)
+	}
+	service.SetOVSDBEndpoint(*ovsdbEndpoint)
+
+	if *collectorAddrs != "" {
+		if _, err := service.StartCollector(strings.Split(*collectorAddrs, ","), *collectorWindow); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *flowStorePath != "" {
+		if _, err := service.StartFlowStore(*flowStorePath, *flowStoreInterval); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	r := router.InitRouter()
 	r.Run(":8080")
 }
@@ -0,0 +1,254 @@
+// Package metrics 实现 Prometheus 文本格式（0.0.4）的指标输出，以及进程内使用的计数器和直方图；
+// 不依赖 prometheus client 库，指标族由调用方按需组装后通过 Write 输出
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType Prometheus 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// 指标类型
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Label 指标标签
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample 一个样本；Suffix 用于直方图的 _bucket/_sum/_count
+type Sample struct {
+	Suffix string
+	Labels []Label
+	Value  float64
+}
+
+// Family 同名指标族
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// NewFamily 创建指标族
+func NewFamily(name, typ, help string) *Family {
+	return &Family{Name: name, Help: help, Type: typ}
+}
+
+// Add 追加一个样本，labels 为 名称、取值 交替排列
+func (f *Family) Add(value float64, labels ...string) {
+	s := Sample{Value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		s.Labels = append(s.Labels, Label{labels[i], labels[i+1]})
+	}
+	f.Samples = append(f.Samples, s)
+}
+
+// Write 按文本格式输出指标族，没有样本的指标族跳过
+func Write(w io.Writer, families []*Family) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		if len(f.Samples) == 0 {
+			continue
+		}
+		bw.WriteString("# HELP " + f.Name + " " + escapeHelp(f.Help) + "\n")
+		bw.WriteString("# TYPE " + f.Name + " " + f.Type + "\n")
+		for _, s := range f.Samples {
+			bw.WriteString(f.Name + s.Suffix)
+			if len(s.Labels) > 0 {
+				bw.WriteByte('{')
+				for i, l := range s.Labels {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					bw.WriteString(l.Name + `="` + escapeLabel(l.Value) + `"`)
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteString(" " + formatValue(s.Value) + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// SanitizeName 将任意字符串转换为合法的指标名片段（非字母数字下划线替换为 _）
+func SanitizeName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// labelKey 标签值组合的映射键
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// CounterVec 带标签的进程内计数器
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]*counterEntry
+}
+
+type counterEntry struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec 创建计数器，labels 为标签名
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labels: labels, values: map[string]*counterEntry{}}
+}
+
+// Inc 计数加一，values 与创建时的标签名一一对应
+func (c *CounterVec) Inc(values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := labelKey(values)
+	e, ok := c.values[k]
+	if !ok {
+		e = &counterEntry{labels: append([]string(nil), values...)}
+		c.values[k] = e
+	}
+	e.value++
+}
+
+// Collect 返回当前计数，按标签值排序
+func (c *CounterVec) Collect() *Family {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := NewFamily(c.name, TypeCounter, c.help)
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e := c.values[k]
+		f.Samples = append(f.Samples, Sample{Labels: pairLabels(c.labels, e.labels), Value: e.value})
+	}
+	return f
+}
+
+// DefaultBuckets 默认的延迟直方图桶（秒）
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// HistogramVec 带标签的进程内直方图
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	values     map[string]*histogramEntry
+}
+
+type histogramEntry struct {
+	labels []string
+	counts []uint64 // 每个桶的非累积计数
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec 创建直方图，buckets 为升序的桶上界，为空时使用 DefaultBuckets
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: map[string]*histogramEntry{}}
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	k := labelKey(values)
+	e, ok := h.values[k]
+	if !ok {
+		e = &histogramEntry{labels: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.values[k] = e
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		e.counts[i]++
+	}
+	e.count++
+	e.sum += v
+}
+
+// Collect 返回累积桶计数、总和和次数
+func (h *HistogramVec) Collect() *Family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := NewFamily(h.name, TypeHistogram, h.help)
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e := h.values[k]
+		labels := pairLabels(h.labels, e.labels)
+		var cum uint64
+		for i, ub := range h.buckets {
+			cum += e.counts[i]
+			le := append(append([]Label(nil), labels...), Label{"le", formatValue(ub)})
+			f.Samples = append(f.Samples, Sample{Suffix: "_bucket", Labels: le, Value: float64(cum)})
+		}
+		inf := append(append([]Label(nil), labels...), Label{"le", "+Inf"})
+		f.Samples = append(f.Samples,
+			Sample{Suffix: "_bucket", Labels: inf, Value: float64(e.count)},
+			Sample{Suffix: "_sum", Labels: labels, Value: e.sum},
+			Sample{Suffix: "_count", Labels: labels, Value: float64(e.count)},
+		)
+	}
+	return f
+}
+
+func pairLabels(names, values []string) []Label {
+	res := make([]Label, 0, len(names))
+	for i, n := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		res = append(res, Label{n, v})
+	}
+	return res
+}
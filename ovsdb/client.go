@@ -0,0 +1,386 @@
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// DefaultEndpoint ovsdb-server 默认 unix socket
+const DefaultEndpoint = "unix:/var/run/openvswitch/db.sock"
+
+// ErrClosed 连接已关闭
+var ErrClosed = errors.New("ovsdb: connection closed")
+
+// ErrUpdateOverflow monitor handler 处理过慢，积压的 update 超过上限，连接已被关闭；
+// 调用方应重新连接并以新的初始数据重新同步
+var ErrUpdateOverflow = errors.New("ovsdb: too many pending monitor updates")
+
+// DefaultMaxPendingUpdates 每个连接最多积压的未分发 update 数
+const DefaultMaxPendingUpdates = 1024
+
+// MonitorHandler 接收 monitor 推送的 update 通知
+type MonitorHandler func(TableUpdates)
+
+type monitorUpdate struct {
+	handler MonitorHandler
+	updates TableUpdates
+}
+
+// Client OVSDB JSON-RPC 客户端，一个连接上可并发发起多个请求
+type Client struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu         sync.Mutex
+	nextID     uint64
+	pending    map[uint64]chan rpcResponse
+	monitors   map[string]MonitorHandler
+	updates    []monitorUpdate // 待分发的 update，由 dispatchLoop 按到达顺序处理
+	maxUpdates int
+	cond       *sync.Cond
+	err        error
+	done       chan struct{}
+}
+
+type rpcRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+	ID     interface{}   `json:"id"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  interface{}     `json:"error"`
+	ID     interface{}     `json:"id"`
+}
+
+type rpcMessage struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  interface{}     `json:"error"`
+	ID     interface{}     `json:"id"`
+}
+
+// RPCError 服务端返回的 JSON-RPC 错误
+type RPCError struct {
+	Method string
+	Err    interface{}
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("ovsdb: %s: %v", e.Method, e.Err)
+}
+
+// TransactError transact 中某个操作失败
+type TransactError struct {
+	Index   int
+	Op      string
+	Err     string
+	Details string
+}
+
+func (e *TransactError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("ovsdb: operation %d (%s) failed: %s: %s", e.Index, e.Op, e.Err, e.Details)
+	}
+	return fmt.Sprintf("ovsdb: operation %d (%s) failed: %s", e.Index, e.Op, e.Err)
+}
+
+// ParseEndpoint 解析 ovsdb 地址，支持 unix:/path、tcp:host:port，裸路径视为 unix socket
+func ParseEndpoint(endpoint string) (network, address string, err error) {
+	switch {
+	case strings.HasPrefix(endpoint, "unix:"):
+		return "unix", strings.TrimPrefix(endpoint, "unix:"), nil
+	case strings.HasPrefix(endpoint, "tcp:"):
+		return "tcp", strings.TrimPrefix(endpoint, "tcp:"), nil
+	case strings.HasPrefix(endpoint, "/"):
+		return "unix", endpoint, nil
+	}
+	return "", "", fmt.Errorf("ovsdb: unsupported endpoint %q", endpoint)
+}
+
+// Dial 连接 ovsdb-server
+func Dial(ctx context.Context, endpoint string) (*Client, error) {
+	network, address, err := ParseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient 基于已建立的连接创建客户端
+func NewClient(conn net.Conn) *Client {
+	c := &Client{
+		conn:       conn,
+		enc:        json.NewEncoder(conn),
+		pending:    make(map[uint64]chan rpcResponse),
+		monitors:   make(map[string]MonitorHandler),
+		maxUpdates: DefaultMaxPendingUpdates,
+		done:       make(chan struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	go c.readLoop()
+	go c.dispatchLoop()
+	return c
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+// Done 连接断开时关闭
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err 连接断开的原因
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Client) readLoop() {
+	dec := json.NewDecoder(c.conn)
+	var err error
+	for {
+		var msg rpcMessage
+		if err = dec.Decode(&msg); err != nil {
+			break
+		}
+		if msg.Method != "" {
+			c.handleNotification(msg)
+			continue
+		}
+		id, ok := msg.ID.(float64)
+		if !ok {
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[uint64(id)]
+		delete(c.pending, uint64(id))
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- rpcResponse{Result: msg.Result, Error: msg.Error, ID: msg.ID}
+		}
+	}
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	if c.err == nil {
+		c.err = ErrClosed
+	}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// dispatchLoop 在独立的 goroutine 中依次调用 monitor handler，handler 内可以发起客户端调用
+// 而不会阻塞 readLoop；连接断开后分发完已收到的 update 再退出
+func (c *Client) dispatchLoop() {
+	for {
+		c.mu.Lock()
+		for len(c.updates) == 0 && c.err == nil {
+			c.cond.Wait()
+		}
+		if len(c.updates) == 0 {
+			c.mu.Unlock()
+			return
+		}
+		u := c.updates[0]
+		c.updates[0] = monitorUpdate{}
+		c.updates = c.updates[1:]
+		c.mu.Unlock()
+		u.handler(u.updates)
+	}
+}
+
+func (c *Client) handleNotification(msg rpcMessage) {
+	switch msg.Method {
+	case "echo":
+		c.send(rpcResponse{Result: msg.Params, ID: msg.ID})
+	case "update":
+		var params []json.RawMessage
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params) != 2 {
+			return
+		}
+		var updates TableUpdates
+		if err := json.Unmarshal(params[1], &updates); err != nil {
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		handler := c.monitors[string(params[0])]
+		if handler == nil || c.err != nil {
+			return
+		}
+		if len(c.updates) >= c.maxUpdates {
+			// 丢弃积压的 update 并断开连接，避免慢 handler 导致内存无限增长
+			c.err = ErrUpdateOverflow
+			c.updates = nil
+			c.cond.Broadcast()
+			c.conn.Close()
+			return
+		}
+		c.updates = append(c.updates, monitorUpdate{handler: handler, updates: updates})
+		c.cond.Signal()
+	}
+}
+
+func (c *Client) send(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.enc.Encode(v)
+}
+
+func (c *Client) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	ch := make(chan rpcResponse, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if params == nil {
+		params = []interface{}{}
+	}
+	if err := c.send(rpcRequest{Method: method, Params: params, ID: id}); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return err
+	}
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return c.Err()
+		}
+		if resp.Error != nil {
+			return &RPCError{Method: method, Err: resp.Error}
+		}
+		if result != nil {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// ListDbs 列出服务端所有数据库
+func (c *Client) ListDbs(ctx context.Context) ([]string, error) {
+	var dbs []string
+	err := c.call(ctx, "list_dbs", nil, &dbs)
+	return dbs, err
+}
+
+// GetSchema 获取数据库 schema
+func (c *Client) GetSchema(ctx context.Context, db string) (*DatabaseSchema, error) {
+	var schema DatabaseSchema
+	if err := c.call(ctx, "get_schema", []interface{}{db}, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// Echo 心跳
+func (c *Client) Echo(ctx context.Context) error {
+	return c.call(ctx, "echo", []interface{}{"ovs-manager"}, nil)
+}
+
+// Transact 在一个事务中执行多个操作，任一操作失败时返回 *TransactError
+func (c *Client) Transact(ctx context.Context, db string, ops ...Operation) ([]OperationResult, error) {
+	params := make([]interface{}, 0, len(ops)+1)
+	params = append(params, db)
+	for _, op := range ops {
+		params = append(params, op)
+	}
+	var raw []*OperationResult
+	if err := c.call(ctx, "transact", params, &raw); err != nil {
+		return nil, err
+	}
+	results := make([]OperationResult, len(raw))
+	for i, r := range raw {
+		if r == nil {
+			continue
+		}
+		results[i] = *r
+		if r.Error != "" {
+			te := &TransactError{Index: i, Err: r.Error, Details: r.Details}
+			if i < len(ops) {
+				te.Op = ops[i].Op
+			} else {
+				te.Op = "commit"
+			}
+			return results, te
+		}
+	}
+	return results, nil
+}
+
+// Monitor 订阅表变更，返回初始数据；后续变更按到达顺序在客户端的分发 goroutine 中回调 handler，
+// handler 可以调用本客户端的方法，但执行期间会推迟后续 update 的分发；
+// 积压超过 DefaultMaxPendingUpdates 时连接被关闭，Err 返回 ErrUpdateOverflow
+func (c *Client) Monitor(ctx context.Context, db, id string, requests map[string]MonitorRequest, handler MonitorHandler) (TableUpdates, error) {
+	key, _ := json.Marshal(id)
+	c.mu.Lock()
+	c.monitors[string(key)] = handler
+	c.mu.Unlock()
+	var initial TableUpdates
+	if err := c.call(ctx, "monitor", []interface{}{db, id, requests}, &initial); err != nil {
+		c.mu.Lock()
+		delete(c.monitors, string(key))
+		c.mu.Unlock()
+		return nil, err
+	}
+	return initial, nil
+}
+
+// MonitorCancel 取消订阅
+func (c *Client) MonitorCancel(ctx context.Context, id string) error {
+	key, _ := json.Marshal(id)
+	c.mu.Lock()
+	delete(c.monitors, string(key))
+	c.mu.Unlock()
+	return c.call(ctx, "monitor_cancel", []interface{}{id}, nil)
+}
+
+// Select 便捷查询，返回满足条件的行
+func (c *Client) Select(ctx context.Context, db, table string, columns []string, where ...Condition) ([]Row, error) {
+	results, err := c.Transact(ctx, db, Operation{Op: "select", Table: table, Columns: columns, Where: where})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return results[0].Rows, nil
+}
@@ -0,0 +1,290 @@
+package ovsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+const testDB = "Open_vSwitch"
+
+// newTestClient 通过 net.Pipe 连接一个内存服务端
+func newTestClient(t *testing.T) (*Client, *Server) {
+	t.Helper()
+	srv := NewServer(OpenvSwitchSchema())
+	return connectTestClient(t, srv), srv
+}
+
+func connectTestClient(t *testing.T, srv *Server) *Client {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	go srv.ServeConn(serverConn)
+	c := NewClient(clientConn)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// addBridge 与 ovs-vsctl add-br 相同：Interface、Port、Bridge 各一行并挂到根表
+func addBridge(ctx context.Context, c *Client, name string) error {
+	_, err := c.Transact(ctx, testDB,
+		Operation{Op: "insert", Table: "Interface", UUIDName: "iface",
+			Row: Row{"name": name, "type": "internal"}},
+		Operation{Op: "insert", Table: "Port", UUIDName: "port",
+			Row: Row{"name": name, "interfaces": NamedUUID{Name: "iface"}}},
+		Operation{Op: "insert", Table: "Bridge", UUIDName: "bridge",
+			Row: Row{"name": name, "ports": NewOvsSet(NamedUUID{Name: "port"})}},
+		Operation{Op: "mutate", Table: "Open_vSwitch",
+			Mutations: []Mutation{NewMutation("bridges", "insert", NewOvsSet(NamedUUID{Name: "bridge"}))}},
+	)
+	return err
+}
+
+func countRows(ctx context.Context, t *testing.T, c *Client, table string) int {
+	t.Helper()
+	rows, err := c.Select(ctx, testDB, table, []string{"_uuid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(rows)
+}
+
+func TestClientGetSchema(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := testContext(t)
+	dbs, err := c.ListDbs(ctx)
+	if err != nil || len(dbs) != 1 || dbs[0] != testDB {
+		t.Fatalf("ListDbs = %v, %v", dbs, err)
+	}
+	schema, err := c.GetSchema(ctx, testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Name != testDB {
+		t.Errorf("schema name = %q", schema.Name)
+	}
+	if _, ok := schema.Tables["Bridge"].Columns["ports"]; !ok {
+		t.Error("Bridge.ports missing from schema")
+	}
+	var rpcErr *RPCError
+	if _, err := c.GetSchema(ctx, "nope"); !errors.As(err, &rpcErr) {
+		t.Errorf("GetSchema(nope) error = %v", err)
+	}
+	if err := c.Echo(ctx); err != nil {
+		t.Errorf("Echo: %v", err)
+	}
+}
+
+func TestClientTransact(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := testContext(t)
+	if err := addBridge(ctx, c, "br0"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := c.Select(ctx, testDB, "Bridge", []string{"name", "ports"}, NewCondition("name", "==", "br0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || len(rows[0].UUIDs("ports")) != 1 {
+		t.Fatalf("Select Bridge = %v", rows)
+	}
+
+	_, err = c.Transact(ctx, testDB,
+		Operation{Op: "update", Table: "Bridge", Where: []Condition{NewCondition("name", "==", "br0")},
+			Row: Row{"external_ids": NewOvsMap(map[string]string{"owner": "test"})}},
+		Operation{Op: "insert", Table: "NoSuchTable", Row: Row{}},
+	)
+	var te *TransactError
+	if !errors.As(err, &te) || te.Index != 1 || te.Op != "insert" {
+		t.Fatalf("expected failure of operation 1, got %v", err)
+	}
+	// 失败的事务整体不生效
+	rows, _ = c.Select(ctx, testDB, "Bridge", []string{"external_ids"})
+	if len(rows) != 1 || len(rows[0].StringMap("external_ids")) != 0 {
+		t.Errorf("aborted update was applied: %v", rows)
+	}
+}
+
+func TestServerGarbageCollection(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := testContext(t)
+	for _, name := range []string{"br0", "br1"} {
+		if err := addBridge(ctx, c, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows, err := c.Select(ctx, testDB, "Bridge", []string{"_uuid"}, NewCondition("name", "==", "br0"))
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("Select br0 = %v, %v", rows, err)
+	}
+	_, err = c.Transact(ctx, testDB, Operation{Op: "mutate", Table: "Open_vSwitch",
+		Mutations: []Mutation{NewMutation("bridges", "delete", NewOvsSet(UUID{GoUUID: rows[0].UUID("_uuid")}))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 根表不再引用的 Bridge 及其 Port、Interface 被回收，br1 保留
+	for _, table := range []string{"Bridge", "Port", "Interface"} {
+		if n := countRows(ctx, t, c, table); n != 1 {
+			t.Errorf("%s has %d rows after deleting br0, want 1", table, n)
+		}
+	}
+	// 未挂到根表的非根行在提交时即被回收
+	if _, err := c.Transact(ctx, testDB, Operation{Op: "insert", Table: "Interface", Row: Row{"name": "orphan"}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(ctx, t, c, "Interface"); n != 1 {
+		t.Errorf("orphan Interface was kept: %d rows", n)
+	}
+}
+
+func TestServerIndexConflict(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := testContext(t)
+	if err := addBridge(ctx, c, "br0"); err != nil {
+		t.Fatal(err)
+	}
+	err := addBridge(ctx, c, "br0")
+	var te *TransactError
+	if !errors.As(err, &te) || te.Op != "commit" || te.Err != "constraint violation" {
+		t.Fatalf("expected constraint violation on commit, got %v", err)
+	}
+	if n := countRows(ctx, t, c, "Bridge"); n != 1 {
+		t.Errorf("Bridge has %d rows after conflicting insert, want 1", n)
+	}
+}
+
+func TestClientMonitor(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := testContext(t)
+	if err := addBridge(ctx, c, "br0"); err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan TableUpdates, 4)
+	initial, err := c.Monitor(ctx, testDB, "test", map[string]MonitorRequest{
+		"Bridge": {Columns: []string{"name"}},
+	}, func(u TableUpdates) { got <- u })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(initial["Bridge"]) != 1 {
+		t.Fatalf("initial updates = %v", initial)
+	}
+	if err := addBridge(ctx, c, "br1"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case u := <-got:
+		var names []string
+		for _, ru := range u["Bridge"] {
+			if ru.Old == nil {
+				names = append(names, ru.New.String("name"))
+			}
+		}
+		if len(names) != 1 || names[0] != "br1" {
+			t.Errorf("update = %v, want insert of br1", u)
+		}
+	case <-ctx.Done():
+		t.Fatal("no update received")
+	}
+
+	if err := c.MonitorCancel(ctx, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := addBridge(ctx, c, "br2"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case u := <-got:
+		t.Errorf("update after monitor_cancel: %v", u)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// handler 中发起客户端调用不应阻塞读循环
+func TestClientMonitorHandlerCallsClient(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := testContext(t)
+	counts := make(chan int, 4)
+	_, err := c.Monitor(ctx, testDB, "test", map[string]MonitorRequest{
+		"Bridge": {Columns: []string{"name"}},
+	}, func(u TableUpdates) {
+		rows, err := c.Select(ctx, testDB, "Bridge", []string{"name"})
+		if err != nil {
+			t.Error(err)
+		}
+		counts <- len(rows)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"br0", "br1"} {
+		if err := addBridge(ctx, c, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for want := 1; want <= 2; want++ {
+		select {
+		case n := <-counts:
+			if n < want {
+				t.Errorf("handler saw %d bridges, want at least %d", n, want)
+			}
+		case <-ctx.Done():
+			t.Fatal("monitor handler deadlocked")
+		}
+	}
+}
+
+// handler 阻塞时积压的 update 超过上限应断开连接，而不是无限占用内存
+func TestClientMonitorOverflow(t *testing.T) {
+	c, srv := newTestClient(t)
+	c.mu.Lock()
+	c.maxUpdates = 2
+	c.mu.Unlock()
+	ctx := testContext(t)
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := c.Monitor(ctx, testDB, "test", map[string]MonitorRequest{
+		"Bridge": {Columns: []string{"name"}},
+	}, func(TableUpdates) { <-release }); err != nil {
+		t.Fatal(err)
+	}
+	writer := connectTestClient(t, srv)
+	for i := 0; i < 5; i++ {
+		if err := addBridge(ctx, writer, fmt.Sprintf("br%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-c.Done():
+	case <-ctx.Done():
+		t.Fatal("client not closed on overflow")
+	}
+	if err := c.Err(); err != ErrUpdateOverflow {
+		t.Errorf("Err() = %v, want ErrUpdateOverflow", err)
+	}
+	if err := c.Echo(ctx); err != ErrUpdateOverflow {
+		t.Errorf("call after overflow = %v", err)
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := testContext(t)
+	c.Close()
+	select {
+	case <-c.Done():
+	case <-ctx.Done():
+		t.Fatal("Done not closed")
+	}
+	if err := c.Echo(ctx); err == nil {
+		t.Error("call on closed client succeeded")
+	}
+}
@@ -0,0 +1,181 @@
+package ovsdb
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// vswitchSchema 内置的 Open_vSwitch 库 schema（vswitch.ovsschema 的常用表子集）
+//
+//go:embed vswitch.ovsschema
+var vswitchSchema []byte
+
+// DatabaseSchema 数据库 schema，对应 get_schema 的返回值
+type DatabaseSchema struct {
+	Name    string                 `json:"name"`
+	Version string                 `json:"version"`
+	Tables  map[string]TableSchema `json:"tables"`
+}
+
+// TableSchema 表 schema
+type TableSchema struct {
+	Columns map[string]*ColumnSchema `json:"columns"`
+	IsRoot  bool                     `json:"isRoot,omitempty"`
+	MaxRows int                      `json:"maxRows,omitempty"`
+	Indexes [][]string               `json:"indexes,omitempty"`
+}
+
+// ColumnSchema 列 schema，Type 保留原始 JSON，解析结果存放在 Key/Value/Min/Max 中
+type ColumnSchema struct {
+	Type      json.RawMessage `json:"type"`
+	Ephemeral bool            `json:"ephemeral,omitempty"`
+	Mutable   *bool           `json:"mutable,omitempty"`
+
+	Key   BaseType `json:"-"`
+	Value BaseType `json:"-"`
+	Min   int      `json:"-"`
+	// Max 为 -1 表示 unlimited
+	Max int `json:"-"`
+}
+
+// BaseType 原子类型：integer/real/boolean/string/uuid，uuid 可引用其它表
+type BaseType struct {
+	Type     string
+	RefTable string
+	RefType  string
+}
+
+// Atomic 是否为单一原子值（非 set/map）
+func (c *ColumnSchema) Atomic() bool {
+	return c.Value.Type == "" && c.Min == 1 && c.Max == 1
+}
+
+// IsMap 是否为 map 列
+func (c *ColumnSchema) IsMap() bool {
+	return c.Value.Type != ""
+}
+
+// Default 该列的默认值
+func (c *ColumnSchema) Default() interface{} {
+	if c.IsMap() {
+		return OvsMap{GoMap: map[interface{}]interface{}{}}
+	}
+	if !c.Atomic() {
+		return OvsSet{GoSet: []interface{}{}}
+	}
+	return c.Key.zero()
+}
+
+func (b BaseType) zero() interface{} {
+	switch b.Type {
+	case "integer", "real":
+		return float64(0)
+	case "boolean":
+		return false
+	case "uuid":
+		return UUID{GoUUID: "00000000-0000-0000-0000-000000000000"}
+	}
+	return ""
+}
+
+// UnmarshalJSON 解析 schema 并展开每列的类型定义
+func (s *DatabaseSchema) UnmarshalJSON(b []byte) error {
+	type alias DatabaseSchema
+	var a alias
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	for tname, t := range a.Tables {
+		for cname, col := range t.Columns {
+			if err := col.parseType(); err != nil {
+				return fmt.Errorf("ovsdb: table %s column %s: %v", tname, cname, err)
+			}
+		}
+	}
+	*s = DatabaseSchema(a)
+	return nil
+}
+
+func (c *ColumnSchema) parseType() error {
+	c.Min, c.Max = 1, 1
+	var simple string
+	if err := json.Unmarshal(c.Type, &simple); err == nil {
+		c.Key = BaseType{Type: simple}
+		return nil
+	}
+	var full struct {
+		Key   json.RawMessage `json:"key"`
+		Value json.RawMessage `json:"value"`
+		Min   *int            `json:"min"`
+		Max   interface{}     `json:"max"`
+	}
+	if err := json.Unmarshal(c.Type, &full); err != nil {
+		return err
+	}
+	var err error
+	if c.Key, err = parseBaseType(full.Key); err != nil {
+		return err
+	}
+	if len(full.Value) > 0 {
+		if c.Value, err = parseBaseType(full.Value); err != nil {
+			return err
+		}
+	}
+	if full.Min != nil {
+		c.Min = *full.Min
+	}
+	switch m := full.Max.(type) {
+	case float64:
+		c.Max = int(m)
+	case string:
+		if m == "unlimited" {
+			c.Max = -1
+		}
+	}
+	return nil
+}
+
+func parseBaseType(raw json.RawMessage) (BaseType, error) {
+	var simple string
+	if err := json.Unmarshal(raw, &simple); err == nil {
+		return BaseType{Type: simple}, nil
+	}
+	var full struct {
+		Type     string `json:"type"`
+		RefTable string `json:"refTable"`
+		RefType  string `json:"refType"`
+	}
+	if err := json.Unmarshal(raw, &full); err != nil {
+		return BaseType{}, err
+	}
+	return BaseType{Type: full.Type, RefTable: full.RefTable, RefType: full.RefType}, nil
+}
+
+// ParseSchema 解析 schema JSON
+func ParseSchema(data []byte) (*DatabaseSchema, error) {
+	var s DatabaseSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadSchema 从文件加载 schema，如 /usr/share/openvswitch/vswitch.ovsschema
+func LoadSchema(path string) (*DatabaseSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(data)
+}
+
+// OpenvSwitchSchema 返回内置的 Open_vSwitch schema
+func OpenvSwitchSchema() *DatabaseSchema {
+	s, err := ParseSchema(vswitchSchema)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
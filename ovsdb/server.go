@@ -0,0 +1,1015 @@
+package ovsdb
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Server 内存版 OVSDB 服务端，实现 list_dbs、get_schema、transact、monitor、
+// monitor_cancel、echo，按 schema 做类型归一化、引用完整性检查和非根表垃圾回收，
+// 用于在没有 ovsdb-server 的环境中联调客户端和 service 层
+type Server struct {
+	schema *DatabaseSchema
+
+	mu     sync.Mutex
+	tables map[string]map[string]Row
+	conns  map[*serverConn]struct{}
+	ln     net.Listener
+}
+
+// NewServer 基于 schema 创建空库；Open_vSwitch 库会预置根行，等价于 ovs-vsctl init
+func NewServer(schema *DatabaseSchema) *Server {
+	s := &Server{
+		schema: schema,
+		tables: make(map[string]map[string]Row, len(schema.Tables)),
+		conns:  make(map[*serverConn]struct{}),
+	}
+	for name := range schema.Tables {
+		s.tables[name] = make(map[string]Row)
+	}
+	if t, ok := schema.Tables["Open_vSwitch"]; ok {
+		row := Row{}
+		for cname, col := range t.Columns {
+			row[cname] = col.Default()
+		}
+		s.tables["Open_vSwitch"][newUUID()] = row
+	}
+	return s
+}
+
+// Listen 在指定地址上监听并在后台处理连接，endpoint 格式同 Dial
+func (s *Server) Listen(endpoint string) error {
+	network, address, err := ParseEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	go s.Serve(ln)
+	return nil
+}
+
+// Serve 在 listener 上接受连接，直到 listener 关闭
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.ln = ln
+	s.mu.Unlock()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// Addr 监听地址，格式可直接传给 Dial
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return ""
+	}
+	addr := s.ln.Addr()
+	return addr.Network() + ":" + addr.String()
+}
+
+// Close 关闭监听和所有连接
+func (s *Server) Close() error {
+	s.mu.Lock()
+	ln := s.ln
+	conns := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.conn.Close()
+	}
+	if ln != nil {
+		return ln.Close()
+	}
+	return nil
+}
+
+type serverMonitor struct {
+	id       json.RawMessage
+	requests map[string]MonitorRequest
+}
+
+type serverConn struct {
+	srv      *Server
+	conn     net.Conn
+	writeMu  sync.Mutex
+	enc      *json.Encoder
+	monitors map[string]*serverMonitor
+}
+
+// ServeConn 处理单个连接，可配合 net.Pipe 在进程内使用
+func (s *Server) ServeConn(conn net.Conn) {
+	sc := &serverConn{srv: s, conn: conn, enc: json.NewEncoder(conn), monitors: make(map[string]*serverMonitor)}
+	s.mu.Lock()
+	s.conns[sc] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, sc)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	dec := json.NewDecoder(conn)
+	for {
+		var msg rpcMessage
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		if msg.Method == "" {
+			// 客户端对 echo 的应答
+			continue
+		}
+		result, rpcErr := sc.dispatch(msg)
+		resp := map[string]interface{}{"id": msg.ID, "result": result, "error": nil}
+		if rpcErr != nil {
+			resp["result"] = nil
+			resp["error"] = rpcErr
+		}
+		sc.write(resp)
+	}
+}
+
+func (sc *serverConn) write(v interface{}) {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	sc.enc.Encode(v)
+}
+
+func (sc *serverConn) dispatch(msg rpcMessage) (interface{}, interface{}) {
+	var params []json.RawMessage
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, "invalid params"
+		}
+	}
+	s := sc.srv
+	switch msg.Method {
+	case "echo":
+		return params, nil
+	case "list_dbs":
+		return []string{s.schema.Name}, nil
+	case "get_schema":
+		if len(params) != 1 || !s.isDB(params[0]) {
+			return nil, "unknown database"
+		}
+		return s.schema, nil
+	case "transact":
+		if len(params) < 1 || !s.isDB(params[0]) {
+			return nil, "unknown database"
+		}
+		ops := make([]Operation, 0, len(params)-1)
+		for _, p := range params[1:] {
+			var op Operation
+			if err := json.Unmarshal(p, &op); err != nil {
+				return nil, fmt.Sprintf("invalid operation: %v", err)
+			}
+			ops = append(ops, op)
+		}
+		return s.transact(ops), nil
+	case "monitor":
+		if len(params) != 3 || !s.isDB(params[0]) {
+			return nil, "invalid params"
+		}
+		requests, err := parseMonitorRequests(params[2])
+		if err != nil {
+			return nil, err.Error()
+		}
+		m := &serverMonitor{id: params[1], requests: requests}
+		s.mu.Lock()
+		sc.monitors[string(params[1])] = m
+		initial := s.initialUpdates(m)
+		s.mu.Unlock()
+		return initial, nil
+	case "monitor_cancel":
+		if len(params) != 1 {
+			return nil, "invalid params"
+		}
+		s.mu.Lock()
+		_, ok := sc.monitors[string(params[0])]
+		delete(sc.monitors, string(params[0]))
+		s.mu.Unlock()
+		if !ok {
+			return nil, "unknown monitor"
+		}
+		return map[string]interface{}{}, nil
+	}
+	return nil, fmt.Sprintf("unknown method %s", msg.Method)
+}
+
+func (s *Server) isDB(raw json.RawMessage) bool {
+	var db string
+	json.Unmarshal(raw, &db)
+	return db == s.schema.Name
+}
+
+func parseMonitorRequests(raw json.RawMessage) (map[string]MonitorRequest, error) {
+	var generic map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	res := make(map[string]MonitorRequest, len(generic))
+	for table, r := range generic {
+		var single MonitorRequest
+		if err := json.Unmarshal(r, &single); err == nil {
+			res[table] = single
+			continue
+		}
+		var multi []MonitorRequest
+		if err := json.Unmarshal(r, &multi); err != nil {
+			return nil, err
+		}
+		merged := MonitorRequest{}
+		for _, m := range multi {
+			merged.Columns = append(merged.Columns, m.Columns...)
+			if m.Select != nil {
+				merged.Select = m.Select
+			}
+		}
+		res[table] = merged
+	}
+	return res, nil
+}
+
+func selectEnabled(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+func (s *Server) initialUpdates(m *serverMonitor) TableUpdates {
+	updates := TableUpdates{}
+	for table, req := range m.requests {
+		if req.Select != nil && !selectEnabled(req.Select.Initial) {
+			continue
+		}
+		rows := s.tables[table]
+		if len(rows) == 0 {
+			continue
+		}
+		tu := make(map[string]RowUpdate, len(rows))
+		for uuid, row := range rows {
+			tu[uuid] = RowUpdate{New: projectRow(row, req.Columns)}
+		}
+		updates[table] = tu
+	}
+	return updates
+}
+
+// transact 在副本上依次执行操作，全部成功且通过完整性检查后才提交
+func (s *Server) transact(ops []Operation) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &txn{schema: s.schema, tables: make(map[string]map[string]Row, len(s.tables)), named: map[string]string{}}
+	for name, rows := range s.tables {
+		copied := make(map[string]Row, len(rows))
+		for uuid, row := range rows {
+			copied[uuid] = row
+		}
+		tx.tables[name] = copied
+	}
+
+	results := make([]interface{}, len(ops))
+	for i, op := range ops {
+		res, err := tx.apply(op)
+		if err != nil {
+			results[i] = err
+			return results
+		}
+		results[i] = res
+	}
+	if err := tx.commit(); err != nil {
+		return append(results, err)
+	}
+	old := s.tables
+	s.tables = tx.tables
+	s.notify(old)
+	return results
+}
+
+// notify 计算提交前后的差异并推送给所有 monitor
+func (s *Server) notify(old map[string]map[string]Row) {
+	for sc := range s.conns {
+		for _, m := range sc.monitors {
+			updates := TableUpdates{}
+			for table, req := range m.requests {
+				tu := diffTable(old[table], s.tables[table], req)
+				if len(tu) > 0 {
+					updates[table] = tu
+				}
+			}
+			if len(updates) > 0 {
+				sc.write(map[string]interface{}{"method": "update", "params": []interface{}{m.id, updates}, "id": nil})
+			}
+		}
+	}
+}
+
+func diffTable(before, after map[string]Row, req MonitorRequest) map[string]RowUpdate {
+	sel := req.Select
+	if sel == nil {
+		sel = &MonitorSelect{}
+	}
+	tu := map[string]RowUpdate{}
+	for uuid, row := range after {
+		prev, existed := before[uuid]
+		switch {
+		case !existed:
+			if selectEnabled(sel.Insert) {
+				tu[uuid] = RowUpdate{New: projectRow(row, req.Columns)}
+			}
+		case !sameRow(prev, row):
+			if !selectEnabled(sel.Modify) {
+				continue
+			}
+			changed := Row{}
+			for col, v := range projectRow(prev, req.Columns) {
+				if !ValuesEqual(v, row[col]) {
+					changed[col] = v
+				}
+			}
+			if len(changed) > 0 {
+				tu[uuid] = RowUpdate{Old: changed, New: projectRow(row, req.Columns)}
+			}
+		}
+	}
+	for uuid, row := range before {
+		if _, ok := after[uuid]; !ok && selectEnabled(sel.Delete) {
+			tu[uuid] = RowUpdate{Old: projectRow(row, req.Columns)}
+		}
+	}
+	return tu
+}
+
+func sameRow(a, b Row) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if !ValuesEqual(v, b[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func projectRow(row Row, columns []string) Row {
+	out := Row{}
+	if len(columns) == 0 {
+		for k, v := range row {
+			out[k] = v
+		}
+		return out
+	}
+	for _, c := range columns {
+		if v, ok := row[c]; ok {
+			out[c] = v
+		}
+	}
+	return out
+}
+
+type opError struct {
+	Error   string `json:"error"`
+	Details string `json:"details,omitempty"`
+}
+
+func newOpError(kind, format string, args ...interface{}) *opError {
+	return &opError{Error: kind, Details: fmt.Sprintf(format, args...)}
+}
+
+type txn struct {
+	schema *DatabaseSchema
+	tables map[string]map[string]Row
+	named  map[string]string
+}
+
+func (t *txn) table(name string) (TableSchema, map[string]Row, *opError) {
+	ts, ok := t.schema.Tables[name]
+	if !ok {
+		return TableSchema{}, nil, newOpError("unknown table", "table %s", name)
+	}
+	return ts, t.tables[name], nil
+}
+
+func (t *txn) apply(op Operation) (interface{}, *opError) {
+	switch op.Op {
+	case "comment", "commit", "assert":
+		return map[string]interface{}{}, nil
+	case "abort":
+		return nil, newOpError("aborted", "aborted by request")
+	}
+	ts, rows, err := t.table(op.Table)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "insert":
+		uuid := newUUID()
+		row := Row{}
+		for cname, col := range ts.Columns {
+			row[cname] = col.Default()
+		}
+		if err := t.setColumns(ts, row, op.Row); err != nil {
+			return nil, err
+		}
+		if op.UUIDName != "" {
+			t.named[op.UUIDName] = uuid
+		}
+		rows[uuid] = row
+		return map[string]interface{}{"uuid": UUID{GoUUID: uuid}}, nil
+	case "select":
+		matched, err := t.match(ts, rows, op.Where)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]Row, 0, len(matched))
+		for _, uuid := range matched {
+			out = append(out, projectRow(withMeta(uuid, rows[uuid]), op.Columns))
+		}
+		return map[string]interface{}{"rows": out}, nil
+	case "update":
+		matched, err := t.match(ts, rows, op.Where)
+		if err != nil {
+			return nil, err
+		}
+		for _, uuid := range matched {
+			row := cloneRow(rows[uuid])
+			if err := t.setColumns(ts, row, op.Row); err != nil {
+				return nil, err
+			}
+			rows[uuid] = row
+		}
+		return map[string]interface{}{"count": len(matched)}, nil
+	case "mutate":
+		matched, err := t.match(ts, rows, op.Where)
+		if err != nil {
+			return nil, err
+		}
+		for _, uuid := range matched {
+			row := cloneRow(rows[uuid])
+			for _, m := range op.Mutations {
+				if err := t.mutate(ts, row, m); err != nil {
+					return nil, err
+				}
+			}
+			rows[uuid] = row
+		}
+		return map[string]interface{}{"count": len(matched)}, nil
+	case "delete":
+		matched, err := t.match(ts, rows, op.Where)
+		if err != nil {
+			return nil, err
+		}
+		for _, uuid := range matched {
+			delete(rows, uuid)
+		}
+		return map[string]interface{}{"count": len(matched)}, nil
+	case "wait":
+		matched, err := t.match(ts, rows, op.Where)
+		if err != nil {
+			return nil, err
+		}
+		got := make([]Row, 0, len(matched))
+		for _, uuid := range matched {
+			got = append(got, projectRow(rows[uuid], op.Columns))
+		}
+		equal := len(got) == len(op.Rows)
+		for i := 0; equal && i < len(got); i++ {
+			equal = sameRow(got[i], projectRow(op.Rows[i], op.Columns))
+		}
+		if (op.Until == "==") != equal {
+			return nil, newOpError("timed out", "wait condition not met")
+		}
+		return map[string]interface{}{}, nil
+	}
+	return nil, newOpError("not supported", "operation %s", op.Op)
+}
+
+func withMeta(uuid string, row Row) Row {
+	out := cloneRow(row)
+	out["_uuid"] = UUID{GoUUID: uuid}
+	out["_version"] = UUID{GoUUID: uuid}
+	return out
+}
+
+func cloneRow(row Row) Row {
+	out := make(Row, len(row)+2)
+	for k, v := range row {
+		out[k] = v
+	}
+	return out
+}
+
+func (t *txn) setColumns(ts TableSchema, row Row, values Row) *opError {
+	for cname, v := range values {
+		col, ok := ts.Columns[cname]
+		if !ok {
+			return newOpError("constraint violation", "unknown column %s", cname)
+		}
+		nv, err := t.normalize(col, v)
+		if err != nil {
+			return err
+		}
+		row[cname] = nv
+	}
+	return nil
+}
+
+// normalize 解析 named-uuid 并按列类型统一为原子值、OvsSet 或 OvsMap
+func (t *txn) normalize(col *ColumnSchema, v interface{}) (interface{}, *opError) {
+	v = t.resolve(v)
+	if col.IsMap() {
+		m, ok := v.(OvsMap)
+		if !ok {
+			return nil, newOpError("constraint violation", "expected map, got %v", v)
+		}
+		return m, nil
+	}
+	set, isSet := v.(OvsSet)
+	if col.Atomic() {
+		if isSet {
+			if len(set.GoSet) != 1 {
+				return nil, newOpError("constraint violation", "expected one value, got %d", len(set.GoSet))
+			}
+			return set.GoSet[0], nil
+		}
+		return v, nil
+	}
+	if !isSet {
+		set = OvsSet{GoSet: []interface{}{v}}
+	}
+	if col.Max > 0 && len(set.GoSet) > col.Max {
+		return nil, newOpError("constraint violation", "too many elements")
+	}
+	return set, nil
+}
+
+func (t *txn) resolve(v interface{}) interface{} {
+	switch x := v.(type) {
+	case NamedUUID:
+		if uuid, ok := t.named[x.Name]; ok {
+			return UUID{GoUUID: uuid}
+		}
+		return x
+	case OvsSet:
+		out := OvsSet{GoSet: make([]interface{}, len(x.GoSet))}
+		for i, e := range x.GoSet {
+			out.GoSet[i] = t.resolve(e)
+		}
+		return out
+	case OvsMap:
+		out := OvsMap{GoMap: make(map[interface{}]interface{}, len(x.GoMap))}
+		for k, e := range x.GoMap {
+			out.GoMap[t.resolve(k)] = t.resolve(e)
+		}
+		return out
+	}
+	return v
+}
+
+func (t *txn) match(ts TableSchema, rows map[string]Row, where []Condition) ([]string, *opError) {
+	uuids := make([]string, 0, len(rows))
+	for uuid := range rows {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	matched := uuids[:0]
+	for _, uuid := range uuids {
+		row := rows[uuid]
+		ok := true
+		for _, c := range where {
+			var actual interface{}
+			var col *ColumnSchema
+			if c.Column == "_uuid" {
+				actual = UUID{GoUUID: uuid}
+				col = &ColumnSchema{Key: BaseType{Type: "uuid"}, Min: 1, Max: 1}
+			} else {
+				var exists bool
+				col, exists = ts.Columns[c.Column]
+				if !exists {
+					return nil, newOpError("syntax error", "unknown column %s", c.Column)
+				}
+				actual = row[c.Column]
+			}
+			want, err := t.normalize(col, c.Value)
+			if err != nil {
+				return nil, err
+			}
+			res, err := evalCondition(c.Function, actual, want)
+			if err != nil {
+				return nil, err
+			}
+			if !res {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, uuid)
+		}
+	}
+	return matched, nil
+}
+
+func evalCondition(fn string, actual, want interface{}) (bool, *opError) {
+	switch fn {
+	case "==":
+		return ValuesEqual(actual, want), nil
+	case "!=":
+		return !ValuesEqual(actual, want), nil
+	case "includes":
+		return includes(actual, want), nil
+	case "excludes":
+		return excludes(actual, want), nil
+	case "<", "<=", ">", ">=":
+		a, ok1 := actual.(float64)
+		b, ok2 := want.(float64)
+		if !ok1 || !ok2 {
+			return false, newOpError("constraint violation", "%s requires numbers", fn)
+		}
+		switch fn {
+		case "<":
+			return a < b, nil
+		case "<=":
+			return a <= b, nil
+		case ">":
+			return a > b, nil
+		}
+		return a >= b, nil
+	}
+	return false, newOpError("syntax error", "unknown function %s", fn)
+}
+
+// ValuesEqual 比较两个已解码的列值，集合和映射不区分元素顺序
+func ValuesEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case OvsSet:
+		y, ok := b.(OvsSet)
+		return ok && len(x.GoSet) == len(y.GoSet) && includes(x, y)
+	case OvsMap:
+		y, ok := b.(OvsMap)
+		if !ok || len(x.GoMap) != len(y.GoMap) {
+			return false
+		}
+		for k, v := range x.GoMap {
+			if w, ok := y.GoMap[k]; !ok || w != v {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func includes(actual, want interface{}) bool {
+	switch x := actual.(type) {
+	case OvsSet:
+		y, ok := want.(OvsSet)
+		if !ok {
+			return setContains(x, want)
+		}
+		for _, e := range y.GoSet {
+			if !setContains(x, e) {
+				return false
+			}
+		}
+		return true
+	case OvsMap:
+		y, ok := want.(OvsMap)
+		if !ok {
+			return false
+		}
+		for k, v := range y.GoMap {
+			if w, ok := x.GoMap[k]; !ok || w != v {
+				return false
+			}
+		}
+		return true
+	}
+	return ValuesEqual(actual, want)
+}
+
+func excludes(actual, want interface{}) bool {
+	switch x := actual.(type) {
+	case OvsSet:
+		y, ok := want.(OvsSet)
+		if !ok {
+			return !setContains(x, want)
+		}
+		for _, e := range y.GoSet {
+			if setContains(x, e) {
+				return false
+			}
+		}
+		return true
+	case OvsMap:
+		y, ok := want.(OvsMap)
+		if !ok {
+			return true
+		}
+		for k, v := range y.GoMap {
+			if w, ok := x.GoMap[k]; ok && w == v {
+				return false
+			}
+		}
+		return true
+	}
+	return !ValuesEqual(actual, want)
+}
+
+func setContains(s OvsSet, e interface{}) bool {
+	for _, x := range s.GoSet {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *txn) mutate(ts TableSchema, row Row, m Mutation) *opError {
+	col, ok := ts.Columns[m.Column]
+	if !ok {
+		return newOpError("syntax error", "unknown column %s", m.Column)
+	}
+	value := t.resolve(m.Value)
+	switch m.Mutator {
+	case "+=", "-=", "*=", "/=", "%=":
+		delta, ok := value.(float64)
+		if !ok {
+			return newOpError("constraint violation", "%s requires a number", m.Mutator)
+		}
+		apply := func(v interface{}) (interface{}, *opError) {
+			x, ok := v.(float64)
+			if !ok {
+				return nil, newOpError("constraint violation", "%s on non-numeric column", m.Mutator)
+			}
+			switch m.Mutator {
+			case "+=":
+				return x + delta, nil
+			case "-=":
+				return x - delta, nil
+			case "*=":
+				return x * delta, nil
+			case "/=", "%=":
+				if delta == 0 {
+					return nil, newOpError("domain error", "division by zero")
+				}
+				if m.Mutator == "/=" {
+					return float64(int64(x) / int64(delta)), nil
+				}
+				return float64(int64(x) % int64(delta)), nil
+			}
+			return x, nil
+		}
+		if set, ok := row[m.Column].(OvsSet); ok {
+			out := OvsSet{GoSet: make([]interface{}, len(set.GoSet))}
+			for i, e := range set.GoSet {
+				nv, err := apply(e)
+				if err != nil {
+					return err
+				}
+				out.GoSet[i] = nv
+			}
+			row[m.Column] = out
+			return nil
+		}
+		nv, err := apply(row[m.Column])
+		if err != nil {
+			return err
+		}
+		row[m.Column] = nv
+		return nil
+	case "insert":
+		if col.IsMap() {
+			cur, _ := row[m.Column].(OvsMap)
+			add, ok := value.(OvsMap)
+			if !ok {
+				return newOpError("constraint violation", "insert into map requires a map")
+			}
+			out := OvsMap{GoMap: make(map[interface{}]interface{}, len(cur.GoMap)+len(add.GoMap))}
+			for k, v := range cur.GoMap {
+				out.GoMap[k] = v
+			}
+			for k, v := range add.GoMap {
+				if _, exists := out.GoMap[k]; !exists {
+					out.GoMap[k] = v
+				}
+			}
+			row[m.Column] = out
+			return nil
+		}
+		cur, _ := row[m.Column].(OvsSet)
+		add, ok := value.(OvsSet)
+		if !ok {
+			add = OvsSet{GoSet: []interface{}{value}}
+		}
+		out := OvsSet{GoSet: append([]interface{}{}, cur.GoSet...)}
+		for _, e := range add.GoSet {
+			if !setContains(out, e) {
+				out.GoSet = append(out.GoSet, e)
+			}
+		}
+		if col.Max > 0 && len(out.GoSet) > col.Max {
+			return newOpError("constraint violation", "too many elements")
+		}
+		row[m.Column] = out
+		return nil
+	case "delete":
+		if col.IsMap() {
+			cur, _ := row[m.Column].(OvsMap)
+			out := OvsMap{GoMap: make(map[interface{}]interface{}, len(cur.GoMap))}
+			for k, v := range cur.GoMap {
+				out.GoMap[k] = v
+			}
+			switch del := value.(type) {
+			case OvsMap:
+				for k, v := range del.GoMap {
+					if out.GoMap[k] == v {
+						delete(out.GoMap, k)
+					}
+				}
+			case OvsSet:
+				for _, k := range del.GoSet {
+					delete(out.GoMap, k)
+				}
+			default:
+				delete(out.GoMap, del)
+			}
+			row[m.Column] = out
+			return nil
+		}
+		cur, _ := row[m.Column].(OvsSet)
+		del, ok := value.(OvsSet)
+		if !ok {
+			del = OvsSet{GoSet: []interface{}{value}}
+		}
+		out := OvsSet{GoSet: []interface{}{}}
+		for _, e := range cur.GoSet {
+			if !setContains(del, e) {
+				out.GoSet = append(out.GoSet, e)
+			}
+		}
+		row[m.Column] = out
+		return nil
+	}
+	return newOpError("syntax error", "unknown mutator %s", m.Mutator)
+}
+
+// commit 检查强引用完整性、清理悬空弱引用并回收不可达的非根表行
+func (t *txn) commit() *opError {
+	for {
+		reachable := map[string]bool{}
+		var visit func(table, uuid string)
+		visit = func(table, uuid string) {
+			if reachable[uuid] {
+				return
+			}
+			reachable[uuid] = true
+			ts := t.schema.Tables[table]
+			for cname, col := range ts.Columns {
+				for _, ref := range refsOf(col, t.tables[table][uuid][cname]) {
+					if ref.weak {
+						continue
+					}
+					if _, ok := t.tables[ref.table][ref.uuid]; ok {
+						visit(ref.table, ref.uuid)
+					}
+				}
+			}
+		}
+		hasRoot := false
+		for name, ts := range t.schema.Tables {
+			if ts.IsRoot {
+				hasRoot = true
+				for uuid := range t.tables[name] {
+					visit(name, uuid)
+				}
+			}
+		}
+		removed := false
+		if hasRoot {
+			for name, ts := range t.schema.Tables {
+				if ts.IsRoot {
+					continue
+				}
+				for uuid := range t.tables[name] {
+					if !reachable[uuid] {
+						delete(t.tables[name], uuid)
+						removed = true
+					}
+				}
+			}
+		}
+		if !removed {
+			break
+		}
+	}
+
+	for name, ts := range t.schema.Tables {
+		for _, index := range ts.Indexes {
+			seen := map[string]string{}
+			for uuid, row := range t.tables[name] {
+				key, _ := json.Marshal(projectRow(row, index))
+				if other, dup := seen[string(key)]; dup {
+					return newOpError("constraint violation", "%s rows %s and %s have the same %v", name, other, uuid, index)
+				}
+				seen[string(key)] = uuid
+			}
+		}
+		for uuid, row := range t.tables[name] {
+			var updated Row
+			for cname, col := range ts.Columns {
+				for _, ref := range refsOf(col, row[cname]) {
+					if _, ok := t.tables[ref.table][ref.uuid]; ok {
+						continue
+					}
+					if !ref.weak {
+						return newOpError("referential integrity violation", "%s row %s column %s references missing %s row %s", name, uuid, cname, ref.table, ref.uuid)
+					}
+					if updated == nil {
+						updated = cloneRow(row)
+					}
+					updated[cname] = dropRef(updated[cname], ref.uuid)
+				}
+			}
+			if updated != nil {
+				t.tables[name][uuid] = updated
+			}
+		}
+	}
+	return nil
+}
+
+type rowRef struct {
+	table string
+	uuid  string
+	weak  bool
+}
+
+func refsOf(col *ColumnSchema, v interface{}) []rowRef {
+	var refs []rowRef
+	add := func(bt BaseType, x interface{}) {
+		if u, ok := x.(UUID); ok && bt.RefTable != "" {
+			refs = append(refs, rowRef{table: bt.RefTable, uuid: u.GoUUID, weak: bt.RefType == "weak"})
+		}
+	}
+	switch x := v.(type) {
+	case OvsSet:
+		for _, e := range x.GoSet {
+			add(col.Key, e)
+		}
+	case OvsMap:
+		for k, e := range x.GoMap {
+			add(col.Key, k)
+			add(col.Value, e)
+		}
+	default:
+		add(col.Key, x)
+	}
+	return refs
+}
+
+func dropRef(v interface{}, uuid string) interface{} {
+	target := UUID{GoUUID: uuid}
+	switch x := v.(type) {
+	case OvsSet:
+		out := OvsSet{GoSet: []interface{}{}}
+		for _, e := range x.GoSet {
+			if e != target {
+				out.GoSet = append(out.GoSet, e)
+			}
+		}
+		return out
+	case OvsMap:
+		out := OvsMap{GoMap: map[interface{}]interface{}{}}
+		for k, e := range x.GoMap {
+			if k != target && e != target {
+				out.GoMap[k] = e
+			}
+		}
+		return out
+	}
+	return v
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,447 @@
+// Package ovsdb 实现 RFC 7047 定义的 OVSDB JSON-RPC 协议客户端，
+// 直接与 ovsdb-server 通信，替代 fork ovs-vsctl 并解析文本输出的方式。
+package ovsdb
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UUID 行标识，JSON 表示为 ["uuid", "<uuid>"]
+type UUID struct {
+	GoUUID string
+}
+
+// MarshalJSON 编码为 ["uuid", "..."]
+func (u UUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{"uuid", u.GoUUID})
+}
+
+// UnmarshalJSON 解码 ["uuid", "..."]
+func (u *UUID) UnmarshalJSON(b []byte) error {
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	if len(arr) != 2 || arr[0] != "uuid" {
+		return fmt.Errorf("ovsdb: invalid uuid %s", string(b))
+	}
+	u.GoUUID = arr[1]
+	return nil
+}
+
+// NamedUUID 同一事务内引用 insert 行的临时名称，JSON 表示为 ["named-uuid", "<name>"]
+type NamedUUID struct {
+	Name string
+}
+
+// MarshalJSON 编码为 ["named-uuid", "..."]
+func (n NamedUUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{"named-uuid", n.Name})
+}
+
+// OvsSet 集合类型，JSON 表示为 ["set", [...]]
+type OvsSet struct {
+	GoSet []interface{}
+}
+
+// NewOvsSet 由元素列表构造集合
+func NewOvsSet(elems ...interface{}) OvsSet {
+	if elems == nil {
+		elems = []interface{}{}
+	}
+	return OvsSet{GoSet: elems}
+}
+
+// MarshalJSON 编码为 ["set", [...]]
+func (s OvsSet) MarshalJSON() ([]byte, error) {
+	elems := s.GoSet
+	if elems == nil {
+		elems = []interface{}{}
+	}
+	return json.Marshal([]interface{}{"set", elems})
+}
+
+// OvsMap 映射类型，JSON 表示为 ["map", [[k, v], ...]]
+type OvsMap struct {
+	GoMap map[interface{}]interface{}
+}
+
+// NewOvsMap 由字符串映射构造 OvsMap
+func NewOvsMap(m map[string]string) OvsMap {
+	gm := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		gm[k] = v
+	}
+	return OvsMap{GoMap: gm}
+}
+
+// MarshalJSON 编码为 ["map", [[k, v], ...]]
+func (m OvsMap) MarshalJSON() ([]byte, error) {
+	pairs := make([][]interface{}, 0, len(m.GoMap))
+	for k, v := range m.GoMap {
+		pairs = append(pairs, []interface{}{k, v})
+	}
+	return json.Marshal([]interface{}{"map", pairs})
+}
+
+// DecodeValue 将 JSON 解码后的 OVSDB 值转换为 Go 类型：
+// ["uuid", x] -> UUID，["named-uuid", x] -> NamedUUID，
+// ["set", [...]] -> OvsSet，["map", [...]] -> OvsMap，其余原子值原样返回
+func DecodeValue(v interface{}) (interface{}, error) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return v, nil
+	}
+	if len(arr) != 2 {
+		return nil, fmt.Errorf("ovsdb: invalid value %v", v)
+	}
+	tag, _ := arr[0].(string)
+	switch tag {
+	case "uuid":
+		s, _ := arr[1].(string)
+		return UUID{GoUUID: s}, nil
+	case "named-uuid":
+		s, _ := arr[1].(string)
+		return NamedUUID{Name: s}, nil
+	case "set":
+		elems, ok := arr[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ovsdb: invalid set %v", v)
+		}
+		set := OvsSet{GoSet: make([]interface{}, 0, len(elems))}
+		for _, e := range elems {
+			d, err := DecodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			set.GoSet = append(set.GoSet, d)
+		}
+		return set, nil
+	case "map":
+		pairs, ok := arr[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ovsdb: invalid map %v", v)
+		}
+		m := OvsMap{GoMap: make(map[interface{}]interface{}, len(pairs))}
+		for _, p := range pairs {
+			kv, ok := p.([]interface{})
+			if !ok || len(kv) != 2 {
+				return nil, fmt.Errorf("ovsdb: invalid map pair %v", p)
+			}
+			k, err := DecodeValue(kv[0])
+			if err != nil {
+				return nil, err
+			}
+			val, err := DecodeValue(kv[1])
+			if err != nil {
+				return nil, err
+			}
+			m.GoMap[k] = val
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("ovsdb: unknown value tag %q", tag)
+}
+
+// Row 表中的一行，列名 -> 解码后的值
+type Row map[string]interface{}
+
+// UnmarshalJSON 解码行并将所有列转换为 Go 类型
+func (r *Row) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	row := make(Row, len(raw))
+	for k, v := range raw {
+		d, err := DecodeValue(v)
+		if err != nil {
+			return err
+		}
+		row[k] = d
+	}
+	*r = row
+	return nil
+}
+
+// String 取字符串列，缺失时返回空串
+func (r Row) String(column string) string {
+	s, _ := r[column].(string)
+	return s
+}
+
+// Int 取整数列，空集合视为 0
+func (r Row) Int(column string) int {
+	switch v := r[column].(type) {
+	case float64:
+		return int(v)
+	case OvsSet:
+		if len(v.GoSet) == 1 {
+			f, _ := v.GoSet[0].(float64)
+			return int(f)
+		}
+	}
+	return 0
+}
+
+// UUID 取行的 _uuid 或其它 uuid 列
+func (r Row) UUID(column string) string {
+	switch v := r[column].(type) {
+	case UUID:
+		return v.GoUUID
+	case OvsSet:
+		if len(v.GoSet) == 1 {
+			if u, ok := v.GoSet[0].(UUID); ok {
+				return u.GoUUID
+			}
+		}
+	}
+	return ""
+}
+
+// Set 取集合列，单个原子值视为只含一个元素的集合
+func (r Row) Set(column string) []interface{} {
+	switch v := r[column].(type) {
+	case nil:
+		return nil
+	case OvsSet:
+		return v.GoSet
+	default:
+		return []interface{}{v}
+	}
+}
+
+// Bool 取布尔列，可选列为空时返回 false
+func (r Row) Bool(column string) bool {
+	for _, v := range r.Set(column) {
+		b, _ := v.(bool)
+		return b
+	}
+	return false
+}
+
+// OptionalInt 取可选整数列（min 0, max 1），为空时返回 nil
+func (r Row) OptionalInt(column string) *int {
+	for _, v := range r.Set(column) {
+		if f, ok := v.(float64); ok {
+			i := int(f)
+			return &i
+		}
+	}
+	return nil
+}
+
+// OptionalString 取可选字符串列，为空时返回空串
+func (r Row) OptionalString(column string) string {
+	for _, v := range r.Set(column) {
+		s, _ := v.(string)
+		return s
+	}
+	return ""
+}
+
+// Strings 取字符串集合列
+func (r Row) Strings(column string) []string {
+	set := r.Set(column)
+	res := make([]string, 0, len(set))
+	for _, v := range set {
+		if s, ok := v.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// Ints 取整数集合列
+func (r Row) Ints(column string) []int {
+	set := r.Set(column)
+	res := make([]int, 0, len(set))
+	for _, v := range set {
+		if f, ok := v.(float64); ok {
+			res = append(res, int(f))
+		}
+	}
+	return res
+}
+
+// UUIDs 取 uuid 集合列
+func (r Row) UUIDs(column string) []string {
+	set := r.Set(column)
+	res := make([]string, 0, len(set))
+	for _, v := range set {
+		if u, ok := v.(UUID); ok {
+			res = append(res, u.GoUUID)
+		}
+	}
+	return res
+}
+
+// IntMap 取 string->integer 映射列，如 statistics
+func (r Row) IntMap(column string) map[string]int64 {
+	m, ok := r[column].(OvsMap)
+	if !ok {
+		return map[string]int64{}
+	}
+	res := make(map[string]int64, len(m.GoMap))
+	for k, v := range m.GoMap {
+		if f, ok := v.(float64); ok {
+			res[fmt.Sprint(k)] = int64(f)
+		}
+	}
+	return res
+}
+
+// StringMap 取 map 列并转换为字符串映射
+func (r Row) StringMap(column string) map[string]string {
+	m, ok := r[column].(OvsMap)
+	if !ok {
+		return map[string]string{}
+	}
+	res := make(map[string]string, len(m.GoMap))
+	for k, v := range m.GoMap {
+		res[fmt.Sprint(k)] = fmt.Sprint(v)
+	}
+	return res
+}
+
+// Condition where 子句中的条件，JSON 表示为 [column, function, value]
+type Condition struct {
+	Column   string
+	Function string
+	Value    interface{}
+}
+
+// NewCondition 构造条件
+func NewCondition(column, function string, value interface{}) Condition {
+	return Condition{Column: column, Function: function, Value: value}
+}
+
+// MarshalJSON 编码为三元组
+func (c Condition) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{c.Column, c.Function, c.Value})
+}
+
+// UnmarshalJSON 解码三元组
+func (c *Condition) UnmarshalJSON(b []byte) error {
+	var arr []interface{}
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	if len(arr) != 3 {
+		return fmt.Errorf("ovsdb: invalid condition %s", string(b))
+	}
+	c.Column, _ = arr[0].(string)
+	c.Function, _ = arr[1].(string)
+	v, err := DecodeValue(arr[2])
+	if err != nil {
+		return err
+	}
+	c.Value = v
+	return nil
+}
+
+// Mutation mutate 操作中的变更，JSON 表示为 [column, mutator, value]
+type Mutation struct {
+	Column  string
+	Mutator string
+	Value   interface{}
+}
+
+// NewMutation 构造变更
+func NewMutation(column, mutator string, value interface{}) Mutation {
+	return Mutation{Column: column, Mutator: mutator, Value: value}
+}
+
+// MarshalJSON 编码为三元组
+func (m Mutation) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{m.Column, m.Mutator, m.Value})
+}
+
+// UnmarshalJSON 解码三元组
+func (m *Mutation) UnmarshalJSON(b []byte) error {
+	var arr []interface{}
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	if len(arr) != 3 {
+		return fmt.Errorf("ovsdb: invalid mutation %s", string(b))
+	}
+	m.Column, _ = arr[0].(string)
+	m.Mutator, _ = arr[1].(string)
+	v, err := DecodeValue(arr[2])
+	if err != nil {
+		return err
+	}
+	m.Value = v
+	return nil
+}
+
+// Operation transact 中的单个操作（insert/select/update/mutate/delete/wait/comment 等）
+type Operation struct {
+	Op        string      `json:"op"`
+	Table     string      `json:"table,omitempty"`
+	Row       Row         `json:"row,omitempty"`
+	Rows      []Row       `json:"rows,omitempty"`
+	Columns   []string    `json:"columns,omitempty"`
+	Mutations []Mutation  `json:"mutations,omitempty"`
+	Timeout   *int        `json:"timeout,omitempty"`
+	Where     []Condition `json:"where,omitempty"`
+	Until     string      `json:"until,omitempty"`
+	UUIDName  string      `json:"uuid-name,omitempty"`
+	Comment   string      `json:"comment,omitempty"`
+	Durable   *bool       `json:"durable,omitempty"`
+}
+
+// MarshalJSON select/delete/update/mutate 必须携带 where，即使为空
+func (o Operation) MarshalJSON() ([]byte, error) {
+	type alias Operation
+	raw, err := json.Marshal(alias(o))
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case "select", "update", "mutate", "delete", "wait":
+		if o.Where == nil {
+			var m map[string]interface{}
+			if err := json.Unmarshal(raw, &m); err != nil {
+				return nil, err
+			}
+			m["where"] = []interface{}{}
+			return json.Marshal(m)
+		}
+	}
+	return raw, nil
+}
+
+// OperationResult 单个操作的结果
+type OperationResult struct {
+	Count   int    `json:"count,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Details string `json:"details,omitempty"`
+	UUID    *UUID  `json:"uuid,omitempty"`
+	Rows    []Row  `json:"rows,omitempty"`
+}
+
+// MonitorSelect 指定需要监听的变更类型
+type MonitorSelect struct {
+	Initial *bool `json:"initial,omitempty"`
+	Insert  *bool `json:"insert,omitempty"`
+	Delete  *bool `json:"delete,omitempty"`
+	Modify  *bool `json:"modify,omitempty"`
+}
+
+// MonitorRequest 单表的监听请求
+type MonitorRequest struct {
+	Columns []string       `json:"columns,omitempty"`
+	Select  *MonitorSelect `json:"select,omitempty"`
+}
+
+// RowUpdate 单行变更，old/new 缺省分别表示新增/删除
+type RowUpdate struct {
+	Old Row `json:"old,omitempty"`
+	New Row `json:"new,omitempty"`
+}
+
+// TableUpdates 表名 -> 行 uuid -> 变更
+type TableUpdates map[string]map[string]RowUpdate
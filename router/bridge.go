@@ -22,6 +22,12 @@ func RegisterBridgeRoutes(rg *gin.RouterGroup) {
 	rg.POST("/get-rstp", api.GetRstpHandler)           // 获取 RSTP 配置
 	rg.POST("/set-ipfix", api.SetIpfixHandler)         // 设置 IPFIX
 	rg.POST("/get-ipfix", api.GetIpfixHandler)         // 获取 IPFIX 配置
+	rg.POST("/bridge/disable-netflow", api.DisableNetFlowHandler) // 关闭 NetFlow
+	rg.POST("/bridge/disable-sflow", api.DisableSFlowHandler)     // 关闭 sFlow
+	rg.POST("/bridge/disable-ipfix", api.DisableIpfixHandler)     // 关闭网桥级 IPFIX
+	rg.POST("/bridge/collector-set/list", api.ListCollectorSetsHandler)   // 查询按流 IPFIX 收集器集合
+	rg.POST("/bridge/collector-set/set", api.SetCollectorSetHandler)      // 创建/修改按流 IPFIX 收集器集合
+	rg.POST("/bridge/collector-set/delete", api.DeleteCollectorSetHandler) // 删除按流 IPFIX 收集器集合
 	rg.POST("/dump-flows", api.DumpFlowsHandler)       // 查询流缓存
 
 }
@@ -0,0 +1,11 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"ovs-manager/api"
+)
+
+// RegisterEventRoutes 注册 OVSDB 变更事件流路由
+func RegisterEventRoutes(rg *gin.RouterGroup) {
+	rg.GET("/events", api.EventsHandler) // SSE 事件流，支持 bridge/table 过滤
+}
@@ -9,5 +9,14 @@ import (
 func RegisterFlowRoutes(rg *gin.RouterGroup) {
 	rg.POST("/flow/list-v2", api.ListFlowsV2Handler)     // 查询流表规则
 	rg.POST("/flow/add-v2", api.AddFlowV2Handler)       // 添加流表规则
+	rg.POST("/flow/validate", api.ValidateFlowHandler)  // 校验流表规则
 	rg.POST("/flow/delete-v2", api.DeleteFlowV2Handler) // 删除流表规则
+	rg.POST("/flow/replace", api.ReplaceFlowsHandler)   // 声明式同步流表
+	rg.POST("/flow/trace", api.TraceFlowHandler)        // 报文跟踪
+	rg.POST("/flow/lint", api.LintFlowsHandler)         // 流表检查
+	rg.POST("/flow/store/list", api.ListStoredFlowsHandler)   // 查询存储的流表
+	rg.POST("/flow/store/drift", api.FlowStoreDriftHandler)   // 存储与已安装流表的差异
+	rg.POST("/flow/store/replay", api.ReplayFlowStoreHandler) // 重新下发存储的流表
+	rg.POST("/flow/export", api.ExportFlowsHandler)           // 导出流表、组表和计量器
+	rg.POST("/flow/import", api.ImportFlowsHandler)           // 导入流表，端口映射并报告冲突
 } 
\ No newline at end of file
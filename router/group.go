@@ -0,0 +1,15 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"ovs-manager/api"
+)
+
+// RegisterGroupRoutes 注册 OpenFlow 组表相关路由
+func RegisterGroupRoutes(rg *gin.RouterGroup) {
+	rg.POST("/group/add", api.AddGroupHandler)       // 新增组表项
+	rg.POST("/group/mod", api.ModGroupHandler)       // 修改组表项
+	rg.POST("/group/delete", api.DeleteGroupHandler) // 删除组表项（仍被引用时拒绝）
+	rg.POST("/group/list", api.ListGroupsHandler)    // 查询组表配置
+	rg.POST("/group/stats", api.GroupStatsHandler)   // 查询组表统计
+}
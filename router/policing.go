@@ -0,0 +1,18 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"ovs-manager/api"
+)
+
+// RegisterPolicingRoutes 注册入方向限速及 OpenFlow 计量器相关路由
+func RegisterPolicingRoutes(rg *gin.RouterGroup) {
+	rg.POST("/port/ingress-policing/set", api.SetIngressPolicingHandler) // 设置入方向限速
+	rg.POST("/port/ingress-policing/get", api.GetIngressPolicingHandler) // 查询入方向限速
+	rg.POST("/meter/add", api.AddMeterHandler)                // 新增计量器
+	rg.POST("/meter/mod", api.ModMeterHandler)                // 修改计量器
+	rg.POST("/meter/delete", api.DeleteMeterHandler)          // 删除计量器
+	rg.POST("/meter/list", api.ListMetersHandler)             // 查询计量器配置
+	rg.POST("/meter/stats", api.MeterStatsHandler)            // 查询计量器统计
+	rg.POST("/meter/add-flow", api.AddMeteredFlowHandler)     // 添加经过计量器的流表
+}
@@ -10,6 +10,8 @@ func RegisterPortRoutes(rg *gin.RouterGroup) {
 	rg.POST("/port/list", api.ListPortsHandler)        // 获取端口列表
 	rg.POST("/port/add", api.AddPortHandler)           // 新增端口（通用）
 	rg.POST("/port/delete", api.DeletePortHandler)     // 删除端口
+	rg.POST("/port/stats", api.GetInterfaceStatsHandler) // 接口状态与统计计数
+	rg.POST("/bridge/port-stats", api.ListBridgeInterfaceStatsHandler) // 网桥所有接口状态与统计计数
 
 	// 端口类型专用API
 	rg.POST("/port/add-normal", api.AddNormalPortHandler)     // 新增普通端口
@@ -35,6 +37,10 @@ func RegisterPortRoutes(rg *gin.RouterGroup) {
 	rg.POST("/set-cfm", api.SetCfmHandler) // 设置 CFM
 	rg.POST("/bridge/set-mcast-snooping", api.SetMcastSnoopingHandler) // 设置组播监听
 	rg.POST("/port/set-hfsc-qos", api.SetHfscQosHandler) // 设置 HFSC QoS
+	rg.POST("/port/impairment", api.SetImpairmentHandler) // 设置端口网络损伤（netem）
+	rg.GET("/port/impairment", api.GetImpairmentHandler) // 查询端口网络损伤
+	rg.DELETE("/port/impairment", api.ClearImpairmentHandler) // 清除端口网络损伤
+	rg.POST("/port/impairment/reapply", api.ReapplyImpairmentHandler) // 重新下发 tc 补充的 netem 参数
 	rg.POST("/bridge/set-datapath-type", api.SetDatapathTypeHandler) // 设置 datapath_type
 	rg.POST("/patch/add", api.AddPatchPortHandler) // 添加 patch 端口（peer可选）
 	rg.POST("/patch/add-without-peer", api.AddPatchPortWithoutPeerHandler) // 添加不设置对端的 patch 端口
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"ovs-manager/api"
+)
+
+// RegisterQosRoutes 注册 QoS/队列相关路由
+func RegisterQosRoutes(rg *gin.RouterGroup) {
+	rg.POST("/qos/list", api.ListQosHandler)          // 查询 QoS
+	rg.POST("/qos/create", api.CreateQosHandler)      // 创建 QoS
+	rg.POST("/qos/update", api.UpdateQosHandler)      // 修改 QoS
+	rg.POST("/qos/delete", api.DeleteQosHandler)      // 删除 QoS
+	rg.POST("/qos/attach", api.AttachQosHandler)      // 端口应用 QoS
+	rg.POST("/qos/detach", api.DetachQosHandler)      // 端口移除 QoS
+	rg.POST("/qos/cleanup", api.CleanupQosHandler)    // 清理未引用的 QoS/队列
+	rg.POST("/queue/list", api.ListQueuesHandler)     // 查询队列
+	rg.POST("/queue/create", api.CreateQueueHandler)  // 创建队列
+	rg.POST("/queue/update", api.UpdateQueueHandler)  // 修改队列
+	rg.POST("/queue/delete", api.DeleteQueueHandler)  // 删除队列
+}
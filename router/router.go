@@ -3,6 +3,7 @@ package router
 import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"ovs-manager/api"
 )
 
 // InitRouter 初始化 Gin 路由，汇总各功能模块
@@ -11,10 +12,12 @@ func InitRouter() *gin.Engine {
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"*"} // 允许所有源，也可以指定多个源，如：[]string{"http://localhost:8080", "http://example.com"}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Cookie"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Cookie", api.TimeoutHeader}
 	config.AllowCredentials = true
 
 	r.Use(cors.New(config))
+	r.Use(api.RequestMetrics())
+	r.Use(api.RequestTimeout())
 
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -22,10 +25,17 @@ func InitRouter() *gin.Engine {
 		})
 	})
 
+	r.GET("/metrics", api.MetricsHandler) // Prometheus 指标
+
 	ovs := r.Group("/api/ovs")
 	RegisterBridgeRoutes(ovs)
 	RegisterPortRoutes(ovs)
 	RegisterMirrorRoutes(ovs)
+	RegisterQosRoutes(ovs)
+	RegisterPolicingRoutes(ovs)
+	RegisterGroupRoutes(ovs)
+	RegisterTelemetryRoutes(ovs)
+	RegisterEventRoutes(ovs)
 	RegisterFlowRoutes(ovs)
 	RegisterVxlanRoutes(ovs)
 	RegisterBondRoutes(ovs)
@@ -33,11 +43,7 @@ func InitRouter() *gin.Engine {
 
 	RegisterNetnsRoutes(r)
 
-	r.POST("/api/ovs/show", func(c *gin.Context) {
-		// 详细状态接口仍在主路由注册
-		// 若需拆分可移至新文件
-		c.JSON(200, gin.H{"message": "TODO: OVSShowHandler"})
-	})
+	r.POST("/api/ovs/show", api.OVSShowHandler) // 整体状态快照
 
 	return r
 }
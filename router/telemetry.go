@@ -0,0 +1,14 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"ovs-manager/api"
+)
+
+// RegisterTelemetryRoutes 注册内嵌流量收集器查询路由
+func RegisterTelemetryRoutes(rg *gin.RouterGroup) {
+	rg.POST("/telemetry/top-talkers", api.TopTalkersHandler) // top talkers
+	rg.POST("/telemetry/ports", api.TrafficByPortHandler)    // 按端口统计流量
+	rg.POST("/telemetry/vlans", api.TrafficByVlanHandler)    // 按 VLAN 统计流量
+	rg.POST("/telemetry/stats", api.TelemetryStatsHandler)   // 收集器状态
+}
@@ -1,17 +1,16 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strings"
+	"sort"
 )
 
 // AddBond 新增 Bond 端口并设置属性
-func AddBond(bridge, bondName string, slaves []string, bondMode, lacp string, otherOptions map[string]string) error {
+func AddBond(ctx context.Context, bridge, bondName string, slaves []string, bondMode, lacp string, otherOptions map[string]string) error {
 	args := []string{"add-bond", bridge, bondName}
 	args = append(args, slaves...)
-	cmd := exec.Command("ovs-vsctl", args...)
-	if err := cmd.Run(); err != nil {
+	if err := run(ctx, "ovs-vsctl", args...); err != nil {
 		return err
 	}
 	setArgs := []string{"set", "port", bondName}
@@ -25,8 +24,7 @@ func AddBond(bridge, bondName string, slaves []string, bondMode, lacp string, ot
 		setArgs = append(setArgs, fmt.Sprintf("%s=%s", k, v))
 	}
 	if len(setArgs) > 3 {
-		cmd2 := exec.Command("ovs-vsctl", setArgs...)
-		if err := cmd2.Run(); err != nil {
+		if err := run(ctx, "ovs-vsctl", setArgs...); err != nil {
 			return err
 		}
 	}
@@ -34,7 +32,7 @@ func AddBond(bridge, bondName string, slaves []string, bondMode, lacp string, ot
 }
 
 // SetBond 设置 Bond 端口属性
-func SetBond(bondName, bondMode, lacp string, otherOptions map[string]string) error {
+func SetBond(ctx context.Context, bondName, bondMode, lacp string, otherOptions map[string]string) error {
 	setArgs := []string{"set", "port", bondName}
 	if bondMode != "" {
 		setArgs = append(setArgs, fmt.Sprintf("bond_mode=%s", bondMode))
@@ -46,8 +44,7 @@ func SetBond(bondName, bondMode, lacp string, otherOptions map[string]string) er
 		setArgs = append(setArgs, fmt.Sprintf("%s=%s", k, v))
 	}
 	if len(setArgs) > 3 {
-		cmd := exec.Command("ovs-vsctl", setArgs...)
-		if err := cmd.Run(); err != nil {
+		if err := run(ctx, "ovs-vsctl", setArgs...); err != nil {
 			return err
 		}
 	}
@@ -55,16 +52,16 @@ func SetBond(bondName, bondMode, lacp string, otherOptions map[string]string) er
 }
 
 // ShowBond 查询 Bond 详细状态
-func ShowBond(bondName string) (string, string, string, error) {
-	bondShow, err := exec.Command("ovs-appctl", "bond/show", bondName).CombinedOutput()
+func ShowBond(ctx context.Context, bondName string) (string, string, string, error) {
+	bondShow, err := runOutput(ctx, "ovs-appctl", "bond/show", bondName)
 	if err != nil {
 		return "", "", "", err
 	}
-	lacpShow, err := exec.Command("ovs-appctl", "lacp/show", bondName).CombinedOutput()
+	lacpShow, err := runOutput(ctx, "ovs-appctl", "lacp/show", bondName)
 	if err != nil {
 		return string(bondShow), "", "", err
 	}
-	portInfo, err := exec.Command("ovs-vsctl", "list", "port", bondName).CombinedOutput()
+	portInfo, err := runOutput(ctx, "ovs-vsctl", "list", "port", bondName)
 	if err != nil {
 		return string(bondShow), string(lacpShow), "", err
 	}
@@ -72,91 +69,47 @@ func ShowBond(bondName string) (string, string, string, error) {
 }
 
 // DeleteBond 删除 Bond 端口
-func DeleteBond(bridge, bondName string) error {
-	cmd := exec.Command("ovs-vsctl", "del-port", bridge, bondName)
-	return cmd.Run()
+func DeleteBond(ctx context.Context, bridge, bondName string) error {
+	return run(ctx, "ovs-vsctl", "del-port", bridge, bondName)
 }
 
-// ListBonds 返回所有 Bond 端口及其成员和模式
-func ListBonds() ([]map[string]interface{}, error) {
-	// 获取所有 port
-	out, err := exec.Command("ovs-vsctl", "list", "port").Output()
+// BondInfo Bond 端口及其成员
+type BondInfo struct {
+	Name    string   `json:"name"`
+	Mode    string   `json:"mode"`
+	Members []string `json:"members"`
+	Bridge  string   `json:"bridge"`
+}
+
+// ListBonds 返回所有 Bond 端口（多接口端口）及其成员、模式和所属网桥，一次批量查询完成
+func ListBonds(ctx context.Context) ([]BondInfo, error) {
+	tables, err := portInventory(ctx)
 	if err != nil {
 		return nil, err
 	}
-	lines := strings.Split(string(out), "\n")
-	var bonds []map[string]interface{}
-	var bond map[string]interface{}
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			if bond != nil && bond["name"] != nil && bond["bond_mode"] != nil && bond["interfaces"] != nil {
-				bonds = append(bonds, bond)
-			}
-			bond = nil
-			continue
-		}
-		if strings.HasPrefix(line, "_uuid") {
-			bond = make(map[string]interface{})
+	ifaces := indexByUUID(tables["Interface"])
+	bridgeOf := make(map[string]string)
+	for _, br := range tables["Bridge"] {
+		for _, id := range br.UUIDs("ports") {
+			bridgeOf[id] = br.String("name")
 		}
-		if bond == nil {
+	}
+	bonds := []BondInfo{}
+	for _, p := range tables["Port"] {
+		ids := p.UUIDs("interfaces")
+		mode := p.OptionalString("bond_mode")
+		if len(ids) < 2 && mode == "" {
 			continue
 		}
-		if strings.HasPrefix(line, "name") {
-			bond["name"] = strings.TrimSpace(strings.TrimPrefix(line, "name               : "))
-		}
-		if strings.HasPrefix(line, "bond_mode") {
-			bond["mode"] = strings.TrimSpace(strings.TrimPrefix(line, "bond_mode          : "))
-		}
-		if strings.HasPrefix(line, "interfaces") {
-			members := strings.TrimSpace(strings.TrimPrefix(line, "interfaces         : "))
-			members = strings.Trim(members, "[]")
-			if members != "" {
-				bond["members"] = strings.Split(members, ", ")
-			} else {
-				bond["members"] = []string{}
+		bond := BondInfo{Name: p.String("name"), Mode: mode, Members: []string{}, Bridge: bridgeOf[p.UUID("_uuid")]}
+		for _, id := range ids {
+			if iface, ok := ifaces[id]; ok {
+				bond.Members = append(bond.Members, iface.String("name"))
 			}
 		}
+		sort.Strings(bond.Members)
+		bonds = append(bonds, bond)
 	}
-	// 获取每个 Bond 所属的网桥
-	for _, bond := range bonds {
-		bondName := bond["name"].(string)
-		bridge, err := getBondBridge(bondName)
-		if err == nil {
-			bond["bridge"] = bridge
-		} else {
-			bond["bridge"] = ""
-		}
-	}
+	sort.Slice(bonds, func(i, j int) bool { return bonds[i].Name < bonds[j].Name })
 	return bonds, nil
 }
-
-// getBondBridge 获取 Bond 所属的网桥
-func getBondBridge(bondName string) (string, error) {
-	// 获取所有网桥
-	out, err := exec.Command("ovs-vsctl", "list", "bridge").Output()
-	if err != nil {
-		return "", err
-	}
-	lines := strings.Split(string(out), "\n")
-	var currentBridge string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "name") {
-			currentBridge = strings.TrimSpace(strings.TrimPrefix(line, "name               : "))
-		}
-		if strings.HasPrefix(line, "ports") {
-			ports := strings.TrimSpace(strings.TrimPrefix(line, "ports              : "))
-			ports = strings.Trim(ports, "[]")
-			if ports != "" {
-				portList := strings.Split(ports, ", ")
-				for _, port := range portList {
-					if port == bondName {
-						return currentBridge, nil
-					}
-				}
-			}
-		}
-	}
-	return "", fmt.Errorf("bond %s not found in any bridge", bondName)
-}
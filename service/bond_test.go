@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestListBonds(t *testing.T) {
+	fake := NewFakeRunner()
+	newPortFixture(2, 25).expectInventory(fake)
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+	bonds, err := ListBonds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BondInfo{
+		{Name: "br0-p24", Mode: "balance-slb", Members: []string{"br0-p24-a", "br0-p24-b"}, Bridge: "br0"},
+		{Name: "br1-p24", Mode: "balance-slb", Members: []string{"br1-p24-a", "br1-p24-b"}, Bridge: "br1"},
+	}
+	if !reflect.DeepEqual(bonds, want) {
+		t.Errorf("ListBonds = %+v, want %+v", bonds, want)
+	}
+}
+
+func TestListBondsReplay(t *testing.T) {
+	replay(t, "bonds.jsonl")
+	bonds, err := ListBonds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 单成员但配置了 bond_mode 的 bond1 与未配置模式的双接口 team0 也应列出
+	want := []BondInfo{
+		{Name: "bond0", Mode: "balance-tcp", Members: []string{"eth1", "eth2"}, Bridge: "br-ex"},
+		{Name: "bond1", Mode: "active-backup", Members: []string{"eth3"}, Bridge: "br-ex"},
+		{Name: "team0", Mode: "", Members: []string{"eth4", "eth5"}, Bridge: "br-int"},
+	}
+	if !reflect.DeepEqual(bonds, want) {
+		t.Errorf("ListBonds = %+v, want %+v", bonds, want)
+	}
+}
@@ -1,19 +1,27 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"strings"
+
+	"ovs-manager/ovsdb"
 )
 
 type Response struct {
 	Name string `json:"name"`
 }
 
-// ListBridges 调用 ovs-vsctl 列出所有 bridge
-func ListBridges() ([]Response, error) {
-	cmd := exec.Command("ovs-vsctl", "list-br")
-	output, err := cmd.Output()
+// ListBridges 列出所有 bridge，配置了 OVSDB 地址时直接查询数据库，否则调用 ovs-vsctl
+func ListBridges(ctx context.Context) ([]Response, error) {
+	ctx, cancel := WithDefaultTimeout(ctx)
+	defer cancel()
+	if client, err := ovsdbClient(ctx); err != nil {
+		return []Response{}, err
+	} else if client != nil {
+		return ovsdbListBridges(ctx, client)
+	}
+	output, err := runOutput(ctx, "ovs-vsctl", "list-br")
 	if err != nil {
 		return []Response{}, err
 	}
@@ -31,125 +39,112 @@ func ListBridges() ([]Response, error) {
 }
 
 // AddBridge 新增 bridge
-func AddBridge(name string) error {
-	cmd := exec.Command("ovs-vsctl", "add-br", name)
-	return cmd.Run()
+func AddBridge(ctx context.Context, name string) error {
+	ctx, cancel := WithDefaultTimeout(ctx)
+	defer cancel()
+	if client, err := ovsdbClient(ctx); err != nil {
+		return err
+	} else if client != nil {
+		return ovsdbAddBridge(ctx, client, name)
+	}
+	return run(ctx, "ovs-vsctl", "add-br", name)
 }
 
-// DeleteBridge 删除 bridge
-func DeleteBridge(name string) error {
-	cmd := exec.Command("ovs-vsctl", "del-br", name)
-	return cmd.Run()
+// DeleteBridge 删除 bridge，启用流表存储时同时删除该网桥的记录，避免同名网桥重建后被重新下发
+func DeleteBridge(ctx context.Context, name string) error {
+	ctx, cancel := WithDefaultTimeout(ctx)
+	defer cancel()
+	client, err := ovsdbClient(ctx)
+	if err != nil {
+		return err
+	}
+	if client != nil {
+		err = ovsdbDeleteBridge(ctx, client, name)
+	} else {
+		err = run(ctx, "ovs-vsctl", "del-br", name)
+	}
+	if err != nil {
+		return err
+	}
+	if store, _ := currentFlowStore(); store != nil {
+		return store.forget(name, func(Flow) bool { return true })
+	}
+	return nil
 }
 
-// SetNetFlow 设置 NetFlow
-func SetNetFlow(bridge, target string, engineID int) error {
-	args := []string{"set", "Bridge", bridge, fmt.Sprintf("netflow=@nf")}
-	nfArgs := []string{"--", "--id=@nf", "create", "NetFlow", fmt.Sprintf("targets=[\"%s\"]", target)}
+// SetNetFlow 设置 NetFlow，网桥已有 NetFlow 时原地修改
+func SetNetFlow(ctx context.Context, bridge, target string, engineID int) error {
+	cols := []string{fmt.Sprintf("targets=%s", ovsStringSet([]string{target}))}
 	if engineID != 0 {
-		nfArgs = append(nfArgs, fmt.Sprintf("engine_id=%d", engineID))
+		cols = append(cols, fmt.Sprintf("engine_id=%d", engineID))
 	}
-	cmd := exec.Command("ovs-vsctl", append(args, nfArgs...)...)
-	return cmd.Run()
+	return setBridgeExporter(ctx, bridge, "netflow", "NetFlow", cols, []string{"engine_id"})
 }
 
-// SetSFlow 设置 sFlow
-func SetSFlow(bridge string, targets []string, sampling, header, polling int, agent string) error {
-	targetStrs := make([]string, len(targets))
-	for i, t := range targets {
-		targetStrs[i] = fmt.Sprintf("\"%s\"", t)
-	}
-	args := []string{"set", "Bridge", bridge, fmt.Sprintf("sflow=@sf")}
-	sfArgs := []string{"--", "--id=@sf", "create", "sFlow", fmt.Sprintf("targets=[%s]", strings.Join(targetStrs, ","))}
+// SetSFlow 设置 sFlow，网桥已有 sFlow 时原地修改
+func SetSFlow(ctx context.Context, bridge string, targets []string, sampling, header, polling int, agent string) error {
+	cols := []string{fmt.Sprintf("targets=%s", ovsStringSet(targets))}
 	if sampling != 0 {
-		sfArgs = append(sfArgs, fmt.Sprintf("sampling=%d", sampling))
+		cols = append(cols, fmt.Sprintf("sampling=%d", sampling))
 	}
 	if header != 0 {
-		sfArgs = append(sfArgs, fmt.Sprintf("header=%d", header))
+		cols = append(cols, fmt.Sprintf("header=%d", header))
 	}
 	if polling != 0 {
-		sfArgs = append(sfArgs, fmt.Sprintf("polling=%d", polling))
+		cols = append(cols, fmt.Sprintf("polling=%d", polling))
 	}
 	if agent != "" {
-		sfArgs = append(sfArgs, fmt.Sprintf("agent=%s", agent))
+		cols = append(cols, fmt.Sprintf("agent=%s", agent))
 	}
-	cmd := exec.Command("ovs-vsctl", append(args, sfArgs...)...)
-	return cmd.Run()
+	return setBridgeExporter(ctx, bridge, "sflow", "sFlow", cols, []string{"sampling", "header", "polling", "agent"})
 }
 
 // SetStp 设置 STP
-func SetStp(bridge string, enable bool) error {
+func SetStp(ctx context.Context, bridge string, enable bool) error {
 	val := "false"
 	if enable {
 		val = "true"
 	}
-	cmd := exec.Command("ovs-vsctl", "set", "Bridge", bridge, fmt.Sprintf("stp_enable=%s", val))
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", "set", "Bridge", bridge, fmt.Sprintf("stp_enable=%s", val))
 }
 
-// SetQos 设置 QoS
-func SetQos(portName, qosType, maxRate string, queues map[string]string) error {
-	args := []string{"set", "port", portName, fmt.Sprintf("qos=@newqos")}
-	qosArgs := []string{"--", "--id=@newqos", "create", "qos", fmt.Sprintf("type=%s", qosType)}
-	if maxRate != "" {
-		qosArgs = append(qosArgs, fmt.Sprintf("other-config:max-rate=%s", maxRate))
-	}
-	if len(queues) > 0 {
-		queueStrs := make([]string, 0, len(queues))
-		for k, v := range queues {
-			queueStrs = append(queueStrs, fmt.Sprintf("%s=%s", k, v))
-		}
-		qosArgs = append(qosArgs, fmt.Sprintf("queues=%s", strings.Join(queueStrs, ",")))
-	}
-	cmd := exec.Command("ovs-vsctl", append(args, qosArgs...)...)
-	return cmd.Run()
+// SetQos 设置 QoS，queues 为队列号到 max-rate 的映射；端口原有的 QoS 及队列不再被引用时一并删除
+func SetQos(ctx context.Context, portName, qosType, maxRate string, queues map[string]string) error {
+	return replacePortQos(ctx, portName, QosSpec{Type: qosType, MaxRate: maxRate}, queues)
 }
 
 // SetRstp 设置 RSTP
-func SetRstp(bridge string, enable bool) error {
+func SetRstp(ctx context.Context, bridge string, enable bool) error {
 	val := "false"
 	if enable {
 		val = "true"
 	}
-	cmd := exec.Command("ovs-vsctl", "set", "Bridge", bridge, fmt.Sprintf("rstp_enable=%s", val))
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", "set", "Bridge", bridge, fmt.Sprintf("rstp_enable=%s", val))
 }
 
-// SetIpfix 设置 IPFIX
-func SetIpfix(bridge string, targets []string, sampling, obsDomainID, obsPointID int) error {
-	targetStrs := make([]string, len(targets))
-	for i, t := range targets {
-		targetStrs[i] = fmt.Sprintf("\"%s\"", t)
-	}
-	args := []string{"set", "Bridge", bridge, fmt.Sprintf("ipfix=@ipf")}
-	ipfixArgs := []string{"--", "--id=@ipf", "create", "IPFIX", fmt.Sprintf("targets=[%s]", strings.Join(targetStrs, ","))}
+// SetIpfix 设置 IPFIX，网桥已有 IPFIX 时原地修改
+func SetIpfix(ctx context.Context, bridge string, targets []string, sampling, obsDomainID, obsPointID int) error {
+	cols := []string{fmt.Sprintf("targets=%s", ovsStringSet(targets))}
 	if sampling != 0 {
-		ipfixArgs = append(ipfixArgs, fmt.Sprintf("sampling=%d", sampling))
+		cols = append(cols, fmt.Sprintf("sampling=%d", sampling))
 	}
 	if obsDomainID != 0 {
-		ipfixArgs = append(ipfixArgs, fmt.Sprintf("obs_domain_id=%d", obsDomainID))
+		cols = append(cols, fmt.Sprintf("obs_domain_id=%d", obsDomainID))
 	}
 	if obsPointID != 0 {
-		ipfixArgs = append(ipfixArgs, fmt.Sprintf("obs_point_id=%d", obsPointID))
+		cols = append(cols, fmt.Sprintf("obs_point_id=%d", obsPointID))
 	}
-	cmd := exec.Command("ovs-vsctl", append(args, ipfixArgs...)...)
-	return cmd.Run()
+	return setBridgeExporter(ctx, bridge, "ipfix", "IPFIX", cols, []string{"sampling", "obs_domain_id", "obs_point_id"})
 }
 
-// DumpFlows 查询流缓存
-func DumpFlows(bridge string) (string, error) {
-	cmd := exec.Command("ovs-ofctl", "dump-flows", bridge)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return string(output), nil
+// DumpFlows 查询流缓存，返回解析后的流表
+func DumpFlows(ctx context.Context, bridge string) ([]Flow, error) {
+	return dumpFlows(ctx, bridge)
 }
 
 // GetNetFlow 获取 NetFlow 配置
-func GetNetFlow(bridgeName string) (map[string]interface{}, error) {
-	cmd := exec.Command("ovs-vsctl", "get", "Bridge", bridgeName, "netflow")
-	output, err := cmd.Output()
+func GetNetFlow(ctx context.Context, bridgeName string) (map[string]interface{}, error) {
+	output, err := runOutput(ctx, "ovs-vsctl", "get", "Bridge", bridgeName, "netflow")
 	if err != nil {
 		// 如果没有配置，返回空配置
 		return map[string]interface{}{
@@ -167,8 +162,7 @@ func GetNetFlow(bridgeName string) (map[string]interface{}, error) {
 	}
 	
 	// 获取 NetFlow 详细信息
-	cmd = exec.Command("ovs-vsctl", "get", "NetFlow", netflowID, "targets")
-	targetsOutput, err := cmd.Output()
+	targetsOutput, err := runOutput(ctx, "ovs-vsctl", "get", "NetFlow", netflowID, "targets")
 	if err != nil {
 		return map[string]interface{}{
 			"target":   "",
@@ -176,8 +170,7 @@ func GetNetFlow(bridgeName string) (map[string]interface{}, error) {
 		}, nil
 	}
 	
-	cmd = exec.Command("ovs-vsctl", "get", "NetFlow", netflowID, "engine_id")
-	engineOutput, err := cmd.Output()
+	engineOutput, err := runOutput(ctx, "ovs-vsctl", "get", "NetFlow", netflowID, "engine_id")
 	
 	targets := strings.TrimSpace(string(targetsOutput))
 	engineID := 1
@@ -201,9 +194,8 @@ func GetNetFlow(bridgeName string) (map[string]interface{}, error) {
 }
 
 // GetSFlow 获取 sFlow 配置
-func GetSFlow(bridgeName string) (map[string]interface{}, error) {
-	cmd := exec.Command("ovs-vsctl", "get", "Bridge", bridgeName, "sflow")
-	output, err := cmd.Output()
+func GetSFlow(ctx context.Context, bridgeName string) (map[string]interface{}, error) {
+	output, err := runOutput(ctx, "ovs-vsctl", "get", "Bridge", bridgeName, "sflow")
 	if err != nil {
 		// 如果没有配置，返回默认配置
 		return map[string]interface{}{
@@ -236,8 +228,7 @@ func GetSFlow(bridgeName string) (map[string]interface{}, error) {
 	}
 	
 	// 获取 targets
-	cmd = exec.Command("ovs-vsctl", "get", "sFlow", sflowID, "targets")
-	targetsOutput, err := cmd.Output()
+	targetsOutput, err := runOutput(ctx, "ovs-vsctl", "get", "sFlow", sflowID, "targets")
 	if err == nil {
 		targets := strings.TrimSpace(string(targetsOutput))
 		if targets != "[]" && targets != "" {
@@ -264,11 +255,11 @@ func GetSFlow(bridgeName string) (map[string]interface{}, error) {
 	}
 	
 	for key, field := range fields {
-		cmd = exec.Command("ovs-vsctl", "get", "sFlow", sflowID, field)
-		output, err := cmd.Output()
+		output, err := runOutput(ctx, "ovs-vsctl", "get", "sFlow", sflowID, field)
 		if err == nil {
 			value := strings.TrimSpace(string(output))
-			if value != "" {
+			// 未设置的可选列输出 []，保留默认值
+			if value != "" && value != "[]" {
 				if key == "agent" {
 					config[key] = strings.Trim(value, "\"")
 				} else {
@@ -284,9 +275,8 @@ func GetSFlow(bridgeName string) (map[string]interface{}, error) {
 }
 
 // GetStp 获取 STP 配置
-func GetStp(bridgeName string) (map[string]interface{}, error) {
-	cmd := exec.Command("ovs-vsctl", "get", "Bridge", bridgeName, "stp_enable")
-	output, err := cmd.Output()
+func GetStp(ctx context.Context, bridgeName string) (map[string]interface{}, error) {
+	output, err := runOutput(ctx, "ovs-vsctl", "get", "Bridge", bridgeName, "stp_enable")
 	if err != nil {
 		return map[string]interface{}{
 			"enable": false,
@@ -300,9 +290,8 @@ func GetStp(bridgeName string) (map[string]interface{}, error) {
 }
 
 // GetRstp 获取 RSTP 配置
-func GetRstp(bridgeName string) (map[string]interface{}, error) {
-	cmd := exec.Command("ovs-vsctl", "get", "Bridge", bridgeName, "rstp_enable")
-	output, err := cmd.Output()
+func GetRstp(ctx context.Context, bridgeName string) (map[string]interface{}, error) {
+	output, err := runOutput(ctx, "ovs-vsctl", "get", "Bridge", bridgeName, "rstp_enable")
 	if err != nil {
 		return map[string]interface{}{
 			"enable": false,
@@ -316,9 +305,8 @@ func GetRstp(bridgeName string) (map[string]interface{}, error) {
 }
 
 // GetIpfix 获取 IPFIX 配置
-func GetIpfix(bridgeName string) (map[string]interface{}, error) {
-	cmd := exec.Command("ovs-vsctl", "get", "Bridge", bridgeName, "ipfix")
-	output, err := cmd.Output()
+func GetIpfix(ctx context.Context, bridgeName string) (map[string]interface{}, error) {
+	output, err := runOutput(ctx, "ovs-vsctl", "get", "Bridge", bridgeName, "ipfix")
 	if err != nil {
 		// 如果没有配置，返回默认配置
 		return map[string]interface{}{
@@ -348,8 +336,7 @@ func GetIpfix(bridgeName string) (map[string]interface{}, error) {
 	}
 	
 	// 获取 targets
-	cmd = exec.Command("ovs-vsctl", "get", "IPFIX", ipfixID, "targets")
-	targetsOutput, err := cmd.Output()
+	targetsOutput, err := runOutput(ctx, "ovs-vsctl", "get", "IPFIX", ipfixID, "targets")
 	if err == nil {
 		targets := strings.TrimSpace(string(targetsOutput))
 		if targets != "[]" && targets != "" {
@@ -375,11 +362,10 @@ func GetIpfix(bridgeName string) (map[string]interface{}, error) {
 	}
 	
 	for key, field := range fields {
-		cmd = exec.Command("ovs-vsctl", "get", "IPFIX", ipfixID, field)
-		output, err := cmd.Output()
+		output, err := runOutput(ctx, "ovs-vsctl", "get", "IPFIX", ipfixID, field)
 		if err == nil {
 			value := strings.TrimSpace(string(output))
-			if value != "" {
+			if value != "" && value != "[]" {
 				var intVal int
 				fmt.Sscanf(value, "%d", &intVal)
 				config[key] = intVal
@@ -390,72 +376,58 @@ func GetIpfix(bridgeName string) (map[string]interface{}, error) {
 	return config, nil
 }
 
-// GetQos 获取 QoS 配置
-func GetQos(bridgeName, portName string) (map[string]interface{}, error) {
-	cmd := exec.Command("ovs-vsctl", "get", "port", portName, "qos")
-	output, err := cmd.Output()
+// QosConfig 端口的 QoS 配置，未配置 QoS 时 Configured 为 false
+type QosConfig struct {
+	Port       string `json:"port"`
+	Configured bool   `json:"configured"`
+	MaxRate    string `json:"maxRate"`
+	QoSSnapshot
+}
+
+// GetQos 获取端口实际的 QoS 配置：类型、完整 other_config，以及每个队列对应 Queue 行的
+// min-rate、max-rate、burst、priority、dscp；bridgeName 非空时校验端口属于该网桥
+func GetQos(ctx context.Context, bridgeName, portName string) (*QosConfig, error) {
+	tables, err := queryTables(ctx,
+		tableQuery{Table: "Bridge", Columns: []string{"name", "ports"}},
+		tableQuery{Table: "Port", Columns: []string{"_uuid", "name", "qos"}},
+		tableQuery{Table: "QoS"},
+		tableQuery{Table: "Queue"},
+	)
 	if err != nil {
-		// 如果没有配置，返回默认配置
-		return map[string]interface{}{
-			"type":    "linux-htb",
-			"maxRate": "",
-			"queues":  map[string]interface{}{},
-		}, nil
+		return nil, err
 	}
-	
-	qosID := strings.TrimSpace(string(output))
-	if qosID == "[]" || qosID == "" {
-		return map[string]interface{}{
-			"type":    "linux-htb",
-			"maxRate": "",
-			"queues":  map[string]interface{}{},
-		}, nil
+	var port ovsdb.Row
+	for _, p := range tables["Port"] {
+		if p.String("name") == portName {
+			port = p
+		}
 	}
-	
-	// 获取 QoS 详细信息
-	config := map[string]interface{}{
-		"type":    "linux-htb",
-		"maxRate": "",
-		"queues":  map[string]interface{}{},
+	if port == nil {
+		return nil, errorf(CauseNotFound, "no port named %s", portName)
 	}
-	
-	// 获取 type
-	cmd = exec.Command("ovs-vsctl", "get", "qos", qosID, "type")
-	typeOutput, err := cmd.Output()
-	if err == nil {
-		qosType := strings.TrimSpace(string(typeOutput))
-		if qosType != "" {
-			config["type"] = strings.Trim(qosType, "\"")
+	if bridgeName != "" {
+		ports, err := bridgePorts(tables, bridgeName)
+		if err != nil {
+			return nil, err
 		}
-	}
-	
-	// 获取 max-rate
-	cmd = exec.Command("ovs-vsctl", "get", "qos", qosID, "other_config")
-	otherConfigOutput, err := cmd.Output()
-	if err == nil {
-		otherConfig := strings.TrimSpace(string(otherConfigOutput))
-		if otherConfig != "{}" && otherConfig != "" {
-			// 解析 other_config，查找 max-rate
-			if strings.Contains(otherConfig, "max-rate") {
-				// 简单解析，实际可能需要更复杂的解析逻辑
-				config["maxRate"] = "1000000" // 默认值，实际应该从 other_config 中解析
-			}
+		found := false
+		for _, p := range ports {
+			found = found || p.UUID("_uuid") == port.UUID("_uuid")
 		}
-	}
-	
-	// 获取 queues
-	cmd = exec.Command("ovs-vsctl", "get", "qos", qosID, "queues")
-	queuesOutput, err := cmd.Output()
-	if err == nil {
-		queues := strings.TrimSpace(string(queuesOutput))
-		if queues != "{}" && queues != "" {
-			// 解析 queues，这里简化处理
-			config["queues"] = map[string]interface{}{
-				"0": "1000000",
-				"1": "500000",
-			}
+		if !found {
+			return nil, errorf(CauseNotFound, "port %s not found on bridge %s", portName, bridgeName)
 		}
 	}
-	
+	config := &QosConfig{
+		Port:        portName,
+		QoSSnapshot: QoSSnapshot{OtherConfig: map[string]string{}, Queues: map[string]QueueSnapshot{}},
+	}
+	q, ok := indexByUUID(tables["QoS"])[port.UUID("qos")]
+	if !ok {
+		return config, nil
+	}
+	config.Configured = true
+	config.QoSSnapshot = *qosSnapshot(q, indexByUUID(tables["Queue"]))
+	config.MaxRate = config.OtherConfig["max-rate"]
 	return config, nil
 }
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func sflowDefaults() map[string]interface{} {
+	return map[string]interface{}{"targets": []string{}, "sampling": 1000, "header": 128, "polling": 30, "agent": ""}
+}
+
+func ipfixDefaults() map[string]interface{} {
+	return map[string]interface{}{"targets": []string{}, "sampling": 1000, "obsDomainID": 1, "obsPointID": 1}
+}
+
+func TestGetSFlow(t *testing.T) {
+	const id = "0f6b9c1e-2d3a-4e5f-8a7b-9c0d1e2f3a4b"
+	fake := NewFakeRunner().
+		Expect("ovs-vsctl get Bridge br0 sflow", id+"\n").
+		Expect("ovs-vsctl get sFlow "+id+" targets", "[\"192.168.1.100:6343\"]\n").
+		Expect("ovs-vsctl get sFlow "+id+" sampling", "512\n").
+		Expect("ovs-vsctl get sFlow "+id+" header", "256\n").
+		Expect("ovs-vsctl get sFlow "+id+" polling", "[]\n").
+		Expect("ovs-vsctl get sFlow "+id+" agent", "\"br0\"\n")
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+	got, err := GetSFlow(context.Background(), "br0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"targets": []string{"192.168.1.100:6343"}, "sampling": 512, "header": 256, "polling": 30, "agent": "br0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSFlow = %v, want %v", got, want)
+	}
+}
+
+func TestGetSFlowReplay(t *testing.T) {
+	replay(t, "sflow.jsonl")
+	tests := []struct {
+		bridge string
+		want   map[string]interface{}
+	}{
+		{"br0", map[string]interface{}{"targets": []string{"10.0.0.1:6343", "10.0.0.2:6343"}, "sampling": 64, "header": 128, "polling": 10, "agent": "eth0"}},
+		{"br1", sflowDefaults()},
+		{"br-missing", sflowDefaults()},
+	}
+	for _, tt := range tests {
+		got, err := GetSFlow(context.Background(), tt.bridge)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.bridge, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetSFlow(%s) = %v, want %v", tt.bridge, got, tt.want)
+		}
+	}
+}
+
+func TestGetIpfixReplay(t *testing.T) {
+	replay(t, "ipfix.jsonl")
+	tests := []struct {
+		bridge string
+		want   map[string]interface{}
+	}{
+		{"br0", map[string]interface{}{"targets": []string{"10.0.0.9:4739"}, "sampling": 1000, "obsDomainID": 7, "obsPointID": 1}},
+		{"br1", ipfixDefaults()},
+		{"br-missing", ipfixDefaults()},
+	}
+	for _, tt := range tests {
+		got, err := GetIpfix(context.Background(), tt.bridge)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.bridge, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetIpfix(%s) = %v, want %v", tt.bridge, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"ovs-manager/ovsdb"
+)
+
+// ErrorCause 错误分类，api 层据此映射 HTTP 状态码
+type ErrorCause string
+
+const (
+	CauseUnknown          ErrorCause = "unknown"
+	CauseNotFound         ErrorCause = "not-found"
+	CauseAlreadyExists    ErrorCause = "already-exists"
+	CauseConflict         ErrorCause = "conflict" // 对象仍被引用等状态冲突
+	CauseInvalidArgument  ErrorCause = "invalid-argument"
+	CauseOVSDBUnreachable ErrorCause = "ovsdb-unreachable"
+	CauseTimeout          ErrorCause = "timeout"
+)
+
+// CommandError 外部命令执行失败，保留命令、参数、退出码和标准错误输出
+type CommandError struct {
+	Command  string
+	Args     []string
+	ExitCode int // 未能启动或被信号终止时为 -1
+	Stderr   string
+	Cause    ErrorCause
+	Err      error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("%s: %v", commandLine(e.Command, e.Args), e.Err)
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// newCommandError 由命令执行错误和标准错误输出构造 CommandError，并根据输出分类
+func newCommandError(name string, args []string, err error, stderr string) *CommandError {
+	ce := &CommandError{
+		Command:  name,
+		Args:     append([]string{}, args...),
+		ExitCode: -1,
+		Stderr:   strings.TrimSpace(stderr),
+		Err:      err,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		ce.ExitCode = exitErr.ExitCode()
+		if ce.Stderr == "" {
+			ce.Stderr = strings.TrimSpace(string(exitErr.Stderr))
+		}
+	}
+	ce.Cause = classifyStderr(ce.Stderr)
+	if ce.Cause == CauseUnknown && errors.Is(err, context.DeadlineExceeded) {
+		ce.Cause = CauseTimeout
+	} else if ce.Cause == CauseUnknown && err != nil {
+		// 如 ovs-vsctl --timeout 到期被 SIGALRM 终止: "signal: alarm clock"
+		ce.Cause = classifyStderr(err.Error())
+	}
+	return ce
+}
+
+// wrapCommandError 保证命令错误都是 *CommandError，便于 api 层统一处理
+func wrapCommandError(name string, args []string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var ce *CommandError
+	if errors.As(err, &ce) {
+		return err
+	}
+	return newCommandError(name, args, err, "")
+}
+
+// stderrPatterns 按顺序匹配标准错误输出中的关键字
+var stderrPatterns = []struct {
+	cause    ErrorCause
+	keywords []string
+}{
+	{CauseTimeout, []string{"timed out", "timeout", "alarm clock"}},
+	{CauseOVSDBUnreachable, []string{"database connection failed", "connection refused", "db.sock", "failed to connect", "is ovs-vswitchd running", "unix:/var/run/openvswitch"}},
+	{CauseAlreadyExists, []string{"already exists", "file exists", "duplicate"}},
+	{CauseNotFound, []string{"no bridge named", "no port named", "no interface named", "no row", "not found", "does not exist", "no such", "cannot find device", "unknown bridge"}},
+	{CauseInvalidArgument, []string{"invalid", "syntax error", "unknown", "not a valid", "bad ", "must be", "unrecognized", "expected", "out of range", "requires", "not allowed"}},
+}
+
+// classifyStderr 根据命令的标准错误输出判断错误分类
+func classifyStderr(stderr string) ErrorCause {
+	s := strings.ToLower(stderr)
+	if s == "" {
+		return CauseUnknown
+	}
+	for _, p := range stderrPatterns {
+		for _, kw := range p.keywords {
+			if strings.Contains(s, kw) {
+				return p.cause
+			}
+		}
+	}
+	return CauseUnknown
+}
+
+// Error service 层自身检查（如镜像不存在）产生的分类错误
+type Error struct {
+	Cause   ErrorCause
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// errorf 构造分类错误
+func errorf(cause ErrorCause, format string, args ...interface{}) error {
+	return &Error{Cause: cause, Message: fmt.Sprintf(format, args...)}
+}
+
+// CauseOf 返回错误的分类：CommandError/Error 取自身分类，OVSDB 连接失败为 ovsdb-unreachable，超时为 timeout
+func CauseOf(err error) ErrorCause {
+	if err == nil {
+		return CauseUnknown
+	}
+	var ce *CommandError
+	if errors.As(err, &ce) {
+		return ce.Cause
+	}
+	var se *Error
+	if errors.As(err, &se) {
+		return se.Cause
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return CauseTimeout
+	}
+	var te *ovsdb.TransactError
+	if errors.As(err, &te) {
+		switch te.Err {
+		case "constraint violation":
+			// ovsdb-server 唯一索引冲突: "... have identical values ... for index on column ..."
+			if strings.Contains(te.Details, "identical values") || strings.Contains(te.Details, "have the same") {
+				return CauseAlreadyExists
+			}
+			return CauseInvalidArgument
+		case "referential integrity violation", "syntax error", "domain error", "range error":
+			return CauseInvalidArgument
+		case "timed out":
+			return CauseTimeout
+		}
+		return CauseUnknown
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return CauseTimeout
+		}
+		return CauseOVSDBUnreachable
+	}
+	if errors.Is(err, ovsdb.ErrClosed) {
+		return CauseOVSDBUnreachable
+	}
+	return CauseUnknown
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ovs-manager/ovsdb"
+)
+
+// 事件类型
+const (
+	EventBridgeAdded       = "bridge_added"
+	EventBridgeRemoved     = "bridge_removed"
+	EventPortAdded         = "port_added"
+	EventPortRemoved       = "port_removed"
+	EventLinkStateChanged  = "interface_link_changed"
+	EventBondMemberChanged = "bond_member_changed"
+	EventMirrorAdded       = "mirror_added"
+	EventMirrorRemoved     = "mirror_removed"
+	EventMirrorChanged     = "mirror_changed"
+)
+
+// eventBufferSize 每个订阅者的事件缓冲，写满说明客户端消费过慢，订阅会被关闭
+const eventBufferSize = 256
+
+// eventRetryInterval 监听连接断开后的重连间隔
+const eventRetryInterval = 2 * time.Second
+
+// Event OVSDB 变更事件；Old/New 为接口链路状态（up/down）或 bond 成员状态（enabled/disabled）
+type Event struct {
+	Type   string    `json:"type"`
+	Table  string    `json:"table"`
+	Bridge string    `json:"bridge,omitempty"`
+	Port   string    `json:"port,omitempty"`
+	Name   string    `json:"name"`
+	Old    string    `json:"old,omitempty"`
+	New    string    `json:"new,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// EventFilter 订阅过滤条件，为空表示不过滤；表名不区分大小写
+type EventFilter struct {
+	Bridges []string
+	Tables  []string
+}
+
+// eventMonitorRequests 监听的表和列；Mirror 不监听 statistics，避免计数刷新产生事件
+var eventMonitorRequests = map[string]ovsdb.MonitorRequest{
+	"Bridge":    {Columns: []string{"name", "ports", "mirrors"}},
+	"Port":      {Columns: []string{"name", "interfaces"}},
+	"Interface": {Columns: []string{"name", "link_state", "lacp_current"}},
+	"Mirror":    {Columns: []string{"name", "select_all", "select_src_port", "select_dst_port", "select_vlan", "output_port", "output_vlan"}},
+}
+
+func (f EventFilter) match(ev Event) bool {
+	if len(f.Tables) > 0 && !containsFold(f.Tables, ev.Table) {
+		return false
+	}
+	if len(f.Bridges) > 0 && !containsFold(f.Bridges, ev.Bridge) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription 事件订阅，C 在订阅关闭（调用 Close 或消费过慢）后被关闭
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	filter EventFilter
+	once   sync.Once
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	events.remove(s)
+}
+
+// SubscribeEvents 订阅 OVSDB 变更事件；第一个订阅者到来时建立 OVSDB monitor 连接，
+// 最后一个订阅者离开时断开。未配置 -ovsdb 时连接默认的 db.sock
+func SubscribeEvents(filter EventFilter) (*Subscription, error) {
+	for i, t := range filter.Tables {
+		matched := false
+		for name := range eventMonitorRequests {
+			if strings.EqualFold(name, t) {
+				filter.Tables[i], matched = name, true
+			}
+		}
+		if !matched {
+			return nil, errorf(CauseInvalidArgument, "unsupported event table %q (supported: Bridge, Port, Interface, Mirror)", t)
+		}
+	}
+	ch := make(chan Event, eventBufferSize)
+	s := &Subscription{C: ch, ch: ch, filter: filter}
+	events.add(s)
+	return s, nil
+}
+
+// eventHub 共享的 OVSDB monitor 连接及其缓存，所有订阅者复用
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	cancel context.CancelFunc
+	cache  map[string]map[string]ovsdb.Row
+}
+
+var events = &eventHub{subs: map[*Subscription]struct{}{}}
+
+func (h *eventHub) add(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[s] = struct{}{}
+	if h.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancel = cancel
+		h.cache = nil
+		go h.run(ctx)
+	}
+}
+
+func (h *eventHub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closeLocked(s)
+	if len(h.subs) == 0 && h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+}
+
+func (h *eventHub) closeLocked(s *Subscription) {
+	delete(h.subs, s)
+	s.once.Do(func() { close(s.ch) })
+}
+
+// run 保持 monitor 连接，断开后按固定间隔重连
+func (h *eventHub) run(ctx context.Context) {
+	for {
+		err := h.monitor(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("ovsdb event monitor: %v, retrying in %s", err, eventRetryInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventRetryInterval):
+		}
+	}
+}
+
+func (h *eventHub) monitor(ctx context.Context) error {
+	ovsdbMu.Lock()
+	endpoint := ovsdbEndpoint
+	ovsdbMu.Unlock()
+	if endpoint == "" {
+		endpoint = ovsdb.DefaultEndpoint
+	}
+	client, err := ovsdb.Dial(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	// 初始数据处理完之前到达的 update 需要等待，保证按顺序应用
+	ready := make(chan struct{})
+	initial, err := client.Monitor(ctx, vswitchDB, "ovs-manager-events", eventMonitorRequests, func(u ovsdb.TableUpdates) {
+		<-ready
+		h.apply(ctx, u, false)
+	})
+	if err != nil {
+		close(ready)
+		return err
+	}
+	// 重连时以新的初始数据与断开前的缓存做差异，补发断开期间的变更
+	h.apply(ctx, initial, true)
+	close(ready)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-client.Done():
+		return client.Err()
+	}
+}
+
+// apply 将变更应用到缓存并向订阅者分发事件；resync 为 true 时 updates 是完整的初始数据。
+// ctx 已取消说明该连接所属的监听已停止（可能已有新的监听在运行），丢弃其变更
+func (h *eventHub) apply(ctx context.Context, updates ovsdb.TableUpdates, resync bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	first := h.cache == nil
+	prev := h.cache
+	next := make(map[string]map[string]ovsdb.Row, len(eventMonitorRequests))
+	for table := range eventMonitorRequests {
+		next[table] = map[string]ovsdb.Row{}
+		if !resync {
+			for uuid, row := range prev[table] {
+				next[table][uuid] = row
+			}
+		}
+	}
+	touched := map[string]map[string]bool{}
+	for table, rows := range updates {
+		if next[table] == nil {
+			continue
+		}
+		touched[table] = map[string]bool{}
+		for uuid, u := range rows {
+			touched[table][uuid] = true
+			if u.New == nil {
+				delete(next[table], uuid)
+				continue
+			}
+			row := ovsdb.Row{}
+			for k, v := range next[table][uuid] {
+				row[k] = v
+			}
+			for k, v := range u.New {
+				row[k] = v
+			}
+			next[table][uuid] = row
+		}
+	}
+	if resync {
+		// 断开期间被删除的行不会出现在初始数据中
+		for table, rows := range prev {
+			if touched[table] == nil {
+				touched[table] = map[string]bool{}
+			}
+			for uuid := range rows {
+				touched[table][uuid] = true
+			}
+		}
+	}
+	h.cache = next
+	if first {
+		return
+	}
+	for _, ev := range diffEvents(newEventState(prev), newEventState(next), touched) {
+		for s := range h.subs {
+			if !s.filter.match(ev) {
+				continue
+			}
+			select {
+			case s.ch <- ev:
+			default:
+				h.closeLocked(s)
+			}
+		}
+	}
+	if len(h.subs) == 0 && h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+}
+
+// eventState 缓存及其反向索引：端口/镜像所属网桥，接口所属端口
+type eventState struct {
+	tables       map[string]map[string]ovsdb.Row
+	portBridge   map[string]string
+	mirrorBridge map[string]string
+	ifacePort    map[string]string
+}
+
+func newEventState(tables map[string]map[string]ovsdb.Row) *eventState {
+	st := &eventState{tables: tables, portBridge: map[string]string{}, mirrorBridge: map[string]string{}, ifacePort: map[string]string{}}
+	for _, br := range tables["Bridge"] {
+		for _, id := range br.UUIDs("ports") {
+			st.portBridge[id] = br.String("name")
+		}
+		for _, id := range br.UUIDs("mirrors") {
+			st.mirrorBridge[id] = br.String("name")
+		}
+	}
+	for uuid, p := range tables["Port"] {
+		for _, id := range p.UUIDs("interfaces") {
+			st.ifacePort[id] = uuid
+		}
+	}
+	return st
+}
+
+// ifaceOwner 返回接口所属端口名、网桥名，以及端口是否为 bond（多接口端口）
+func (st *eventState) ifaceOwner(uuid string) (string, string, bool) {
+	portUUID := st.ifacePort[uuid]
+	p, ok := st.tables["Port"][portUUID]
+	if !ok {
+		return "", "", false
+	}
+	return p.String("name"), st.portBridge[portUUID], len(p.UUIDs("interfaces")) > 1
+}
+
+// memberState bond 成员是否可用：链路 up 且（启用 LACP 时）LACP 协商完成
+func memberState(iface ovsdb.Row) string {
+	if iface.OptionalString("link_state") == "up" && (len(iface.Set("lacp_current")) == 0 || iface.Bool("lacp_current")) {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// diffEvents 比较变更前后的状态，生成按表、名称排序的事件
+func diffEvents(prev, next *eventState, touched map[string]map[string]bool) []Event {
+	now := time.Now()
+	var res []Event
+	emit := func(ev Event) {
+		ev.Time = now
+		res = append(res, ev)
+	}
+	for _, table := range []string{"Bridge", "Port", "Interface", "Mirror"} {
+		ids := make([]string, 0, len(touched[table]))
+		for id := range touched[table] {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			before, existed := prev.tables[table][id]
+			after, exists := next.tables[table][id]
+			switch table {
+			case "Bridge":
+				switch {
+				case !existed && exists:
+					emit(Event{Type: EventBridgeAdded, Table: table, Bridge: after.String("name"), Name: after.String("name")})
+				case existed && !exists:
+					emit(Event{Type: EventBridgeRemoved, Table: table, Bridge: before.String("name"), Name: before.String("name")})
+				}
+			case "Port":
+				switch {
+				case !existed && exists:
+					emit(Event{Type: EventPortAdded, Table: table, Bridge: next.portBridge[id], Name: after.String("name")})
+				case existed && !exists:
+					emit(Event{Type: EventPortRemoved, Table: table, Bridge: prev.portBridge[id], Name: before.String("name")})
+				}
+			case "Interface":
+				if !existed || !exists {
+					continue
+				}
+				port, bridge, bond := next.ifaceOwner(id)
+				ev := Event{Table: table, Bridge: bridge, Port: port, Name: after.String("name")}
+				if o, n := before.OptionalString("link_state"), after.OptionalString("link_state"); o != n {
+					ev.Type, ev.Old, ev.New = EventLinkStateChanged, o, n
+					emit(ev)
+				}
+				if o, n := memberState(before), memberState(after); bond && o != n {
+					ev.Type, ev.Old, ev.New = EventBondMemberChanged, o, n
+					emit(ev)
+				}
+			case "Mirror":
+				switch {
+				case !existed && exists:
+					emit(Event{Type: EventMirrorAdded, Table: table, Bridge: next.mirrorBridge[id], Name: after.String("name")})
+				case existed && !exists:
+					emit(Event{Type: EventMirrorRemoved, Table: table, Bridge: prev.mirrorBridge[id], Name: before.String("name")})
+				case existed && exists && !sameEventRow(before, after):
+					emit(Event{Type: EventMirrorChanged, Table: table, Bridge: next.mirrorBridge[id], Name: after.String("name")})
+				}
+			}
+		}
+	}
+	return res
+}
+
+// sameEventRow 比较两行的监听列
+func sameEventRow(a, b ovsdb.Row) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if !ovsdb.ValuesEqual(v, b[k]) {
+			return false
+		}
+	}
+	return true
+}
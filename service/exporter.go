@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CollectorSetSpec Flow_Sample_Collector_Set 的 IPFIX 导出参数；采样率由流表 sample 动作指定
+type CollectorSetSpec struct {
+	Targets            []string
+	ObsDomainID        int
+	ObsPointID         int
+	CacheActiveTimeout int
+	CacheMaxFlows      int
+	OtherConfig        map[string]string
+}
+
+// CollectorSet 按流 IPFIX 采样的收集器集合，流表通过 sample(collector_set_id=ID,...) 引用
+type CollectorSet struct {
+	UUID   string         `json:"uuid"`
+	ID     int            `json:"id"`
+	Bridge string         `json:"bridge"`
+	IPFIX  *IPFIXSnapshot `json:"ipfix,omitempty"`
+}
+
+// ovsStringSet 生成 ovs-vsctl 字符串集合参数，如 ["a","b"]
+func ovsStringSet(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("\"%s\"", v)
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
+
+// bridgeExporter 返回网桥 column 列（netflow/sflow/ipfix）引用的行 UUID，未配置时为空
+func bridgeExporter(ctx context.Context, bridge, column string) (string, error) {
+	tables, err := queryTables(ctx, tableQuery{Table: "Bridge", Columns: []string{"name", column}})
+	if err != nil {
+		return "", err
+	}
+	for _, br := range tables["Bridge"] {
+		if br.String("name") == bridge {
+			return br.UUID(column), nil
+		}
+	}
+	return "", errorf(CauseNotFound, "no bridge named %s", bridge)
+}
+
+// setBridgeExporter 网桥已有导出行时清空 optional 列后原地修改，否则新建并挂到网桥上
+func setBridgeExporter(ctx context.Context, bridge, column, table string, cols, optional []string) error {
+	uuid, err := bridgeExporter(ctx, bridge, column)
+	if err != nil {
+		return err
+	}
+	if uuid == "" {
+		args := []string{"--", "--id=@exp", "create", table}
+		args = append(args, cols...)
+		args = append(args, "--", "set", "Bridge", bridge, column+"=@exp")
+		return run(ctx, "ovs-vsctl", args...)
+	}
+	args := append([]string{"--", "clear", table, uuid}, optional...)
+	args = append(args, "--", "set", table, uuid)
+	return run(ctx, "ovs-vsctl", append(args, cols...)...)
+}
+
+// disableBridgeExporter 清空网桥的导出列并删除导出行；未配置时不做修改
+func disableBridgeExporter(ctx context.Context, bridge, column, table string) error {
+	uuid, err := bridgeExporter(ctx, bridge, column)
+	if err != nil || uuid == "" {
+		return err
+	}
+	return run(ctx, "ovs-vsctl", "--", "clear", "Bridge", bridge, column, "--", "destroy", table, uuid)
+}
+
+// DisableNetFlow 关闭网桥的 NetFlow 并删除 NetFlow 行
+func DisableNetFlow(ctx context.Context, bridge string) error {
+	return disableBridgeExporter(ctx, bridge, "netflow", "NetFlow")
+}
+
+// DisableSFlow 关闭网桥的 sFlow 并删除 sFlow 行
+func DisableSFlow(ctx context.Context, bridge string) error {
+	return disableBridgeExporter(ctx, bridge, "sflow", "sFlow")
+}
+
+// DisableIpfix 关闭网桥级 IPFIX 并删除 IPFIX 行，不影响 Flow_Sample_Collector_Set
+func DisableIpfix(ctx context.Context, bridge string) error {
+	return disableBridgeExporter(ctx, bridge, "ipfix", "IPFIX")
+}
+
+// ipfixCols 生成 IPFIX 列参数
+func (spec CollectorSetSpec) ipfixCols() []string {
+	cols := []string{fmt.Sprintf("targets=%s", ovsStringSet(spec.Targets))}
+	for name, v := range map[string]int{"obs_domain_id": spec.ObsDomainID, "obs_point_id": spec.ObsPointID, "cache_active_timeout": spec.CacheActiveTimeout, "cache_max_flows": spec.CacheMaxFlows} {
+		if v != 0 {
+			cols = append(cols, fmt.Sprintf("%s=%d", name, v))
+		}
+	}
+	sort.Strings(cols[1:])
+	for _, k := range sortedKeys(spec.OtherConfig) {
+		cols = append(cols, fmt.Sprintf("other_config:%s=%s", k, spec.OtherConfig[k]))
+	}
+	return cols
+}
+
+// ListCollectorSets 查询 Flow_Sample_Collector_Set，bridge 为空时返回所有网桥的
+func ListCollectorSets(ctx context.Context, bridge string) ([]CollectorSet, error) {
+	tables, err := queryTables(ctx,
+		tableQuery{Table: "Bridge", Columns: []string{"_uuid", "name"}},
+		tableQuery{Table: "Flow_Sample_Collector_Set"},
+		tableQuery{Table: "IPFIX"},
+	)
+	if err != nil {
+		return nil, err
+	}
+	bridges := indexByUUID(tables["Bridge"])
+	ipfixes := indexByUUID(tables["IPFIX"])
+	res := []CollectorSet{}
+	for _, row := range tables["Flow_Sample_Collector_Set"] {
+		set := CollectorSet{UUID: row.UUID("_uuid"), ID: row.Int("id")}
+		if br, ok := bridges[row.UUID("bridge")]; ok {
+			set.Bridge = br.String("name")
+		}
+		if bridge != "" && set.Bridge != bridge {
+			continue
+		}
+		if ipf, ok := ipfixes[row.UUID("ipfix")]; ok {
+			set.IPFIX = &IPFIXSnapshot{
+				UUID:        ipf.UUID("_uuid"),
+				Targets:     ipf.Strings("targets"),
+				Sampling:    ipf.OptionalInt("sampling"),
+				ObsDomainID: ipf.OptionalInt("obs_domain_id"),
+				ObsPointID:  ipf.OptionalInt("obs_point_id"),
+			}
+		}
+		res = append(res, set)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Bridge != res[j].Bridge {
+			return res[i].Bridge < res[j].Bridge
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+// findCollectorSet 按网桥和 ID 查找收集器集合
+func findCollectorSet(ctx context.Context, bridge string, id int) (*CollectorSet, error) {
+	sets, err := ListCollectorSets(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sets {
+		if sets[i].ID == id {
+			return &sets[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SetCollectorSet 创建或原地修改网桥上指定 ID 的 Flow_Sample_Collector_Set 及其 IPFIX 导出配置
+func SetCollectorSet(ctx context.Context, bridge string, id int, spec CollectorSetSpec) error {
+	if id < 0 {
+		return errorf(CauseInvalidArgument, "collector set id must not be negative")
+	}
+	if len(spec.Targets) == 0 {
+		return errorf(CauseInvalidArgument, "collector set needs at least one IPFIX target")
+	}
+	if _, err := bridgeExporter(ctx, bridge, "ipfix"); err != nil {
+		return err
+	}
+	existing, err := findCollectorSet(ctx, bridge, id)
+	if err != nil {
+		return err
+	}
+	cols := spec.ipfixCols()
+	switch {
+	case existing == nil:
+		args := []string{"--", "--id=@br", "get", "Bridge", bridge, "--", "--id=@ipf", "create", "IPFIX"}
+		args = append(args, cols...)
+		args = append(args, "--", "create", "Flow_Sample_Collector_Set", fmt.Sprintf("id=%d", id), "bridge=@br", "ipfix=@ipf")
+		return run(ctx, "ovs-vsctl", args...)
+	case existing.IPFIX == nil:
+		args := append([]string{"--", "--id=@ipf", "create", "IPFIX"}, cols...)
+		args = append(args, "--", "set", "Flow_Sample_Collector_Set", existing.UUID, "ipfix=@ipf")
+		return run(ctx, "ovs-vsctl", args...)
+	default:
+		uuid := existing.IPFIX.UUID
+		args := []string{"--", "clear", "IPFIX", uuid, "obs_domain_id", "obs_point_id", "cache_active_timeout", "cache_max_flows", "other_config", "--", "set", "IPFIX", uuid}
+		return run(ctx, "ovs-vsctl", append(args, cols...)...)
+	}
+}
+
+// DeleteCollectorSet 删除网桥上指定 ID 的 Flow_Sample_Collector_Set，其 IPFIX 行随之回收
+func DeleteCollectorSet(ctx context.Context, bridge string, id int) error {
+	existing, err := findCollectorSet(ctx, bridge, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errorf(CauseNotFound, "no collector set %d on bridge %s", id, bridge)
+	}
+	return run(ctx, "ovs-vsctl", "destroy", "Flow_Sample_Collector_Set", existing.UUID)
+}
@@ -1,31 +1,144 @@
 package service
 
 import (
-	"os/exec"
+	"context"
+	"sort"
 )
 
-// ListFlowsV2 查询指定 bridge 的所有流表（支持自定义表达式）
-func ListFlowsV2(bridge string) (string, error) {
-	cmd := exec.Command("ovs-ofctl", "dump-flows", bridge)
-	output, err := cmd.Output()
+// FlowFilter 流表查询过滤与排序条件，零值表示不过滤
+type FlowFilter struct {
+	Table       *int
+	Cookie      *FlowCookie
+	CookieMask  *FlowCookie // 为空时精确匹配 cookie
+	PriorityMin *int
+	PriorityMax *int
+	MatchField  string // 只返回包含该匹配字段的流表，如 nw_src、in_port、ip
+	MatchValue  string // 与 MatchField 同时使用时要求字段值相等
+	SortBy      string // packets、bytes、priority，为空时保持 ovs-ofctl 输出顺序
+	Ascending   bool   // 默认降序
+}
+
+// match 判断流表是否满足过滤条件
+func (f FlowFilter) match(flow Flow) bool {
+	if f.Table != nil && flow.Table != *f.Table {
+		return false
+	}
+	if f.Cookie != nil {
+		mask := ^FlowCookie(0)
+		if f.CookieMask != nil {
+			mask = *f.CookieMask
+		}
+		if flow.Cookie&mask != *f.Cookie&mask {
+			return false
+		}
+	}
+	if f.PriorityMin != nil && flow.Priority < *f.PriorityMin {
+		return false
+	}
+	if f.PriorityMax != nil && flow.Priority > *f.PriorityMax {
+		return false
+	}
+	if f.MatchField != "" {
+		v, ok := flow.MatchValue(f.MatchField)
+		if !ok || (f.MatchValue != "" && v != f.MatchValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// dumpFlows 执行 ovs-ofctl dump-flows 并解析
+func dumpFlows(ctx context.Context, bridge string) ([]Flow, error) {
+	output, err := runOutput(ctx, "ovs-ofctl", "dump-flows", bridge)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(output), nil
+	return ParseFlows(string(output))
 }
 
-// AddFlowV2 添加流表规则
-func AddFlowV2(bridge, flow string) error {
-	cmd := exec.Command("ovs-ofctl", "add-flow", bridge, flow)
-	return cmd.Run()
+// ListFlowsV2 查询指定 bridge 的流表，按 filter 过滤和排序
+func ListFlowsV2(ctx context.Context, bridge string, filter FlowFilter) ([]Flow, error) {
+	flows, err := dumpFlows(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	res := []Flow{}
+	for _, flow := range flows {
+		if filter.match(flow) {
+			res = append(res, flow)
+		}
+	}
+	var less func(a, b Flow) bool
+	switch filter.SortBy {
+	case "packets":
+		less = func(a, b Flow) bool { return a.NPackets < b.NPackets }
+	case "bytes":
+		less = func(a, b Flow) bool { return a.NBytes < b.NBytes }
+	case "priority":
+		less = func(a, b Flow) bool { return a.Priority < b.Priority }
+	}
+	if less != nil {
+		sort.SliceStable(res, func(i, j int) bool {
+			if filter.Ascending {
+				return less(res[i], res[j])
+			}
+			return less(res[j], res[i])
+		})
+	}
+	return res, nil
+}
+
+// AddFlowV2 添加流表规则，动作中含 meter 指令或 group 动作时以 OpenFlow13 下发；
+// 启用流表存储时记录该流表，未指定 cookie 的流表打上 FlowStoreCookie
+func AddFlowV2(ctx context.Context, bridge, flow string) error {
+	store, _ := currentFlowStore()
+	var ports map[string]int
+	if store != nil {
+		if !flowHasCookie(flow) {
+			flow = tagFlowCookie(flow, FlowStoreCookie)
+		}
+		var err error
+		if ports, err = bridgeOFPorts(ctx, bridge); err != nil {
+			return err
+		}
+	}
+	args := []string{"add-flow", bridge, flow}
+	if flowUsesMeter(flow) || flowHasAction(flow, "group") {
+		args = append([]string{"-O", meterProtocol}, args...)
+	}
+	if err := run(ctx, "ovs-ofctl", args...); err != nil {
+		return err
+	}
+	if store != nil {
+		return store.record(bridge, flow, ports)
+	}
+	return nil
 }
 
-// DeleteFlowV2 删除流表规则（支持全删和条件删）
-func DeleteFlowV2(bridge, match string) error {
-	if match == "" {
-		cmd := exec.Command("ovs-ofctl", "del-flows", bridge)
-		return cmd.Run()
-	}
-	cmd := exec.Command("ovs-ofctl", "del-flows", bridge, match)
-	return cmd.Run()
-} 
\ No newline at end of file
+// DeleteFlowV2 删除流表规则（支持全删和条件删），启用流表存储时同步删除记录；
+// 条件在 del-flows 之前解析，无法解析时不删除任何流表
+func DeleteFlowV2(ctx context.Context, bridge, match string) error {
+	store, _ := currentFlowStore()
+	var del *flowDeleteMatch
+	var ports map[string]int
+	if store != nil {
+		var err error
+		if del, err = parseFlowDeleteMatch(match); err != nil {
+			return err
+		}
+		if ports, err = bridgeOFPorts(ctx, bridge); err != nil {
+			return err
+		}
+	}
+	args := []string{"del-flows", bridge}
+	if match != "" {
+		args = append(args, match)
+	}
+	if err := run(ctx, "ovs-ofctl", args...); err != nil {
+		return err
+	}
+	if store != nil {
+		return store.forget(bridge, func(f Flow) bool { return del.covers(f, ports) })
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FlowFieldError 流表校验错误，Field 为出错的字段或动作
+type FlowFieldError struct {
+	Field   string `json:"field"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// maxFlowTable 可安装流表的最大表号，255 为 OFPTT_ALL 保留
+const maxFlowTable = 254
+
+// flowProtocols 协议简写及其隐含的 dl_type 与 nw_proto
+var flowProtocols = map[string]struct {
+	dlType  string
+	nwProto int
+}{
+	"ip":    {"ip", 0},
+	"ipv6":  {"ipv6", 0},
+	"icmp":  {"ip", 1},
+	"icmp6": {"ipv6", 58},
+	"tcp":   {"ip", 6},
+	"tcp6":  {"ipv6", 6},
+	"udp":   {"ip", 17},
+	"udp6":  {"ipv6", 17},
+	"sctp":  {"ip", 132},
+	"sctp6": {"ipv6", 132},
+	"arp":   {"arp", 0},
+	"rarp":  {"arp", 0},
+	"mpls":  {"mpls", 0},
+	"mplsm": {"mpls", 0},
+}
+
+// dlTypes dl_type/eth_type 数值对应的三层协议
+var dlTypes = map[uint64]string{
+	0x0800: "ip",
+	0x86dd: "ipv6",
+	0x0806: "arp",
+	0x8035: "arp",
+	0x8847: "mpls",
+	0x8848: "mpls",
+}
+
+// flowPrereqs 匹配字段需要的前置条件：三层协议（任一）及四层协议号（任一）
+var flowPrereqs = map[string]struct {
+	l3 []string
+	l4 []int
+}{
+	"nw_src":      {[]string{"ip", "arp"}, nil},
+	"nw_dst":      {[]string{"ip", "arp"}, nil},
+	"ip_src":      {[]string{"ip"}, nil},
+	"ip_dst":      {[]string{"ip"}, nil},
+	"nw_proto":    {[]string{"ip", "ipv6", "arp"}, nil},
+	"ip_proto":    {[]string{"ip", "ipv6"}, nil},
+	"nw_tos":      {[]string{"ip", "ipv6"}, nil},
+	"ip_dscp":     {[]string{"ip", "ipv6"}, nil},
+	"nw_ecn":      {[]string{"ip", "ipv6"}, nil},
+	"ip_ecn":      {[]string{"ip", "ipv6"}, nil},
+	"nw_ttl":      {[]string{"ip", "ipv6"}, nil},
+	"ip_frag":     {[]string{"ip", "ipv6"}, nil},
+	"nw_frag":     {[]string{"ip", "ipv6"}, nil},
+	"ipv6_src":    {[]string{"ipv6"}, nil},
+	"ipv6_dst":    {[]string{"ipv6"}, nil},
+	"ipv6_label":  {[]string{"ipv6"}, nil},
+	"arp_op":      {[]string{"arp"}, nil},
+	"arp_spa":     {[]string{"arp"}, nil},
+	"arp_tpa":     {[]string{"arp"}, nil},
+	"arp_sha":     {[]string{"arp"}, nil},
+	"arp_tha":     {[]string{"arp"}, nil},
+	"tp_src":      {[]string{"ip", "ipv6"}, []int{6, 17, 132}},
+	"tp_dst":      {[]string{"ip", "ipv6"}, []int{6, 17, 132}},
+	"tcp_src":     {[]string{"ip", "ipv6"}, []int{6}},
+	"tcp_dst":     {[]string{"ip", "ipv6"}, []int{6}},
+	"tcp_flags":   {[]string{"ip", "ipv6"}, []int{6}},
+	"udp_src":     {[]string{"ip", "ipv6"}, []int{17}},
+	"udp_dst":     {[]string{"ip", "ipv6"}, []int{17}},
+	"sctp_src":    {[]string{"ip", "ipv6"}, []int{132}},
+	"sctp_dst":    {[]string{"ip", "ipv6"}, []int{132}},
+	"icmp_type":   {[]string{"ip", "ipv6"}, []int{1, 58}},
+	"icmp_code":   {[]string{"ip", "ipv6"}, []int{1, 58}},
+	"icmpv4_type": {[]string{"ip"}, []int{1}},
+	"icmpv4_code": {[]string{"ip"}, []int{1}},
+	"icmpv6_type": {[]string{"ipv6"}, []int{58}},
+	"icmpv6_code": {[]string{"ipv6"}, []int{58}},
+	"nd_target":   {[]string{"ipv6"}, []int{58}},
+	"nd_sll":      {[]string{"ipv6"}, []int{58}},
+	"nd_tll":      {[]string{"ipv6"}, []int{58}},
+	"mpls_label":  {[]string{"mpls"}, nil},
+	"mpls_tc":     {[]string{"mpls"}, nil},
+	"mpls_bos":    {[]string{"mpls"}, nil},
+	"mpls_ttl":    {[]string{"mpls"}, nil},
+}
+
+// flowPlainFields 无前置条件的匹配字段
+var flowPlainFields = map[string]bool{
+	"in_port": true, "in_port_oxm": true, "dl_src": true, "dl_dst": true, "eth_src": true, "eth_dst": true,
+	"dl_type": true, "eth_type": true, "dl_vlan": true, "dl_vlan_pcp": true, "vlan_tci": true, "vlan_vid": true,
+	"vlan_pcp": true, "metadata": true, "pkt_mark": true, "skb_priority": true, "actset_output": true,
+	"packet_type": true, "recirc_id": true, "dp_hash": true, "conj_id": true, "out_port": true, "out_group": true,
+	"tun_id": true, "tunnel_id": true, "tun_src": true, "tun_dst": true, "tun_ipv6_src": true, "tun_ipv6_dst": true,
+	"tun_flags": true, "tun_gbp_id": true, "tun_gbp_flags": true, "tun_tos": true, "tun_ttl": true,
+	"ct_state": true, "ct_zone": true, "ct_mark": true, "ct_label": true, "ct_nw_src": true, "ct_nw_dst": true,
+	"ct_ipv6_src": true, "ct_ipv6_dst": true, "ct_nw_proto": true, "ct_tp_src": true, "ct_tp_dst": true,
+}
+
+// flowRegFields 寄存器和扩展字段
+var flowRegFields = regexp.MustCompile(`^(x{0,2}reg\d+|tun_metadata\d+|(NXM|OXM)_[A-Z0-9_]+)$`)
+
+// flowStatFields dump-flows 输出中的统计字段，不能出现在 add-flow 中
+var flowStatFields = []string{"duration", "n_packets", "n_bytes", "idle_age", "hard_age"}
+
+// specialPorts OpenFlow 保留端口名
+var specialPorts = map[string]bool{
+	"LOCAL": true, "IN_PORT": true, "NORMAL": true, "FLOOD": true, "ALL": true,
+	"CONTROLLER": true, "NONE": true, "TABLE": true, "ANY": true,
+}
+
+// ValidateFlow 在安装前校验 add-flow 表达式：语法、表号、优先级、引用的端口是否存在于 bridge，
+// 以及匹配字段和动作的协议前置条件；返回字段级错误列表，为空表示通过
+func ValidateFlow(ctx context.Context, bridge, flow string) ([]FlowFieldError, error) {
+	ports, err := bridgeOFPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	return checkFlow(flow, ports), nil
+}
+
+// bridgeOFPorts 返回 bridge 上端口名到 ofport 的映射
+func bridgeOFPorts(ctx context.Context, bridge string) (map[string]int, error) {
+	tables, err := selectTables(ctx, "Bridge", "Port", "Interface")
+	if err != nil {
+		return nil, err
+	}
+	ports := indexByUUID(tables["Port"])
+	ifaces := indexByUUID(tables["Interface"])
+	for _, br := range tables["Bridge"] {
+		if br.String("name") != bridge {
+			continue
+		}
+		res := make(map[string]int)
+		for _, pid := range br.UUIDs("ports") {
+			for _, iid := range ports[pid].UUIDs("interfaces") {
+				iface, ok := ifaces[iid]
+				if !ok {
+					continue
+				}
+				ofport := -1
+				if p := iface.OptionalInt("ofport"); p != nil {
+					ofport = *p
+				}
+				res[iface.String("name")] = ofport
+			}
+		}
+		return res, nil
+	}
+	return nil, errorf(CauseNotFound, "no bridge named %s", bridge)
+}
+
+// checkFlow 校验流表表达式，ports 为 bridge 上的端口名到 ofport 映射
+func checkFlow(text string, ports map[string]int) []FlowFieldError {
+	errs := []FlowFieldError{}
+	add := func(field, value, format string, args ...interface{}) {
+		errs = append(errs, FlowFieldError{Field: field, Value: value, Message: fmt.Sprintf(format, args...)})
+	}
+	if !strings.Contains(text, "actions=") {
+		add("actions", "", "missing actions=")
+		return errs
+	}
+	for _, tok := range splitTopLevel(text[:strings.Index(text, "actions=")]) {
+		key, _, _ := strings.Cut(tok, "=")
+		for _, stat := range flowStatFields {
+			if key == stat {
+				add(key, "", "%s is a statistic and cannot be set", key)
+			}
+		}
+	}
+	flow, err := ParseFlow(text)
+	if err != nil {
+		add("flow", text, "%v", err)
+		return errs
+	}
+	if flow.Table < 0 || flow.Table > maxFlowTable {
+		add("table", strconv.Itoa(flow.Table), "table must be between 0 and %d", maxFlowTable)
+	}
+	if flow.Priority < 0 || flow.Priority > 65535 {
+		add("priority", strconv.Itoa(flow.Priority), "priority must be between 0 and 65535")
+	}
+	if len(flow.Actions) == 0 {
+		add("actions", "", "actions must not be empty, use drop to discard packets")
+	}
+
+	// 先确定匹配中声明的三层协议和四层协议号
+	l3, l4 := "", -1
+	for _, m := range flow.Match {
+		if p, ok := flowProtocols[m.Field]; ok {
+			l3 = p.dlType
+			if p.nwProto != 0 {
+				l4 = p.nwProto
+			}
+		}
+		switch m.Field {
+		case "dl_type", "eth_type":
+			v, err := parseUint(m.Value)
+			if err != nil {
+				add(m.Field, m.Value, "invalid ethertype")
+				continue
+			}
+			l3 = dlTypes[v]
+		case "nw_proto", "ip_proto":
+			v, err := parseUint(m.Value)
+			if err != nil || v > 255 {
+				add(m.Field, m.Value, "ip protocol must be between 0 and 255")
+				continue
+			}
+			l4 = int(v)
+		}
+	}
+
+	for _, m := range flow.Match {
+		field := m.Field
+		if _, ok := flowProtocols[field]; ok {
+			continue
+		}
+		if req, ok := flowPrereqs[field]; ok {
+			if !containsString(req.l3, l3) {
+				add(field, m.Value, "%s requires %s match (e.g. dl_type or protocol shorthand)", field, strings.Join(req.l3, "/"))
+			} else if req.l4 != nil && !containsInt(req.l4, l4) {
+				add(field, m.Value, "%s requires nw_proto %s", field, joinInts(req.l4))
+			}
+			continue
+		}
+		if !flowPlainFields[field] && !flowRegFields.MatchString(field) {
+			add(field, m.Value, "unknown match field %s", field)
+			continue
+		}
+		switch field {
+		case "in_port":
+			if msg := checkPortRef(m.Value, ports); msg != "" {
+				add(field, m.Value, "%s", msg)
+			}
+		case "dl_vlan":
+			if v, err := parseUint(m.Value); err != nil || v > 4095 {
+				add(field, m.Value, "dl_vlan must be between 0 and 4095")
+			}
+		case "dl_vlan_pcp", "vlan_pcp":
+			if v, err := parseUint(m.Value); err != nil || v > 7 {
+				add(field, m.Value, "%s must be between 0 and 7", field)
+			}
+		}
+	}
+
+	for _, action := range flow.Actions {
+		checkFlowAction(action, l3, l4, ports, add)
+	}
+	return errs
+}
+
+// checkFlowAction 校验单个动作引用的端口、表号及协议前置条件
+func checkFlowAction(action, l3 string, l4 int, ports map[string]int, add func(field, value, format string, args ...interface{})) {
+	name, arg := action, ""
+	if i := strings.IndexAny(action, ":("); i >= 0 {
+		name, arg = action[:i], strings.TrimSuffix(action[i+1:], ")")
+	}
+	name = strings.ToLower(name)
+	requireL3 := func(allowed ...string) {
+		if !containsString(allowed, l3) {
+			add("actions", action, "%s requires %s match", name, strings.Join(allowed, "/"))
+		}
+	}
+	switch name {
+	case "output":
+		if strings.HasPrefix(arg, "NXM_") || strings.Contains(arg, "[") {
+			return
+		}
+		if msg := checkPortRef(arg, ports); msg != "" {
+			add("actions", action, "%s", msg)
+		}
+	case "enqueue":
+		port, _, _ := strings.Cut(arg, ":")
+		if msg := checkPortRef(port, ports); msg != "" {
+			add("actions", action, "%s", msg)
+		}
+	case "resubmit":
+		if !strings.Contains(action, "(") {
+			if msg := checkPortRef(arg, ports); msg != "" {
+				add("actions", action, "%s", msg)
+			}
+			return
+		}
+		port, rest, _ := strings.Cut(arg, ",")
+		if msg := checkPortRef(port, ports); msg != "" {
+			add("actions", action, "%s", msg)
+		}
+		if table, _, _ := strings.Cut(rest, ","); table != "" {
+			checkTableRef(action, table, add)
+		}
+	case "goto_table":
+		checkTableRef(action, arg, add)
+	case "meter":
+		if id, err := strconv.Atoi(arg); err != nil || id <= 0 {
+			add("actions", action, "meter id must be a positive integer")
+		}
+	case "group":
+		if id, err := strconv.Atoi(arg); err != nil || id < 0 || id > maxGroupID {
+			add("actions", action, "group id must be between 0 and %d", maxGroupID)
+		}
+	case "mod_nw_src", "mod_nw_dst", "mod_nw_tos", "mod_nw_ecn", "mod_nw_ttl":
+		requireL3("ip", "ipv6")
+	case "dec_ttl":
+		requireL3("ip", "ipv6")
+	case "mod_tp_src", "mod_tp_dst":
+		requireL3("ip", "ipv6")
+		if !containsInt([]int{6, 17, 132}, l4) {
+			add("actions", action, "%s requires tcp/udp/sctp match", name)
+		}
+	case "dec_mpls_ttl", "set_mpls_label", "set_mpls_tc", "set_mpls_ttl", "pop_mpls":
+		requireL3("mpls")
+	case "set_field":
+		value, field, ok := strings.Cut(arg, "->")
+		if !ok || value == "" || field == "" {
+			add("actions", action, "set_field syntax is set_field:value->field")
+			return
+		}
+		if req, ok := flowPrereqs[field]; ok && !containsString(req.l3, l3) {
+			add("actions", action, "set_field on %s requires %s match", field, strings.Join(req.l3, "/"))
+		}
+	default:
+		// 纯数字动作等价于 output:N
+		if _, err := strconv.Atoi(action); err == nil {
+			if msg := checkPortRef(action, ports); msg != "" {
+				add("actions", action, "%s", msg)
+			}
+		}
+	}
+}
+
+// checkPortRef 校验端口引用（端口名或 ofport），返回错误信息，为空表示存在
+func checkPortRef(ref string, ports map[string]int) string {
+	if ref == "" || specialPorts[strings.ToUpper(ref)] {
+		return ""
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		for _, ofport := range ports {
+			if ofport == n {
+				return ""
+			}
+		}
+		return fmt.Sprintf("no port with ofport %d on bridge", n)
+	}
+	if _, ok := ports[ref]; !ok {
+		return fmt.Sprintf("no port named %s on bridge", ref)
+	}
+	return ""
+}
+
+// checkTableRef 校验动作中引用的表号
+func checkTableRef(action, table string, add func(field, value, format string, args ...interface{})) {
+	n, err := strconv.Atoi(table)
+	if err != nil || n < 0 || n > maxFlowTable {
+		add("actions", action, "table must be between 0 and %d", maxFlowTable)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+func joinInts(list []int) string {
+	s := make([]string, len(list))
+	for i, v := range list {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, "/")
+}
@@ -0,0 +1,32 @@
+package service
+
+import "testing"
+
+func TestCheckFlowNumericFields(t *testing.T) {
+	ports := map[string]int{"eth1": 1}
+	tests := []struct {
+		flow  string
+		field string
+	}{
+		{"table=0,dl_vlan=100,actions=drop", ""},
+		{"table=0,dl_vlan=0x64,actions=drop", ""},
+		{"table=0,dl_vlan=4096,actions=drop", "dl_vlan"},
+		{"table=0,dl_vlan=-1,actions=drop", "dl_vlan"},
+		{"table=0,dl_vlan_pcp=0x7,actions=drop", ""},
+		{"table=0,dl_vlan_pcp=8,actions=drop", "dl_vlan_pcp"},
+		{"table=0,ip,nw_proto=17,udp_dst=53,actions=drop", ""},
+		// 十六进制协议号同样用于判断四层字段的前提条件
+		{"table=0,ip,nw_proto=0x11,udp_dst=53,actions=drop", ""},
+		{"table=0,ip,nw_proto=0x100,actions=drop", "nw_proto"},
+		{"table=0,ip,nw_proto=udp,actions=drop", "nw_proto"},
+	}
+	for _, tt := range tests {
+		errs := checkFlow(tt.flow, ports)
+		switch {
+		case tt.field == "" && len(errs) > 0:
+			t.Errorf("checkFlow(%q) = %+v, want no errors", tt.flow, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("checkFlow(%q) = %+v, want one error on %s", tt.flow, errs, tt.field)
+		}
+	}
+}
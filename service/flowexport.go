@@ -0,0 +1,476 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlowBundle 网桥流表、组表和计量器的导出内容：均为 ovs-ofctl add-flow/add-group/add-meter 语法，
+// 端口以端口名表示，导入时换算为目标网桥的 ofport
+type FlowBundle struct {
+	Bridge string   `json:"bridge,omitempty"`
+	Meters []Meter  `json:"meters"`
+	Groups []Group  `json:"groups"`
+	Flows  []string `json:"flows"`
+}
+
+// 文本格式中的分节标记，以 # 开头的行会被 ovs-ofctl add-flows/add-groups 忽略
+const (
+	bundleMeters = "# [meters]"
+	bundleGroups = "# [groups]"
+	bundleFlows  = "# [flows]"
+)
+
+// Text 输出按 meters、groups、flows 分节的文本，各节每行一条 ovs-ofctl add-meter/add-group/add-flow 语法；
+// 整个文本不能直接交给单条 ovs-ofctl 命令，需按分节拆分后分别使用，或经 ParseFlowBundleText 导入
+func (b FlowBundle) Text() string {
+	var sb strings.Builder
+	if b.Bridge != "" {
+		fmt.Fprintf(&sb, "# ovs-manager export of bridge %s\n", b.Bridge)
+	}
+	if len(b.Meters) > 0 {
+		sb.WriteString(bundleMeters + "\n")
+		for _, m := range b.Meters {
+			sb.WriteString(m.String() + "\n")
+		}
+	}
+	if len(b.Groups) > 0 {
+		sb.WriteString(bundleGroups + "\n")
+		for _, g := range b.Groups {
+			sb.WriteString(g.String() + "\n")
+		}
+	}
+	sb.WriteString(bundleFlows + "\n")
+	for _, f := range b.Flows {
+		sb.WriteString(f + "\n")
+	}
+	return sb.String()
+}
+
+// ParseFlowBundleText 解析 Text 输出的文本；没有分节标记时整个文件视为流表，
+// 因此也接受普通的 ovs-ofctl add-flows 文件或 dump-flows 输出
+func ParseFlowBundleText(text string) (*FlowBundle, error) {
+	bundle := &FlowBundle{Meters: []Meter{}, Groups: []Group{}, Flows: []string{}}
+	section := bundleFlows
+	var meters, groups []string
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == bundleMeters || line == bundleGroups || line == bundleFlows:
+			section = line
+			continue
+		case line == "" || strings.HasPrefix(line, "#") || strings.Contains(line, " reply ("):
+			continue
+		}
+		switch section {
+		case bundleMeters:
+			meters = append(meters, line)
+		case bundleGroups:
+			groups = append(groups, line)
+		default:
+			flow, err := ParseFlow(line)
+			if err != nil {
+				return nil, errorf(CauseInvalidArgument, "line %d: %v", i+1, err)
+			}
+			bundle.Flows = append(bundle.Flows, flowAddString(flow))
+		}
+	}
+	if len(meters) > 0 {
+		// add-meter 语法以逗号分隔且 bands= 紧跟第一个带，转换为 dump-meters 的空白分隔形式后复用 ParseMeters
+		spec := strings.NewReplacer(",", " ", "bands=", "bands= ").Replace(strings.Join(meters, "\n"))
+		parsed, err := ParseMeters(spec)
+		if err != nil {
+			return nil, errorf(CauseInvalidArgument, "meters: %v", err)
+		}
+		bundle.Meters = parsed
+	}
+	if len(groups) > 0 {
+		parsed, err := ParseGroups(strings.Join(groups, "\n"))
+		if err != nil {
+			return nil, errorf(CauseInvalidArgument, "groups: %v", err)
+		}
+		bundle.Groups = parsed
+	}
+	return bundle, nil
+}
+
+// flowAddString 将解析后的流表还原为 add-flow 语法，去掉统计字段
+func flowAddString(f Flow) string {
+	var parts []string
+	if f.Cookie != 0 {
+		parts = append(parts, fmt.Sprintf("cookie=%#x", uint64(f.Cookie)))
+	}
+	parts = append(parts, f.MatchString())
+	if f.IdleTimeout > 0 {
+		parts = append(parts, fmt.Sprintf("idle_timeout=%d", f.IdleTimeout))
+	}
+	if f.HardTimeout > 0 {
+		parts = append(parts, fmt.Sprintf("hard_timeout=%d", f.HardTimeout))
+	}
+	if f.Importance > 0 {
+		parts = append(parts, fmt.Sprintf("importance=%d", f.Importance))
+	}
+	parts = append(parts, f.Flags...)
+	actions := "drop"
+	if len(f.Actions) > 0 {
+		actions = strings.Join(f.Actions, ",")
+	}
+	return strings.Join(parts, ",") + ",actions=" + actions
+}
+
+// mapFlowPorts 按 mapPort 替换 in_port 匹配及动作中的端口引用
+func mapFlowPorts(f Flow, mapPort func(string) string) Flow {
+	match := make([]FlowMatchField, len(f.Match))
+	for i, m := range f.Match {
+		if m.Field == "in_port" {
+			m.Value = mapPort(m.Value)
+		}
+		match[i] = m
+	}
+	f.Match = match
+	f.Actions = mapActionPorts(f.Actions, mapPort)
+	return f
+}
+
+// mapActionPorts 替换 output、enqueue、resubmit 及纯数字动作中的端口引用
+func mapActionPorts(actions []string, mapPort func(string) string) []string {
+	res := make([]string, len(actions))
+	for i, action := range actions {
+		res[i] = action
+		name, arg := action, ""
+		sep := strings.IndexAny(action, ":(")
+		if sep >= 0 {
+			name, arg = action[:sep], action[sep+1:]
+		}
+		paren := sep >= 0 && action[sep] == '('
+		switch strings.ToLower(name) {
+		case "output":
+			if !paren && !strings.HasPrefix(arg, "NXM_") && !strings.Contains(arg, "[") {
+				res[i] = "output:" + mapPort(arg)
+			}
+		case "enqueue":
+			if paren {
+				port, rest, _ := strings.Cut(arg, ",")
+				res[i] = "enqueue(" + mapPort(port) + "," + rest
+			} else if port, queue, ok := strings.Cut(arg, ":"); ok {
+				res[i] = "enqueue:" + mapPort(port) + ":" + queue
+			}
+		case "resubmit":
+			if !paren {
+				res[i] = "resubmit:" + mapPort(arg)
+			} else if port, rest, _ := strings.Cut(arg, ","); port != "" {
+				res[i] = "resubmit(" + mapPort(port) + "," + rest
+			}
+		default:
+			if _, err := strconv.Atoi(action); err == nil {
+				res[i] = "output:" + mapPort(action)
+			}
+		}
+	}
+	return res
+}
+
+// mapGroupPorts 替换组表桶中 watch_port 及动作的端口引用
+func mapGroupPorts(g Group, mapPort func(string) string) Group {
+	buckets := make([]GroupBucket, len(g.Buckets))
+	for i, b := range g.Buckets {
+		if b.WatchPort != "" {
+			b.WatchPort = mapPort(b.WatchPort)
+		}
+		b.Actions = mapActionPorts(b.Actions, mapPort)
+		buckets[i] = b
+	}
+	g.Buckets = buckets
+	return g
+}
+
+// bridgeFlows 读取网桥流表，支持 OpenFlow13 时按 OpenFlow13 读取以保留 meter 指令和 group 动作
+func bridgeFlows(ctx context.Context, bridge string, of13 bool) ([]Flow, error) {
+	if of13 {
+		return groupFlows(ctx, bridge)
+	}
+	return dumpFlows(ctx, bridge)
+}
+
+// ExportFlows 导出 bridge 的流表、组表和计量器，ofport 换算为端口名；网桥不支持 OpenFlow13 时只导出流表
+func ExportFlows(ctx context.Context, bridge string) (*FlowBundle, error) {
+	ports, err := bridgeOFPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	of13, err := bridgeSupportsOpenFlow13(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(ports))
+	for name, ofport := range ports {
+		if ofport >= 0 {
+			names[strconv.Itoa(ofport)] = name
+		}
+	}
+	toName := func(ref string) string {
+		if name, ok := names[ref]; ok {
+			return name
+		}
+		return ref
+	}
+
+	bundle := &FlowBundle{Bridge: bridge, Meters: []Meter{}, Groups: []Group{}, Flows: []string{}}
+	if of13 {
+		if bundle.Meters, err = ListMeters(ctx, bridge); err != nil {
+			return nil, err
+		}
+		groups, err := ListGroups(ctx, bridge)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			bundle.Groups = append(bundle.Groups, mapGroupPorts(g, toName))
+		}
+	}
+	flows, err := bridgeFlows(ctx, bridge, of13)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flows {
+		bundle.Flows = append(bundle.Flows, flowAddString(mapFlowPorts(f, toName)))
+	}
+	return bundle, nil
+}
+
+// FlowImportOptions 导入选项
+type FlowImportOptions struct {
+	PortMap   map[string]string // 源端口名到目标端口名的映射，未列出的端口按同名查找
+	Overwrite bool              // 目标网桥已有同 ID 的组表/计量器或同匹配的流表但内容不同时覆盖，否则视为冲突
+	DryRun    bool              // 只检查冲突不下发
+}
+
+// FlowImportConflict 导入冲突，Kind 为 meter、group 或 flow
+type FlowImportConflict struct {
+	Kind    string `json:"kind"`
+	Item    string `json:"item"`
+	Message string `json:"message"`
+}
+
+// FlowImportCounts 各类对象的导入结果计数
+type FlowImportCounts struct {
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// FlowImportReport 导入报告；存在冲突时不下发任何内容
+type FlowImportReport struct {
+	Meters    FlowImportCounts     `json:"meters"`
+	Groups    FlowImportCounts     `json:"groups"`
+	Flows     FlowImportCounts     `json:"flows"`
+	Conflicts []FlowImportConflict `json:"conflicts"`
+	Applied   bool                 `json:"applied"`
+}
+
+// ImportFlows 将导出内容导入 bridge：端口名换算为目标网桥的 ofport，与目标网桥已有内容比较，
+// 有冲突（端口不存在、流表校验失败、同 ID/同匹配但内容不同且未指定 Overwrite）时只返回报告不下发；
+// 否则依次下发计量器、组表，再以 OpenFlow bundle 原子下发流表；任一步失败时按相反顺序撤销已下发的
+// 计量器和组表（新增的删除，覆盖的恢复为原有定义），使网桥保持导入前的状态
+func ImportFlows(ctx context.Context, bridge string, bundle *FlowBundle, opts FlowImportOptions) (*FlowImportReport, error) {
+	ports, err := bridgeOFPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	of13, err := bridgeSupportsOpenFlow13(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	report := &FlowImportReport{Conflicts: []FlowImportConflict{}}
+	conflict := func(kind, item, format string, args ...interface{}) {
+		report.Conflicts = append(report.Conflicts, FlowImportConflict{Kind: kind, Item: item, Message: fmt.Sprintf(format, args...)})
+	}
+	// 端口映射失败的引用原样保留，由各对象的校验报告为冲突
+	toOFPort := func(ref string) string {
+		if target, ok := opts.PortMap[ref]; ok {
+			ref = target
+		}
+		if ofport, ok := ports[ref]; ok && ofport >= 0 {
+			return strconv.Itoa(ofport)
+		}
+		return ref
+	}
+	needOF13 := len(bundle.Meters) > 0 || len(bundle.Groups) > 0
+
+	var existingMeters []Meter
+	var existingGroups []Group
+	if needOF13 && of13 {
+		if existingMeters, err = ListMeters(ctx, bridge); err != nil {
+			return nil, err
+		}
+		if existingGroups, err = ListGroups(ctx, bridge); err != nil {
+			return nil, err
+		}
+	}
+
+	// undo 为撤销该命令的 verb 和 spec
+	type command struct {
+		verb, spec string
+		undo       [2]string
+	}
+	var meterCmds, groupCmds []command
+	for _, m := range bundle.Meters {
+		item := fmt.Sprintf("meter %d", m.ID)
+		if err := m.validate(); err != nil {
+			conflict("meter", item, "%v", err)
+			continue
+		}
+		verb, undo := "add-meter", [2]string{"del-meter", fmt.Sprintf("meter=%d", m.ID)}
+		for _, old := range existingMeters {
+			if old.ID == m.ID {
+				verb, undo = "mod-meter", [2]string{"mod-meter", old.String()}
+				if old.String() == m.String() {
+					verb = ""
+				}
+			}
+		}
+		switch {
+		case verb == "":
+			report.Meters.Unchanged++
+		case verb == "mod-meter" && !opts.Overwrite:
+			conflict("meter", item, "meter %d already exists on bridge %s with a different configuration: %s", m.ID, bridge, m.String())
+		default:
+			meterCmds = append(meterCmds, command{verb, m.String(), undo})
+		}
+	}
+	groups := append([]Group(nil), bundle.Groups...)
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	for _, g := range groups {
+		item := fmt.Sprintf("group %d", g.ID)
+		g = mapGroupPorts(g, toOFPort)
+		if err := g.validate(ports); err != nil {
+			conflict("group", item, "%v", err)
+			continue
+		}
+		verb, undo := "add-group", [2]string{"del-groups", fmt.Sprintf("group_id=%d", g.ID)}
+		for _, old := range existingGroups {
+			if old.ID == g.ID {
+				verb, undo = "mod-group", [2]string{"mod-group", old.String()}
+				if old.String() == g.String() {
+					verb = ""
+				}
+			}
+		}
+		switch {
+		case verb == "":
+			report.Groups.Unchanged++
+		case verb == "mod-group" && !opts.Overwrite:
+			conflict("group", item, "group %d already exists on bridge %s with different buckets: %s", g.ID, bridge, g.String())
+		default:
+			groupCmds = append(groupCmds, command{verb, g.String(), undo})
+		}
+	}
+
+	installed, err := bridgeFlows(ctx, bridge, of13)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]Flow, len(installed))
+	for _, f := range installed {
+		current[flowKey(f, ports)] = f
+	}
+	var flowLines, flowTexts []string
+	seen := make(map[string]string)
+	for _, text := range bundle.Flows {
+		flow, err := ParseFlow(text)
+		if err != nil {
+			conflict("flow", text, "%v", err)
+			continue
+		}
+		mapped := flowAddString(mapFlowPorts(flow, toOFPort))
+		if errs := checkFlow(mapped, ports); len(errs) > 0 {
+			conflict("flow", text, "%s: %s", errs[0].Field, errs[0].Message)
+			continue
+		}
+		flow, _ = ParseFlow(mapped)
+		key := flowKey(flow, ports)
+		if prev, dup := seen[key]; dup {
+			conflict("flow", text, "same match as %s", prev)
+			continue
+		}
+		seen[key] = text
+		old, exists := current[key]
+		switch {
+		case exists && flowBody(old, ports) == flowBody(flow, ports):
+			report.Flows.Unchanged++
+		case exists && !opts.Overwrite:
+			conflict("flow", text, "a flow with the same match is installed with %s", flowBody(old, ports))
+		default:
+			flowLines = append(flowLines, "add "+mapped)
+			flowTexts = append(flowTexts, mapped)
+			if exists {
+				report.Flows.Updated++
+			} else {
+				report.Flows.Added++
+			}
+		}
+	}
+	for _, c := range meterCmds {
+		if c.verb == "add-meter" {
+			report.Meters.Added++
+		} else {
+			report.Meters.Updated++
+		}
+	}
+	for _, c := range groupCmds {
+		if c.verb == "add-group" {
+			report.Groups.Added++
+		} else {
+			report.Groups.Updated++
+		}
+	}
+	if len(report.Conflicts) > 0 || opts.DryRun {
+		return report, nil
+	}
+
+	if len(meterCmds) > 0 || len(groupCmds) > 0 {
+		if err := requireOpenFlow13(ctx, bridge); err != nil {
+			return nil, err
+		}
+	}
+	if len(flowLines) > 0 {
+		if err := requireProtocols(ctx, bridge, bundleProtocol); err != nil {
+			return nil, err
+		}
+	}
+	// 计量器和组表均以 OpenFlow13 下发，applied 记录已成功的命令以便失败时撤销
+	var applied []command
+	rollback := func(cause error) error {
+		for i := len(applied) - 1; i >= 0; i-- {
+			c := applied[i]
+			if err := run(ctx, "ovs-ofctl", "-O", meterProtocol, c.undo[0], bridge, c.undo[1]); err != nil {
+				return fmt.Errorf("%w (rollback %s %s failed: %v)", cause, c.undo[0], c.undo[1], err)
+			}
+		}
+		return cause
+	}
+	for _, c := range append(append([]command(nil), meterCmds...), groupCmds...) {
+		if err := run(ctx, "ovs-ofctl", "-O", meterProtocol, c.verb, bridge, c.spec); err != nil {
+			return nil, rollback(err)
+		}
+		applied = append(applied, c)
+	}
+	if len(flowLines) > 0 {
+		input := []byte(strings.Join(flowLines, "\n") + "\n")
+		if _, err := runInput(ctx, input, "ovs-ofctl", "-O", bundleProtocol, "--bundle", "add-flows", bridge, "-"); err != nil {
+			return nil, rollback(err)
+		}
+		if store, _ := currentFlowStore(); store != nil {
+			for _, text := range flowTexts {
+				if err := store.record(bridge, text, ports); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	report.Applied = true
+	return report, nil
+}
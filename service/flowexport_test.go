@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFlowBundleText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want *FlowBundle
+	}{
+		{
+			name: "exported sections",
+			text: "# ovs-manager export of bridge br0\n" +
+				"# [meters]\n" +
+				"meter=1,kbps,burst,stats,bands=type=drop,rate=1000,burst_size=100\n" +
+				"meter=2,pktps,bands=type=dscp_remark,rate=10,prec_level=1\n" +
+				"# [groups]\n" +
+				"group_id=1,type=select,bucket=weight:10,actions=output:eth1,bucket=weight:20,actions=learn(table=10,NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[]),output:eth2\n" +
+				"# [flows]\n" +
+				"cookie=0x10,table=0,priority=100,ip,in_port=eth1,actions=meter:1,group:1\n" +
+				"table=1,priority=0,actions=drop\n",
+			want: &FlowBundle{
+				Meters: []Meter{
+					{ID: 1, Unit: "kbps", Burst: true, Stats: true, Bands: []MeterBand{{Type: "drop", Rate: 1000, BurstSize: 100}}},
+					{ID: 2, Unit: "pktps", Bands: []MeterBand{{Type: "dscp_remark", Rate: 10, PrecLevel: 1}}},
+				},
+				Groups: []Group{{ID: 1, Type: "select", Buckets: []GroupBucket{
+					{Weight: 10, Actions: []string{"output:eth1"}},
+					{Weight: 20, Actions: []string{"learn(table=10,NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[])", "output:eth2"}},
+				}}},
+				Flows: []string{
+					"cookie=0x10,table=0,priority=100,ip,in_port=eth1,actions=meter:1,group:1",
+					"table=1,priority=0,actions=drop",
+				},
+			},
+		},
+		{
+			name: "plain dump-flows output",
+			text: "NXST_FLOW reply (xid=0x4):\n" +
+				" cookie=0x0, duration=5.1s, table=0, n_packets=3, n_bytes=180, idle_timeout=60, idle_age=1, priority=10,arp actions=NORMAL\n" +
+				" cookie=0x0, duration=5.1s, table=0, n_packets=0, n_bytes=0, send_flow_rem priority=0 actions=drop\n",
+			want: &FlowBundle{Meters: []Meter{}, Groups: []Group{}, Flows: []string{
+				"table=0,priority=10,arp,idle_timeout=60,actions=NORMAL",
+				"table=0,priority=0,send_flow_rem,actions=drop",
+			}},
+		},
+		{
+			name: "empty",
+			text: "\n# nothing here\n",
+			want: &FlowBundle{Meters: []Meter{}, Groups: []Group{}, Flows: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFlowBundleText(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFlowBundleText =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			// Text 的输出应能解析回相同的内容
+			again, err := ParseFlowBundleText(got.Text())
+			if err != nil || !reflect.DeepEqual(again, got) {
+				t.Errorf("round trip of\n%s= %+v, %v", got.Text(), again, err)
+			}
+		})
+	}
+	for _, text := range []string{
+		"# [flows]\ntable=0,actions=drop\ntable=x,actions=drop\n",
+		"# [meters]\nmeter=x,kbps,bands=type=drop,rate=1\n",
+		"# [groups]\ngroup_id=x,type=all\n",
+	} {
+		if _, err := ParseFlowBundleText(text); CauseOf(err) != CauseInvalidArgument {
+			t.Errorf("ParseFlowBundleText(%q) error = %v", text, err)
+		}
+	}
+	if _, err := ParseFlowBundleText("table=0,actions=drop\ntable=x,actions=drop\n"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error does not name the line: %v", err)
+	}
+}
+
+// expectImportBridge 预设 br0（eth1、eth2 的 ofport 为 1、2，protocols 含 OpenFlow13/14）上已有 meter 1、无组表和流表
+func expectImportBridge(fake *FakeRunner) {
+	fake.Expect("ovs-vsctl --format=json --data=json -- list Bridge -- list Port -- list Interface",
+		vsctlList([]map[string]interface{}{{"name": "br0", "ports": fixtureSet(fixtureUUID(1), fixtureUUID(2))}}, []string{"name", "ports"})+
+			vsctlList([]map[string]interface{}{
+				{"_uuid": fixtureUUID(1), "name": "eth1", "interfaces": fixtureUUID(11)},
+				{"_uuid": fixtureUUID(2), "name": "eth2", "interfaces": fixtureUUID(12)},
+			}, []string{"_uuid", "name", "interfaces"})+
+			vsctlList([]map[string]interface{}{
+				{"_uuid": fixtureUUID(11), "name": "eth1", "ofport": 1},
+				{"_uuid": fixtureUUID(12), "name": "eth2", "ofport": 2},
+			}, []string{"_uuid", "name", "ofport"}))
+	expectProtocols(fake, "OpenFlow13", "OpenFlow14")
+	fake.Expect("ovs-ofctl -O OpenFlow13 dump-meters br0", "OFPST_METER_CONFIG reply (OF1.3) (xid=0x2):\nmeter=1 kbps bands=\ntype=drop rate=1000\n")
+	fake.Expect("ovs-ofctl -O OpenFlow13 dump-groups br0", "OFPST_GROUP_DESC reply (OF1.3) (xid=0x2):\n")
+	fake.Expect("ovs-ofctl -O OpenFlow13 dump-flows br0", "OFPST_FLOW reply (OF1.3) (xid=0x2):\n")
+}
+
+func TestImportFlowsRollsBackOnBundleFailure(t *testing.T) {
+	fake := NewFakeRunner()
+	expectImportBridge(fake)
+	fake.Expect("ovs-ofctl -O OpenFlow13 mod-meter br0 meter=1,kbps,bands=type=drop,rate=2000", "")
+	fake.Expect("ovs-ofctl -O OpenFlow13 add-meter br0 meter=2,kbps,bands=type=drop,rate=500", "")
+	fake.Expect("ovs-ofctl -O OpenFlow13 add-group br0 group_id=1,type=all,bucket=actions=output:2", "")
+	fake.ExpectError("ovs-ofctl -O OpenFlow14 --bundle add-flows br0 -", errors.New("exit status 1"))
+	fake.Expect("ovs-ofctl -O OpenFlow13 del-groups br0 group_id=1", "")
+	fake.Expect("ovs-ofctl -O OpenFlow13 del-meter br0 meter=2", "")
+	fake.Expect("ovs-ofctl -O OpenFlow13 mod-meter br0 meter=1,kbps,bands=type=drop,rate=1000", "")
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+
+	bundle := &FlowBundle{
+		Meters: []Meter{
+			{ID: 1, Unit: "kbps", Bands: []MeterBand{{Type: "drop", Rate: 2000}}},
+			{ID: 2, Unit: "kbps", Bands: []MeterBand{{Type: "drop", Rate: 500}}},
+		},
+		Groups: []Group{{ID: 1, Type: "all", Buckets: []GroupBucket{{Actions: []string{"output:eth2"}}}}},
+		Flows:  []string{"table=0,priority=10,in_port=eth1,actions=meter:1,group:1"},
+	}
+	report, err := ImportFlows(context.Background(), "br0", bundle, FlowImportOptions{Overwrite: true})
+	if err == nil {
+		t.Fatalf("ImportFlows succeeded: %+v", report)
+	}
+	var ce *CommandError
+	if !errors.As(err, &ce) {
+		t.Errorf("error = %v, want the add-flows failure", err)
+	}
+	var writes []string
+	for _, call := range fake.Calls() {
+		if !strings.Contains(call, " dump-") && !strings.HasPrefix(call, "ovs-vsctl ") {
+			writes = append(writes, call)
+		}
+	}
+	want := []string{
+		"ovs-ofctl -O OpenFlow13 mod-meter br0 meter=1,kbps,bands=type=drop,rate=2000",
+		"ovs-ofctl -O OpenFlow13 add-meter br0 meter=2,kbps,bands=type=drop,rate=500",
+		"ovs-ofctl -O OpenFlow13 add-group br0 group_id=1,type=all,bucket=actions=output:2",
+		"ovs-ofctl -O OpenFlow14 --bundle add-flows br0 -",
+		"ovs-ofctl -O OpenFlow13 del-groups br0 group_id=1",
+		"ovs-ofctl -O OpenFlow13 del-meter br0 meter=2",
+		"ovs-ofctl -O OpenFlow13 mod-meter br0 meter=1,kbps,bands=type=drop,rate=1000",
+	}
+	if !reflect.DeepEqual(writes, want) {
+		t.Errorf("writes =\n%s\nwant\n%s", strings.Join(writes, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestImportFlowsRollsBackOnGroupFailure(t *testing.T) {
+	fake := NewFakeRunner()
+	expectImportBridge(fake)
+	fake.Expect("ovs-ofctl -O OpenFlow13 add-meter br0 meter=2,kbps,bands=type=drop,rate=500", "")
+	fake.ExpectError("ovs-ofctl -O OpenFlow13 add-group br0 group_id=1,type=all,bucket=actions=output:2", errors.New("exit status 1"))
+	fake.ExpectError("ovs-ofctl -O OpenFlow13 del-meter br0 meter=2", errors.New("exit status 1"))
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+
+	bundle := &FlowBundle{
+		Meters: []Meter{{ID: 2, Unit: "kbps", Bands: []MeterBand{{Type: "drop", Rate: 500}}}},
+		Groups: []Group{{ID: 1, Type: "all", Buckets: []GroupBucket{{Actions: []string{"output:eth2"}}}}},
+	}
+	_, err := ImportFlows(context.Background(), "br0", bundle, FlowImportOptions{})
+	// 撤销失败时错误中同时给出原因和未能撤销的命令
+	if err == nil || !strings.Contains(err.Error(), "add-group") || !strings.Contains(err.Error(), "rollback del-meter meter=2 failed") {
+		t.Errorf("error = %v", err)
+	}
+	if calls := fake.Calls(); strings.Contains(strings.Join(calls, "\n"), "add-flows") {
+		t.Errorf("flows applied after group failure: %q", calls)
+	}
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFlowStaleAfter 零报文流表被视为陈旧的默认存活时长
+const DefaultFlowStaleAfter = 24 * time.Hour
+
+// 流表检查问题类型
+const (
+	LintShadowed    = "shadowed"          // 被同表更高优先级的流表完全覆盖，永远不会命中
+	LintDuplicate   = "duplicate"         // 与同表另一条流表匹配和动作完全相同
+	LintUnreachable = "unreachable_table" // 所在表无法从表 0 经 resubmit/goto_table 到达
+	LintMissingPort = "missing_port"      // 引用了网桥上不存在的端口
+	LintStale       = "stale"             // 存活超过阈值但报文计数为 0
+)
+
+// FlowLintOptions 流表检查参数
+type FlowLintOptions struct {
+	StaleAfter time.Duration // 为 0 时不检查陈旧流表
+}
+
+// FlowLintIssue 一条检查结果，Flow 为问题流表的匹配部分，Related 为覆盖或重复的另一条流表
+type FlowLintIssue struct {
+	Kind     string `json:"kind"`
+	Table    int    `json:"table"`
+	Priority int    `json:"priority"`
+	Flow     string `json:"flow"`
+	Related  string `json:"related,omitempty"`
+	Message  string `json:"message"`
+}
+
+// FlowLintReport 流表检查报告
+type FlowLintReport struct {
+	FlowCount int             `json:"flowCount"`
+	Issues    []FlowLintIssue `json:"issues"`
+	Summary   map[string]int  `json:"summary"`
+}
+
+// LintFlows 检查 bridge 上的流表：被覆盖、重复、所在表不可达、引用不存在的端口以及长期零报文的流表
+func LintFlows(ctx context.Context, bridge string, opts FlowLintOptions) (*FlowLintReport, error) {
+	ports, err := bridgeOFPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	flows, err := dumpFlows(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	return lintFlows(flows, ports, opts), nil
+}
+
+// lintFlows 对已解析的流表做静态分析，ports 为 bridge 上的端口名到 ofport 映射
+func lintFlows(flows []Flow, ports map[string]int, opts FlowLintOptions) *FlowLintReport {
+	report := &FlowLintReport{FlowCount: len(flows), Issues: []FlowLintIssue{}, Summary: map[string]int{}}
+	add := func(kind string, f Flow, related, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, FlowLintIssue{
+			Kind: kind, Table: f.Table, Priority: f.Priority, Flow: f.MatchString(), Related: related,
+			Message: fmt.Sprintf(format, args...),
+		})
+		report.Summary[kind]++
+	}
+
+	// 同表内按优先级从高到低比较，每条流表只报告第一个覆盖它的流表
+	sorted := append([]Flow(nil), flows...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Table != sorted[j].Table {
+			return sorted[i].Table < sorted[j].Table
+		}
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	matches := make([]map[string]string, len(sorted))
+	for i, f := range sorted {
+		matches[i] = normalizeFlowMatch(f)
+	}
+	for i, f := range sorted {
+		for j := 0; j < i; j++ {
+			prev := sorted[j]
+			if prev.Table != f.Table || !canShadow(prev) {
+				continue
+			}
+			sameActions := strings.Join(prev.Actions, ",") == strings.Join(f.Actions, ",")
+			if sameActions && sameFlowMatch(matches[j], matches[i]) {
+				add(LintDuplicate, f, prev.MatchString(), "same match and actions as another flow in table %d", f.Table)
+				break
+			}
+			if prev.Priority > f.Priority && coversFlowMatch(matches[j], matches[i]) {
+				if sameActions {
+					add(LintShadowed, f, prev.MatchString(), "fully covered by a higher priority flow with the same actions, the flow is redundant")
+				} else {
+					add(LintShadowed, f, prev.MatchString(), "fully covered by a higher priority flow, the flow never matches")
+				}
+				break
+			}
+		}
+	}
+
+	reachable := reachableTables(flows)
+	for _, f := range sorted {
+		if !reachable[f.Table] {
+			add(LintUnreachable, f, "", "no resubmit or goto_table reaches table %d from table 0", f.Table)
+		}
+	}
+
+	for _, f := range sorted {
+		for _, ref := range flowPortRefs(f) {
+			if msg := checkPortRef(ref, ports); msg != "" {
+				add(LintMissingPort, f, "", "%s", msg)
+			}
+		}
+	}
+
+	if opts.StaleAfter > 0 {
+		for _, f := range sorted {
+			if f.NPackets == 0 && !containsString(f.Flags, "no_packet_counts") && f.Duration >= opts.StaleAfter.Seconds() {
+				add(LintStale, f, "", "no packets matched in %s", time.Duration(f.Duration*float64(time.Second)).Truncate(time.Second))
+			}
+		}
+	}
+	return report
+}
+
+// canShadow 带 conjunction 动作或 conj_id 匹配的流表不按普通方式命中，不参与覆盖判断
+func canShadow(f Flow) bool {
+	if _, ok := f.MatchValue("conj_id"); ok {
+		return false
+	}
+	for _, a := range f.Actions {
+		if strings.HasPrefix(a, "conjunction(") {
+			return false
+		}
+	}
+	return true
+}
+
+// protocolEthertypes 协议简写对应的 dl_type
+var protocolEthertypes = map[string]uint64{
+	"ip": 0x0800, "icmp": 0x0800, "tcp": 0x0800, "udp": 0x0800, "sctp": 0x0800,
+	"ipv6": 0x86dd, "icmp6": 0x86dd, "tcp6": 0x86dd, "udp6": 0x86dd, "sctp6": 0x86dd,
+	"arp": 0x0806, "rarp": 0x8035, "mpls": 0x8847, "mplsm": 0x8848,
+}
+
+// normalizeFlowMatch 展开协议简写并统一字段名，返回字段到取值的映射
+func normalizeFlowMatch(f Flow) map[string]string {
+	res := make(map[string]string, len(f.Match))
+	for _, m := range f.Match {
+		if ethertype, ok := protocolEthertypes[m.Field]; ok && m.Value == "" {
+			res["dl_type"] = fmt.Sprintf("%#06x", ethertype)
+			if p := flowProtocols[m.Field]; p.nwProto != 0 {
+				res["nw_proto"] = strconv.Itoa(p.nwProto)
+			}
+			continue
+		}
+		field := m.Field
+		if alias, ok := matchAliases[field]; ok {
+			field = alias
+		}
+		value := strings.ToLower(m.Value)
+		switch field {
+		case "dl_type":
+			if v, err := parseUint(value); err == nil {
+				value = fmt.Sprintf("%#06x", v)
+			}
+		case "nw_proto":
+			if v, err := parseUint(value); err == nil {
+				value = strconv.FormatUint(v, 10)
+			}
+		}
+		res[field] = value
+	}
+	return res
+}
+
+func sameFlowMatch(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for field, va := range a {
+		if vb, ok := b[field]; !ok || !flowValueCovers(va, vb) || !flowValueCovers(vb, va) {
+			return false
+		}
+	}
+	return true
+}
+
+// coversFlowMatch 判断匹配 a 是否包含匹配 b 能命中的所有报文：a 的每个字段 b 都有，且取值范围不大于 a
+func coversFlowMatch(a, b map[string]string) bool {
+	for field, va := range a {
+		vb, ok := b[field]
+		if !ok || !flowValueCovers(va, vb) {
+			return false
+		}
+	}
+	return true
+}
+
+// flowValueCovers 判断字段取值 a（可带掩码或前缀）是否包含取值 b
+func flowValueCovers(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if pa, ok := parseFlowPrefix(a); ok {
+		pb, ok := parseFlowPrefix(b)
+		return ok && pa.Addr().Is4() == pb.Addr().Is4() && pa.Bits() <= pb.Bits() && pa.Contains(pb.Addr())
+	}
+	va, ma, ok := parseMaskedValue(a)
+	if !ok {
+		return false
+	}
+	vb, mb, ok := parseMaskedValue(b)
+	return ok && mb&ma == ma && vb&ma == va&ma
+}
+
+// parseFlowPrefix 解析 IP 地址或 CIDR 前缀（nw_src=10.0.0.0/24、ipv6_dst=fd00::/64）
+func parseFlowPrefix(s string) (netip.Prefix, bool) {
+	if p, err := netip.ParsePrefix(s); err == nil {
+		return p.Masked(), true
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
+
+// parseMaskedValue 解析 value/mask 形式的整数或 MAC 取值，无掩码时为全 1
+func parseMaskedValue(s string) (uint64, uint64, bool) {
+	value, mask, hasMask := strings.Cut(s, "/")
+	v, ok := parseFlowUint(value)
+	if !ok {
+		return 0, 0, false
+	}
+	m := ^uint64(0)
+	if hasMask {
+		if m, ok = parseFlowUint(mask); !ok {
+			return 0, 0, false
+		}
+	}
+	return v & m, m, true
+}
+
+func parseFlowUint(s string) (uint64, bool) {
+	if v, err := parseUint(s); err == nil {
+		return v, true
+	}
+	if mac, err := net.ParseMAC(s); err == nil && len(mac) == 6 {
+		var v uint64
+		for _, b := range mac {
+			v = v<<8 | uint64(b)
+		}
+		return v, true
+	}
+	return 0, false
+}
+
+// reachableTables 从表 0 出发，沿 resubmit、goto_table 及 ct(table=N) 可到达的表
+func reachableTables(flows []Flow) map[int]bool {
+	edges := make(map[int][]int)
+	for _, f := range flows {
+		edges[f.Table] = append(edges[f.Table], flowTableRefs(f.Actions)...)
+	}
+	reachable := map[int]bool{0: true}
+	queue := []int{0}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[t] {
+			if !reachable[next] {
+				reachable[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return reachable
+}
+
+// flowTableRefs 返回动作跳转到的表号，递归进入 clone 等嵌套动作
+func flowTableRefs(actions []string) []int {
+	var res []int
+	addTable := func(s string) {
+		if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			res = append(res, n)
+		}
+	}
+	for _, action := range actions {
+		name, arg := action, ""
+		if i := strings.IndexAny(action, ":("); i >= 0 {
+			name, arg = action[:i], strings.TrimSuffix(action[i+1:], ")")
+		}
+		switch strings.ToLower(name) {
+		case "goto_table":
+			addTable(arg)
+		case "resubmit":
+			if !strings.Contains(action, "(") {
+				// resubmit:port 在当前表重新查找
+				continue
+			}
+			_, rest, _ := strings.Cut(arg, ",")
+			if t, _, _ := strings.Cut(rest, ","); t != "" {
+				addTable(t)
+			}
+		case "ct":
+			for _, opt := range splitTopLevel(arg) {
+				if t, ok := strings.CutPrefix(opt, "table="); ok {
+					addTable(t)
+				}
+			}
+		case "clone", "write_actions", "apply_actions":
+			res = append(res, flowTableRefs(splitTopLevel(arg))...)
+		}
+	}
+	return res
+}
+
+// flowPortRefs 返回流表匹配和动作中引用的端口（in_port、output、enqueue、resubmit 端口）
+func flowPortRefs(f Flow) []string {
+	var refs []string
+	if v, ok := f.MatchValue("in_port"); ok {
+		refs = append(refs, v)
+	}
+	for _, action := range f.Actions {
+		name, arg := action, ""
+		if i := strings.IndexAny(action, ":("); i >= 0 {
+			name, arg = action[:i], strings.TrimSuffix(action[i+1:], ")")
+		}
+		switch strings.ToLower(name) {
+		case "output":
+			if !strings.HasPrefix(arg, "NXM_") && !strings.Contains(arg, "[") {
+				refs = append(refs, arg)
+			}
+		case "enqueue", "resubmit":
+			port, _, _ := strings.Cut(arg, ":")
+			port, _, _ = strings.Cut(port, ",")
+			refs = append(refs, port)
+		default:
+			if _, err := strconv.Atoi(action); err == nil {
+				refs = append(refs, action)
+			}
+		}
+	}
+	return refs
+}
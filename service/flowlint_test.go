@@ -0,0 +1,102 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const lintDump = "NXST_FLOW reply (xid=0x4):\n" +
+	" cookie=0x0, duration=100s, table=0, n_packets=5, n_bytes=500, priority=200,ip actions=resubmit(,1)\n" +
+	" cookie=0x0, duration=100s, table=0, n_packets=0, n_bytes=0, priority=100,tcp,nw_dst=10.0.0.1 actions=output:2\n" +
+	" cookie=0x0, duration=100s, table=0, n_packets=5, n_bytes=500, priority=50,in_port=1 actions=ct(commit,table=3),goto_table:2\n" +
+	" cookie=0x0, duration=100s, table=0, n_packets=5, n_bytes=500, priority=0 actions=clone(resubmit(,4)),learn(table=5,NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[]),NORMAL\n" +
+	" cookie=0x0, duration=100s, table=1, n_packets=5, n_bytes=500, priority=100,dl_type=0x0800,nw_dst=10.0.0.0/8 actions=output:eth1\n" +
+	" cookie=0x0, duration=100s, table=1, n_packets=5, n_bytes=500, priority=100,ip,nw_dst=10.0.0.0/8 actions=output:eth1\n" +
+	" cookie=0x0, duration=100s, table=1, n_packets=5, n_bytes=500, priority=90,ip,nw_dst=10.1.0.0/16 actions=output:3\n" +
+	" cookie=0x0, duration=100s, table=1, n_packets=5, n_bytes=500, priority=80,ip,nw_dst=192.168.0.0/16 actions=output:9\n" +
+	" cookie=0x0, duration=100s, table=2, n_packets=5, n_bytes=500, priority=100,ip,nw_src=10.0.0.1 actions=conjunction(1,1/2)\n" +
+	" cookie=0x0, duration=100s, table=2, n_packets=5, n_bytes=500, priority=100,ip actions=conjunction(1,2/2)\n" +
+	" cookie=0x0, duration=100s, table=2, n_packets=5, n_bytes=500, priority=50,ip,nw_src=10.0.0.1 actions=drop\n" +
+	" cookie=0x0, duration=90000s, table=3, n_packets=0, n_bytes=0, priority=10,in_port=eth9 actions=drop\n" +
+	" cookie=0x0, duration=90000s, table=4, n_packets=0, n_bytes=0, no_packet_counts priority=10 actions=drop\n" +
+	" cookie=0x0, duration=90000s, table=5, n_packets=7, n_bytes=700, priority=0 actions=drop\n"
+
+func TestLintFlows(t *testing.T) {
+	flows, err := ParseFlows(lintDump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports := map[string]int{"eth1": 1, "eth2": 2, "eth3": 3}
+	report := lintFlows(flows, ports, FlowLintOptions{StaleAfter: DefaultFlowStaleAfter})
+
+	// 每条结果概括为 "类型 流表 <- 相关流表"
+	var got []string
+	for _, issue := range report.Issues {
+		s := issue.Kind + " " + issue.Flow
+		if issue.Related != "" {
+			s += " <- " + issue.Related
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"shadowed table=0,priority=100,tcp,nw_dst=10.0.0.1 <- table=0,priority=200,ip",
+		"duplicate table=1,priority=100,ip,nw_dst=10.0.0.0/8 <- table=1,priority=100,dl_type=0x0800,nw_dst=10.0.0.0/8",
+		"shadowed table=1,priority=90,ip,nw_dst=10.1.0.0/16 <- table=1,priority=100,dl_type=0x0800,nw_dst=10.0.0.0/8",
+		"unreachable_table table=5,priority=0",
+		"missing_port table=1,priority=80,ip,nw_dst=192.168.0.0/16",
+		"missing_port table=3,priority=10,in_port=eth9",
+		"stale table=3,priority=10,in_port=eth9",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	wantSummary := map[string]int{LintShadowed: 2, LintDuplicate: 1, LintUnreachable: 1, LintMissingPort: 2, LintStale: 1}
+	if report.FlowCount != len(flows) || !reflect.DeepEqual(report.Summary, wantSummary) {
+		t.Errorf("flow count = %d, summary = %v", report.FlowCount, report.Summary)
+	}
+	if len(report.Issues) == len(want) {
+		if msg := report.Issues[4].Message; msg != "no port with ofport 9 on bridge" {
+			t.Errorf("missing ofport message = %q", msg)
+		}
+		if msg := report.Issues[6].Message; msg != "no packets matched in 25h0m0s" {
+			t.Errorf("stale message = %q", msg)
+		}
+	}
+
+	report = lintFlows(flows, ports, FlowLintOptions{})
+	if report.Summary[LintStale] != 0 {
+		t.Errorf("stale flows reported without StaleAfter: %v", report.Summary)
+	}
+}
+
+func TestFlowValueCovers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"10.0.0.0/8", "10.1.0.0/16", true},
+		{"10.1.0.0/16", "10.0.0.0/8", false},
+		{"10.0.0.0/8", "10.0.0.1", true},
+		{"fd00::/64", "fd00::1", true},
+		{"10.0.0.0/8", "fd00::1", false},
+		{"0x1/0x1", "0x3", true},
+		{"0x1/0x1", "0x2", false},
+		{"10", "0xa", true},
+		{"00:00:00:00:00:00/01:00:00:00:00:00", "52:54:00:00:00:01", true},
+		{"01:00:00:00:00:00/01:00:00:00:00:00", "52:54:00:00:00:01", false},
+		{"eth1", "eth2", false},
+	}
+	for _, tt := range tests {
+		if got := flowValueCovers(tt.a, tt.b); got != tt.want {
+			t.Errorf("flowValueCovers(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFlowTableRefs(t *testing.T) {
+	actions := splitTopLevel("resubmit(,1),resubmit:2,goto_table:3,ct(commit,zone=1,table=4),clone(set_field:1->reg0,resubmit(,5)),write_actions(resubmit(1,6)),learn(table=7,output:NXM_OF_IN_PORT[])")
+	if got := flowTableRefs(actions); !reflect.DeepEqual(got, []int{1, 3, 4, 5, 6}) {
+		t.Errorf("flowTableRefs = %v", got)
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultFlowPriority OpenFlow 默认优先级，ovs-ofctl 输出中省略
+const defaultFlowPriority = 32768
+
+// FlowCookie 流表 cookie，JSON 中以十六进制字符串表示，避免 64 位整数精度丢失
+type FlowCookie uint64
+
+// MarshalJSON 输出 "0x..." 格式
+func (c FlowCookie) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%#x\"", uint64(c))), nil
+}
+
+// UnmarshalJSON 支持十六进制/十进制字符串或数字，掩码可写作 -1
+func (c *FlowCookie) UnmarshalJSON(b []byte) error {
+	v, err := parseCookie(strings.Trim(string(b), "\""))
+	if err != nil {
+		return fmt.Errorf("invalid cookie %s", b)
+	}
+	*c = v
+	return nil
+}
+
+// FlowMatchField 流表的一个匹配字段，如 ip 或 nw_src=10.0.0.1
+type FlowMatchField struct {
+	Field string `json:"field"`
+	Value string `json:"value,omitempty"`
+}
+
+// Flow ovs-ofctl dump-flows 的一条流表项
+type Flow struct {
+	Cookie      FlowCookie       `json:"cookie"`
+	Table       int              `json:"table"`
+	Priority    int              `json:"priority"`
+	Duration    float64          `json:"duration"`
+	NPackets    uint64           `json:"nPackets"`
+	NBytes      uint64           `json:"nBytes"`
+	IdleTimeout int              `json:"idleTimeout"`
+	HardTimeout int              `json:"hardTimeout"`
+	IdleAge     *int             `json:"idleAge,omitempty"`
+	HardAge     *int             `json:"hardAge,omitempty"`
+	Importance  int              `json:"importance,omitempty"`
+	Flags       []string         `json:"flags,omitempty"`
+	Match       []FlowMatchField `json:"match"`
+	Actions     []string         `json:"actions"`
+	Raw         string           `json:"raw"`
+}
+
+// MatchValue 返回匹配字段的值，ok 表示流表是否匹配该字段
+func (f Flow) MatchValue(field string) (string, bool) {
+	for _, m := range f.Match {
+		if m.Field == field {
+			return m.Value, true
+		}
+	}
+	return "", false
+}
+
+// MatchString 按 ovs-ofctl 语法重新拼接匹配部分（含 table 和 priority）
+func (f Flow) MatchString() string {
+	parts := []string{fmt.Sprintf("table=%d", f.Table), fmt.Sprintf("priority=%d", f.Priority)}
+	for _, m := range f.Match {
+		if m.Value == "" {
+			parts = append(parts, m.Field)
+		} else {
+			parts = append(parts, m.Field+"="+m.Value)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// flowFlags 不带值的流表标志
+var flowFlags = map[string]bool{
+	"send_flow_rem":    true,
+	"check_overlap":    true,
+	"reset_counts":     true,
+	"no_packet_counts": true,
+	"no_byte_counts":   true,
+}
+
+// ParseFlows 解析 ovs-ofctl dump-flows 输出，忽略 NXST_FLOW/OFPST_FLOW 等回复头
+func ParseFlows(output string) ([]Flow, error) {
+	flows := []Flow{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, "_FLOW reply") {
+			continue
+		}
+		flow, err := ParseFlow(line)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
+
+// ParseFlow 解析单条流表，也可用于 add-flow 语法（无统计字段）
+func ParseFlow(line string) (Flow, error) {
+	line = strings.TrimSpace(line)
+	flow := Flow{Priority: defaultFlowPriority, Match: []FlowMatchField{}, Actions: []string{}, Raw: line}
+	matchPart, actionPart := line, ""
+	if idx := strings.Index(line, "actions="); idx >= 0 {
+		matchPart, actionPart = line[:idx], line[idx+len("actions="):]
+	}
+	for _, tok := range splitTopLevel(matchPart) {
+		key, value, hasValue := strings.Cut(tok, "=")
+		var err error
+		switch key {
+		case "cookie":
+			flow.Cookie, err = parseCookie(strings.SplitN(value, "/", 2)[0])
+		case "duration":
+			flow.Duration, err = strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+		case "table":
+			flow.Table, err = strconv.Atoi(value)
+		case "priority":
+			flow.Priority, err = strconv.Atoi(value)
+		case "n_packets":
+			flow.NPackets, err = strconv.ParseUint(value, 10, 64)
+		case "n_bytes":
+			flow.NBytes, err = strconv.ParseUint(value, 10, 64)
+		case "idle_timeout":
+			flow.IdleTimeout, err = strconv.Atoi(value)
+		case "hard_timeout":
+			flow.HardTimeout, err = strconv.Atoi(value)
+		case "importance":
+			flow.Importance, err = strconv.Atoi(value)
+		case "idle_age", "hard_age":
+			var v int
+			v, err = strconv.Atoi(value)
+			if key == "idle_age" {
+				flow.IdleAge = &v
+			} else {
+				flow.HardAge = &v
+			}
+		default:
+			if !hasValue && flowFlags[key] {
+				flow.Flags = append(flow.Flags, key)
+			} else {
+				flow.Match = append(flow.Match, FlowMatchField{Field: key, Value: value})
+			}
+		}
+		if err != nil {
+			return Flow{}, errorf(CauseInvalidArgument, "invalid flow %q: bad %s value %q", line, key, value)
+		}
+	}
+	flow.Actions = append(flow.Actions, splitTopLevel(actionPart)...)
+	return flow, nil
+}
+
+// splitTopLevel 按不在括号内的逗号和空白切分，忽略空项
+func splitTopLevel(s string) []string {
+	var res []string
+	depth, start := 0, 0
+	flush := func(end int) {
+		if tok := strings.TrimSpace(s[start:end]); tok != "" {
+			res = append(res, tok)
+		}
+		start = end + 1
+	}
+	for i, ch := range s {
+		switch ch {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',', ' ', '\t':
+			if depth == 0 {
+				flush(i)
+			}
+		}
+	}
+	flush(len(s))
+	return res
+}
+
+// parseUint 解析十进制或 0x 开头的十六进制数
+func parseUint(s string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimSpace(s), 0, 64)
+}
+
+// parseCookie 解析 cookie 或 cookie 掩码，同 ovs-ofctl 一样接受有符号数（-1 表示全 1）
+func parseCookie(s string) (FlowCookie, error) {
+	if v, err := parseUint(s); err == nil {
+		return FlowCookie(v), nil
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 0, 64)
+	return FlowCookie(v), err
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseFlows(t *testing.T) {
+	idle, hard := 3, 10
+	output := "NXST_FLOW reply (xid=0x4):\n" +
+		" cookie=0x1f, duration=12.345s, table=0, n_packets=10, n_bytes=980, idle_timeout=60, idle_age=3, hard_age=10, priority=100,ip,in_port=1,nw_dst=10.0.0.0/24 actions=mod_dl_src:00:00:00:00:00:01,output:2\n" +
+		" cookie=0x0, duration=5.1s, table=1, n_packets=0, n_bytes=0, send_flow_rem reset_counts priority=0 actions=drop\n" +
+		" cookie=0xffffffffffffffff, duration=1s, table=2, n_packets=0, n_bytes=0, importance=5, tcp,tp_dst=80 actions=learn(table=10,hard_timeout=60,priority=10,NXM_OF_VLAN_TCI[0..11],NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[]),resubmit(,3)\n" +
+		" table=3, priority=10,reg0=0x1/0xffff actions=ct(commit,zone=1,exec(load:0x1->NXM_NX_CT_MARK[])),set_field:{0x2,0x3}->tun_metadata0,write_actions(output:4)\n"
+	flows, err := ParseFlows(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Flow{
+		{Cookie: 0x1f, Table: 0, Priority: 100, Duration: 12.345, NPackets: 10, NBytes: 980, IdleTimeout: 60, IdleAge: &idle, HardAge: &hard,
+			Match:   []FlowMatchField{{Field: "ip"}, {Field: "in_port", Value: "1"}, {Field: "nw_dst", Value: "10.0.0.0/24"}},
+			Actions: []string{"mod_dl_src:00:00:00:00:00:01", "output:2"}},
+		{Table: 1, Priority: 0, Duration: 5.1, Flags: []string{"send_flow_rem", "reset_counts"},
+			Match: []FlowMatchField{}, Actions: []string{"drop"}},
+		{Cookie: 0xffffffffffffffff, Table: 2, Priority: defaultFlowPriority, Duration: 1, Importance: 5,
+			Match: []FlowMatchField{{Field: "tcp"}, {Field: "tp_dst", Value: "80"}},
+			Actions: []string{
+				"learn(table=10,hard_timeout=60,priority=10,NXM_OF_VLAN_TCI[0..11],NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[])",
+				"resubmit(,3)",
+			}},
+		{Table: 3, Priority: 10,
+			Match: []FlowMatchField{{Field: "reg0", Value: "0x1/0xffff"}},
+			Actions: []string{
+				"ct(commit,zone=1,exec(load:0x1->NXM_NX_CT_MARK[]))",
+				"set_field:{0x2,0x3}->tun_metadata0",
+				"write_actions(output:4)",
+			}},
+	}
+	if len(flows) != len(want) {
+		t.Fatalf("got %d flows, want %d", len(flows), len(want))
+	}
+	for i := range want {
+		want[i].Raw = flows[i].Raw
+		if !reflect.DeepEqual(flows[i], want[i]) {
+			t.Errorf("flow %d =\n%+v\nwant\n%+v", i, flows[i], want[i])
+		}
+	}
+	if got := flows[0].MatchString(); got != "table=0,priority=100,ip,in_port=1,nw_dst=10.0.0.0/24" {
+		t.Errorf("MatchString = %q", got)
+	}
+	if v, ok := flows[0].MatchValue("nw_dst"); !ok || v != "10.0.0.0/24" {
+		t.Errorf("MatchValue(nw_dst) = %q, %v", v, ok)
+	}
+}
+
+func TestParseFlowErrors(t *testing.T) {
+	for _, line := range []string{
+		"table=x,actions=drop",
+		"priority=high,actions=drop",
+		"cookie=0xzz,actions=drop",
+		"n_packets=-1,actions=drop",
+		"duration=forever,actions=drop",
+	} {
+		if _, err := ParseFlow(line); CauseOf(err) != CauseInvalidArgument {
+			t.Errorf("ParseFlow(%q) error = %v", line, err)
+		}
+	}
+}
+
+func TestSplitTopLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a,b  c,,", []string{"a", "b", "c"}},
+		{"output:1,learn(table=1,output:NXM_OF_IN_PORT[]),resubmit(,2)",
+			[]string{"output:1", "learn(table=1,output:NXM_OF_IN_PORT[])", "resubmit(,2)"}},
+		{"load:0x1->NXM_NX_REG0[0..15],set_field:{1,2}->tun_metadata0",
+			[]string{"load:0x1->NXM_NX_REG0[0..15]", "set_field:{1,2}->tun_metadata0"}},
+		{"clone(ct(commit,exec(set_field:1->ct_mark)),output:2),drop",
+			[]string{"clone(ct(commit,exec(set_field:1->ct_mark)),output:2)", "drop"}},
+		// 括号不配对时剩余部分作为一项，不会丢失内容
+		{"learn(table=1,output:2", []string{"learn(table=1,output:2"}},
+	}
+	for _, tt := range tests {
+		if got := splitTopLevel(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitTopLevel(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFlowCookieJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want FlowCookie
+	}{
+		{`"0x1f"`, 0x1f},
+		{`"31"`, 31},
+		{`31`, 31},
+		{`"-1"`, 0xffffffffffffffff},
+		{`"0xffffffffffffffff"`, 0xffffffffffffffff},
+	}
+	for _, tt := range tests {
+		var c FlowCookie
+		if err := json.Unmarshal([]byte(tt.in), &c); err != nil || c != tt.want {
+			t.Errorf("Unmarshal(%s) = %#x, %v", tt.in, uint64(c), err)
+		}
+	}
+	var c FlowCookie
+	if err := json.Unmarshal([]byte(`"cookie"`), &c); err == nil {
+		t.Error("invalid cookie accepted")
+	}
+	if b, _ := json.Marshal(FlowCookie(0xabc)); string(b) != `"0xabc"` {
+		t.Errorf("Marshal = %s", b)
+	}
+}
@@ -0,0 +1,566 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FlowStoreCookie 未指定 cookie 的流表写入存储时打上的 cookie（"ovsm"），用于识别由 ovs-manager 安装的流表
+const FlowStoreCookie FlowCookie = 0x6f76736d00000000
+
+// FlowStore 按网桥持久化经 API 安装的流表，ovs-vswitchd 重启或网桥重建后自动重新下发
+type FlowStore struct {
+	path string
+	mu   sync.Mutex
+	// bridges 网桥到流表文本（add-flow 语法）的映射，按安装顺序排列
+	bridges map[string][]string
+	// uuids 巡检时记录的网桥 _uuid，变化说明网桥被删除后重建
+	uuids map[string]string
+	stop  chan struct{}
+}
+
+var (
+	flowStoreMu sync.RWMutex
+	flowStore   *FlowStore
+)
+
+// SetFlowStore 设置流表存储，nil 表示关闭；返回之前的存储
+func SetFlowStore(s *FlowStore) *FlowStore {
+	flowStoreMu.Lock()
+	defer flowStoreMu.Unlock()
+	old := flowStore
+	flowStore = s
+	return old
+}
+
+// currentFlowStore 返回当前流表存储，未启用时返回 not-found 错误
+func currentFlowStore() (*FlowStore, error) {
+	flowStoreMu.RLock()
+	defer flowStoreMu.RUnlock()
+	if flowStore == nil {
+		return nil, errorf(CauseNotFound, "flow store is not enabled (start with -flow-store)")
+	}
+	return flowStore, nil
+}
+
+// OpenFlowStore 从 path 加载流表存储，文件不存在时创建空存储
+func OpenFlowStore(path string) (*FlowStore, error) {
+	s := &FlowStore{path: path, bridges: map[string][]string{}, uuids: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Bridges map[string][]string `json:"bridges"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("flow store %s: %v", path, err)
+	}
+	if file.Bridges != nil {
+		s.bridges = file.Bridges
+	}
+	return s, nil
+}
+
+// StartFlowStore 打开流表存储并启动巡检，interval 为 0 时只记录不自动重新下发；
+// 巡检无法区分 ovs-vswitchd 重启和运维在 API 之外删除流表，因此需显式指定 interval 开启
+func StartFlowStore(path string, interval time.Duration) (*FlowStore, error) {
+	s, err := OpenFlowStore(path)
+	if err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		s.stop = make(chan struct{})
+		go s.watch(interval)
+	}
+	SetFlowStore(s)
+	return s, nil
+}
+
+// Close 停止巡检
+func (s *FlowStore) Close() {
+	if s.stop != nil {
+		close(s.stop)
+	}
+}
+
+// save 先写临时文件再重命名，避免进程中途退出留下不完整的文件；调用方持有 mu
+func (s *FlowStore) save() error {
+	data, err := json.MarshalIndent(struct {
+		Bridges map[string][]string `json:"bridges"`
+	}{s.bridges}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Flows 返回 bridge 上存储的流表
+func (s *FlowStore) Flows(bridge string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.bridges[bridge]...)
+}
+
+// Bridges 返回有存储流表的网桥，按名称排序
+func (s *FlowStore) Bridges() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]string, 0, len(s.bridges))
+	for br := range s.bridges {
+		res = append(res, br)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// record 记录新安装的流表，表号、优先级和匹配相同的旧记录被替换（与 add-flow 语义一致）；
+// ports 用于将 in_port 的端口名与 ofport 视为同一匹配
+func (s *FlowStore) record(bridge, text string, ports map[string]int) error {
+	flow, err := ParseFlow(text)
+	if err != nil {
+		return err
+	}
+	key := flowKey(flow, ports)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flows := s.bridges[bridge]
+	replaced := false
+	for i, stored := range flows {
+		if f, err := ParseFlow(stored); err == nil && flowKey(f, ports) == key {
+			flows[i], replaced = text, true
+			break
+		}
+	}
+	if !replaced {
+		flows = append(flows, text)
+	}
+	s.bridges[bridge] = flows
+	return s.save()
+}
+
+// flowDeleteMatch 解析后的 del-flows 条件，match 为空表示删除全部流表
+type flowDeleteMatch struct {
+	all      bool
+	hasTable bool
+	table    int
+	cookie   *FlowCookie
+	mask     FlowCookie
+	fields   map[string]string
+}
+
+// parseFlowDeleteMatch 解析 del-flows 条件；在执行 del-flows 之前调用，
+// 避免交换机上的流表已删除而存储中的记录因条件无法解析而残留
+func parseFlowDeleteMatch(match string) (*flowDeleteMatch, error) {
+	if strings.TrimSpace(match) == "" {
+		return &flowDeleteMatch{all: true}, nil
+	}
+	target, err := ParseFlow(match)
+	if err != nil {
+		return nil, err
+	}
+	del := &flowDeleteMatch{table: target.Table, fields: normalizeFlowMatch(target)}
+	for _, tok := range splitTopLevel(match) {
+		key, value, _ := strings.Cut(tok, "=")
+		switch key {
+		case "table":
+			del.hasTable = true
+		case "cookie":
+			c, m, hasMask := strings.Cut(value, "/")
+			cookie, err := parseCookie(c)
+			if err != nil {
+				return nil, errorf(CauseInvalidArgument, "invalid cookie %q", value)
+			}
+			del.cookie, del.mask = &cookie, ^FlowCookie(0)
+			if hasMask {
+				if del.mask, err = parseCookie(m); err != nil {
+					return nil, errorf(CauseInvalidArgument, "invalid cookie mask %q", value)
+				}
+			}
+		}
+	}
+	return del, nil
+}
+
+// covers 按 del-flows 的非严格匹配语义判断流表是否被删除：表号、cookie 符合且匹配字段被条件包含；
+// in_port 按 ports 换算为 ofport 后比较
+func (d *flowDeleteMatch) covers(f Flow, ports map[string]int) bool {
+	return d.all || (!d.hasTable || f.Table == d.table) &&
+		(d.cookie == nil || f.Cookie&d.mask == *d.cookie&d.mask) &&
+		coversFlowMatch(inPortOFPort(d.fields, ports), inPortOFPort(normalizeFlowMatch(f), ports))
+}
+
+// inPortOFPort 将已规整的匹配中以端口名表示的 in_port 换算为 ofport，规整时端口名已转为小写
+func inPortOFPort(fields map[string]string, ports map[string]int) map[string]string {
+	value, ok := fields["in_port"]
+	if !ok {
+		return fields
+	}
+	for name, ofport := range ports {
+		if ofport >= 0 && strings.EqualFold(name, value) {
+			res := make(map[string]string, len(fields))
+			for k, v := range fields {
+				res[k] = v
+			}
+			res["in_port"] = strconv.Itoa(ofport)
+			return res
+		}
+	}
+	return fields
+}
+
+// forget 删除满足 drop 的记录
+func (s *FlowStore) forget(bridge string, drop func(Flow) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.bridges[bridge]; !ok {
+		return nil
+	}
+	kept := []string{}
+	for _, stored := range s.bridges[bridge] {
+		if f, err := ParseFlow(stored); err == nil && drop(f) {
+			continue
+		}
+		kept = append(kept, stored)
+	}
+	if len(kept) == 0 {
+		delete(s.bridges, bridge)
+	} else {
+		s.bridges[bridge] = kept
+	}
+	return s.save()
+}
+
+// replace 声明式同步后用 desired 替换 cookie 范围内的记录，范围外的记录保留
+func (s *FlowStore) replace(bridge string, inScope func(FlowCookie) bool, desired []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var flows []string
+	for _, stored := range s.bridges[bridge] {
+		if f, err := ParseFlow(stored); err == nil && !inScope(f.Cookie) {
+			flows = append(flows, stored)
+		}
+	}
+	flows = append(flows, desired...)
+	if len(flows) == 0 {
+		delete(s.bridges, bridge)
+	} else {
+		s.bridges[bridge] = flows
+	}
+	return s.save()
+}
+
+// ListStoredFlows 返回存储的流表，bridge 为空时返回所有网桥
+func ListStoredFlows(bridge string) (map[string][]string, error) {
+	s, err := currentFlowStore()
+	if err != nil {
+		return nil, err
+	}
+	res := map[string][]string{}
+	for _, br := range s.Bridges() {
+		if bridge == "" || br == bridge {
+			res[br] = s.Flows(br)
+		}
+	}
+	return res, nil
+}
+
+// flowHasCookie 判断 add-flow 表达式是否显式指定了 cookie
+func flowHasCookie(text string) bool {
+	matchPart, _, _ := strings.Cut(text, "actions=")
+	for _, tok := range splitTopLevel(matchPart) {
+		if strings.HasPrefix(tok, "cookie=") {
+			return true
+		}
+	}
+	return false
+}
+
+// tagFlowCookie 为未指定 cookie 的 add-flow 表达式加上 cookie
+func tagFlowCookie(text string, cookie FlowCookie) string {
+	return fmt.Sprintf("cookie=%#x,%s", uint64(cookie), text)
+}
+
+// FlowDrift 存储的流表与网桥上已安装流表的差异
+type FlowDrift struct {
+	Bridge   string       `json:"bridge"`
+	Stored   int          `json:"stored"`
+	Missing  []string     `json:"missing"`  // 已存储但未安装
+	Modified []FlowChange `json:"modified"` // 已安装但动作或超时与存储不同
+	Extra    []string     `json:"extra"`    // 带 FlowStoreCookie 但未存储的已安装流表
+	InSync   int          `json:"inSync"`
+	// Unresolved 缺失或被修改、但引用的组表或计量器不在网桥上的存储流表，不会重新下发
+	Unresolved []FlowUnresolved `json:"unresolved"`
+	// reinstall 需要重新下发的存储流表
+	reinstall []string
+	// keys 存储流表去重后的匹配数，与 Missing 比较判断是否全部缺失
+	keys int
+}
+
+// FlowUnresolved 依赖缺失而无法重新下发的存储流表
+type FlowUnresolved struct {
+	Flow    string   `json:"flow"`
+	Missing []string `json:"missing"` // 如 "group 3"、"meter 1"
+}
+
+// FlowStoreDrift 比较 bridge 上存储的流表与已安装流表
+func FlowStoreDrift(ctx context.Context, bridge string) (*FlowDrift, error) {
+	s, err := currentFlowStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.drift(ctx, bridge)
+}
+
+func (s *FlowStore) drift(ctx context.Context, bridge string) (*FlowDrift, error) {
+	stored := s.Flows(bridge)
+	ports, err := bridgeOFPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	of13, err := bridgeSupportsOpenFlow13(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := bridgeFlows(ctx, bridge, of13)
+	if err != nil {
+		return nil, err
+	}
+	drift := &FlowDrift{Bridge: bridge, Stored: len(stored), Missing: []string{}, Modified: []FlowChange{}, Extra: []string{}, Unresolved: []FlowUnresolved{}}
+	want := make(map[string]Flow, len(stored))
+	var order []string
+	for _, text := range stored {
+		flow, err := ParseFlow(text)
+		if err != nil {
+			continue
+		}
+		key := flowKey(flow, ports)
+		if _, dup := want[key]; !dup {
+			order = append(order, key)
+		}
+		want[key] = flow
+	}
+	seen := make(map[string]bool)
+	for _, flow := range installed {
+		key := flowKey(flow, ports)
+		target, ok := want[key]
+		switch {
+		case !ok:
+			if flow.Cookie == FlowStoreCookie {
+				drift.Extra = append(drift.Extra, flow.Raw)
+			}
+			continue
+		case flowBody(flow, ports) != flowBody(target, ports):
+			drift.Modified = append(drift.Modified, FlowChange{Match: key, Old: flowBody(flow, ports), New: flowBody(target, ports)})
+			drift.reinstall = append(drift.reinstall, target.Raw)
+		default:
+			drift.InSync++
+		}
+		seen[key] = true
+	}
+	for _, key := range order {
+		if !seen[key] {
+			drift.Missing = append(drift.Missing, want[key].Raw)
+			drift.reinstall = append(drift.reinstall, want[key].Raw)
+		}
+	}
+	drift.keys = len(order)
+	if err := resolveFlowDeps(ctx, bridge, of13, drift); err != nil {
+		return nil, err
+	}
+	return drift, nil
+}
+
+// resolveFlowDeps 存储只保存流表，ovs-vswitchd 重启后组表和计量器同样丢失；
+// 将引用了网桥上不存在的组表或计量器的流表移出 reinstall，避免整个 bundle 被交换机拒绝
+func resolveFlowDeps(ctx context.Context, bridge string, of13 bool, drift *FlowDrift) error {
+	refs := make([][]string, len(drift.reinstall))
+	needed := false
+	for i, text := range drift.reinstall {
+		flow, _ := ParseFlow(text)
+		for _, id := range actionArgs(flow.Actions, "group") {
+			refs[i] = append(refs[i], "group "+id)
+		}
+		for _, id := range actionArgs(flow.Actions, "meter") {
+			refs[i] = append(refs[i], "meter "+id)
+		}
+		needed = needed || len(refs[i]) > 0
+	}
+	if !needed {
+		return nil
+	}
+	present := make(map[string]bool)
+	if of13 {
+		groups, err := ListGroups(ctx, bridge)
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			present[fmt.Sprintf("group %d", g.ID)] = true
+		}
+		meters, err := ListMeters(ctx, bridge)
+		if err != nil {
+			return err
+		}
+		for _, m := range meters {
+			present[fmt.Sprintf("meter %d", m.ID)] = true
+		}
+	}
+	var reinstall []string
+	for i, text := range drift.reinstall {
+		var missing []string
+		for _, ref := range refs[i] {
+			if !present[ref] {
+				missing = append(missing, ref)
+			}
+		}
+		if len(missing) > 0 {
+			drift.Unresolved = append(drift.Unresolved, FlowUnresolved{Flow: text, Missing: missing})
+			continue
+		}
+		reinstall = append(reinstall, text)
+	}
+	drift.reinstall = reinstall
+	return nil
+}
+
+// ReplayFlowStore 重新下发 bridge 上缺失或被修改的存储流表，返回下发前的差异
+func ReplayFlowStore(ctx context.Context, bridge string) (*FlowDrift, error) {
+	s, err := currentFlowStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.replay(ctx, bridge)
+}
+
+func (s *FlowStore) replay(ctx context.Context, bridge string) (*FlowDrift, error) {
+	drift, err := s.drift(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	if err := reinstallFlows(ctx, bridge, drift.reinstall); err != nil {
+		return nil, err
+	}
+	return drift, nil
+}
+
+// reinstallFlows 通过 OpenFlow bundle 一次性下发流表，同一匹配的已安装流表被覆盖
+func reinstallFlows(ctx context.Context, bridge string, flows []string) error {
+	if len(flows) == 0 {
+		return nil
+	}
+	lines := make([]string, len(flows))
+	for i, text := range flows {
+		lines[i] = "add " + text
+	}
+	if err := requireProtocols(ctx, bridge, bundleProtocol); err != nil {
+		return err
+	}
+	input := []byte(strings.Join(lines, "\n") + "\n")
+	_, err := runInput(ctx, input, "ovs-ofctl", "-O", bundleProtocol, "--bundle", "add-flows", bridge, "-")
+	return err
+}
+
+// watch 定期巡检存储中的网桥：网桥 _uuid 变化（被删除后重建）或存储的流表全部缺失
+// （ovs-vswitchd 重启后流表被清空）时重新下发；部分差异只通过 drift 接口报告，不自动覆盖。
+// 运维绕过 API（如直接 ovs-ofctl del-flows）删除全部存储流表时同样会被重新下发，经 API 删除的流表已从存储中移除。
+// 依赖的组表或计量器不存在的流表不下发，只在 drift 的 unresolved 中报告
+func (s *FlowStore) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := WithDefaultTimeout(context.Background())
+			if err := s.check(ctx); err != nil {
+				log.Printf("flow store: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// check 执行一次巡检
+func (s *FlowStore) check(ctx context.Context) error {
+	bridges := s.Bridges()
+	if len(bridges) == 0 {
+		return nil
+	}
+	tables, err := queryTables(ctx, tableQuery{Table: "Bridge", Columns: []string{"_uuid", "name"}})
+	if err != nil {
+		return err
+	}
+	current := make(map[string]string)
+	for _, br := range tables["Bridge"] {
+		current[br.String("name")] = br.UUID("_uuid")
+	}
+	for _, bridge := range bridges {
+		uuid, ok := current[bridge]
+		s.mu.Lock()
+		prev := s.uuids[bridge]
+		if ok {
+			s.uuids[bridge] = uuid
+		} else {
+			delete(s.uuids, bridge)
+		}
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+		drift, err := s.drift(ctx, bridge)
+		if err != nil {
+			log.Printf("flow store: %s: %v", bridge, err)
+			continue
+		}
+		if len(drift.reinstall) == 0 {
+			continue
+		}
+		var reason string
+		switch {
+		case prev != "" && prev != uuid:
+			reason = "bridge was recreated"
+		case drift.keys > 0 && len(drift.Missing) == drift.keys:
+			reason = "all stored flows are missing (ovs-vswitchd restarted?)"
+		default:
+			continue
+		}
+		if err := reinstallFlows(ctx, bridge, drift.reinstall); err != nil {
+			log.Printf("flow store: %s: %s, reinstall failed: %v", bridge, reason, err)
+			continue
+		}
+		if len(drift.Unresolved) > 0 {
+			log.Printf("flow store: %s: %s, reinstalled %d flows, skipped %d flows whose groups or meters are missing", bridge, reason, len(drift.reinstall), len(drift.Unresolved))
+			continue
+		}
+		log.Printf("flow store: %s: %s, reinstalled %d flows", bridge, reason, len(drift.reinstall))
+	}
+	return nil
+}
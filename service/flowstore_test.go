@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestFlowStore 在临时目录创建流表存储并设为当前存储，测试结束后恢复
+func newTestFlowStore(t *testing.T) *FlowStore {
+	t.Helper()
+	s, err := OpenFlowStore(filepath.Join(t.TempDir(), "flows.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := SetFlowStore(s)
+	t.Cleanup(func() { SetFlowStore(prev) })
+	return s
+}
+
+func TestFlowStoreRecordInPortName(t *testing.T) {
+	s := newTestFlowStore(t)
+	ports := map[string]int{"eth1": 1}
+	for _, text := range []string{
+		"table=0,priority=10,in_port=eth1,actions=drop",
+		"table=0,priority=10,in_port=1,actions=output:2",
+		"table=0,priority=10,in_port=2,actions=drop",
+	} {
+		if err := s.record("br0", text, ports); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"table=0,priority=10,in_port=1,actions=output:2", "table=0,priority=10,in_port=2,actions=drop"}
+	if got := s.Flows("br0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("stored flows = %q, want %q", got, want)
+	}
+	// 重新打开后内容不变
+	reopened, err := OpenFlowStore(s.path)
+	if err != nil || !reflect.DeepEqual(reopened.Flows("br0"), want) {
+		t.Errorf("reopened store = %q, %v", reopened.Flows("br0"), err)
+	}
+}
+
+func TestFlowStoreForgetOnAPIDelete(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(fake *FakeRunner)
+		delete func(ctx context.Context) error
+		want   []string
+	}{
+		{
+			name:   "delete-v2 by ofport",
+			expect: func(fake *FakeRunner) { fake.Expect("ovs-ofctl del-flows br0 in_port=1", "") },
+			delete: func(ctx context.Context) error { return DeleteFlowV2(ctx, "br0", "in_port=1") },
+			want:   []string{"cookie=0x2,table=0,priority=5,in_port=eth2,actions=meter:1,output:1"},
+		},
+		{
+			name:   "delete-v2 by port name",
+			expect: func(fake *FakeRunner) { fake.Expect("ovs-ofctl del-flows br0 table=0,in_port=eth2", "") },
+			delete: func(ctx context.Context) error { return DeleteFlowV2(ctx, "br0", "table=0,in_port=eth2") },
+			want:   []string{"cookie=0x1,table=0,priority=10,in_port=eth1,actions=output:2"},
+		},
+		{
+			name:   "meter",
+			expect: func(fake *FakeRunner) { fake.Expect("ovs-ofctl -O OpenFlow13 del-meter br0 meter=1", "") },
+			delete: func(ctx context.Context) error { return DeleteMeter(ctx, "br0", 1) },
+			want:   []string{"cookie=0x1,table=0,priority=10,in_port=eth1,actions=output:2"},
+		},
+		{
+			name:   "bridge",
+			expect: func(fake *FakeRunner) { fake.Expect("ovs-vsctl del-br br0", "") },
+			delete: func(ctx context.Context) error { return DeleteBridge(ctx, "br0") },
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestFlowStore(t)
+			ports := map[string]int{"eth1": 1, "eth2": 2}
+			s.record("br0", "cookie=0x1,table=0,priority=10,in_port=eth1,actions=output:2", ports)
+			s.record("br0", "cookie=0x2,table=0,priority=5,in_port=eth2,actions=meter:1,output:1", ports)
+			fake := NewFakeRunner()
+			expectImportBridge(fake)
+			tt.expect(fake)
+			prev := SetRunner(fake)
+			defer SetRunner(prev)
+
+			if err := tt.delete(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Flows("br0"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stored flows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
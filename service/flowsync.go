@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlowChange 匹配条件相同但动作或超时不同的流表
+type FlowChange struct {
+	Match string `json:"match"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// FlowDiff 期望流表与已安装流表的差异
+type FlowDiff struct {
+	Added     []string     `json:"added"`
+	Removed   []string     `json:"removed"`
+	Modified  []FlowChange `json:"modified"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// Empty 是否没有任何变更
+func (d FlowDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// FlowSyncOptions 声明式流表同步选项
+type FlowSyncOptions struct {
+	Cookie     *FlowCookie // 只管理 cookie 匹配的流表，其余流表不受影响
+	CookieMask *FlowCookie // 为空时精确匹配 cookie
+	DryRun     bool        // 只计算差异不下发
+}
+
+// inScope 判断 cookie 是否在同步管理的范围内，未指定 Cookie 时管理全部流表
+func (o FlowSyncOptions) inScope(c FlowCookie) bool {
+	if o.Cookie == nil {
+		return true
+	}
+	mask := ^FlowCookie(0)
+	if o.CookieMask != nil {
+		mask = *o.CookieMask
+	}
+	return c&mask == *o.Cookie&mask
+}
+
+// matchAliases 同义匹配字段，统一为 ovs-ofctl dump-flows 的输出名称
+var matchAliases = map[string]string{
+	"eth_src": "dl_src", "eth_dst": "dl_dst", "eth_type": "dl_type",
+	"ip_src": "nw_src", "ip_dst": "nw_dst", "ip_proto": "nw_proto",
+	"tcp_src": "tp_src", "tcp_dst": "tp_dst", "udp_src": "tp_src", "udp_dst": "tp_dst",
+	"sctp_src": "tp_src", "sctp_dst": "tp_dst", "tunnel_id": "tun_id",
+	"icmpv4_type": "icmp_type", "icmpv4_code": "icmp_code", "icmpv6_type": "icmp_type", "icmpv6_code": "icmp_code",
+}
+
+// l4Shorthands 三层协议 + nw_proto 对应的协议简写
+var l4Shorthands = map[string]string{
+	"ip/1": "icmp", "ip/6": "tcp", "ip/17": "udp", "ip/132": "sctp",
+	"ipv6/58": "icmp6", "ipv6/6": "tcp6", "ipv6/17": "udp6", "ipv6/132": "sctp6",
+}
+
+// flowKey 以表号、优先级和规范化后的匹配字段作为流表的唯一标识
+func flowKey(f Flow, ports map[string]int) string {
+	fields := make(map[string]string, len(f.Match))
+	for _, m := range f.Match {
+		field := m.Field
+		if alias, ok := matchAliases[field]; ok {
+			field = alias
+		}
+		value := m.Value
+		if field == "in_port" {
+			if ofport, ok := ports[value]; ok {
+				value = strconv.Itoa(ofport)
+			}
+		}
+		fields[field] = value
+	}
+	if v, ok := fields["dl_type"]; ok {
+		if n, err := parseUint(v); err == nil {
+			if l3, ok := dlTypes[n]; ok && (n == 0x0800 || n == 0x86dd || n == 0x0806) {
+				delete(fields, "dl_type")
+				fields[l3] = ""
+			}
+		}
+	}
+	for _, l3 := range []string{"ip", "ipv6"} {
+		if _, ok := fields[l3]; !ok {
+			continue
+		}
+		if short, ok := l4Shorthands[l3+"/"+fields["nw_proto"]]; ok {
+			delete(fields, l3)
+			delete(fields, "nw_proto")
+			fields[short] = ""
+		}
+	}
+	parts := make([]string, 0, len(fields))
+	for field, value := range fields {
+		if value == "" {
+			parts = append(parts, field)
+		} else {
+			parts = append(parts, field+"="+value)
+		}
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("table=%d,priority=%d,%s", f.Table, f.Priority, strings.Join(parts, ","))
+}
+
+// flowBody 参与比较的流表内容：超时和规范化后的动作
+func flowBody(f Flow, ports map[string]int) string {
+	return fmt.Sprintf("idle_timeout=%d,hard_timeout=%d,actions=%s", f.IdleTimeout, f.HardTimeout, strings.Join(normalizeFlowActions(f.Actions, ports), ","))
+}
+
+// normalizeFlowActions 与 flowKey 规范化匹配字段一样统一动作写法：保留端口转为 dump-flows 的大写形式
+// （controller 展开为 CONTROLLER:65535），纯数字或端口名写作 output:N，output/enqueue/resubmit 中的端口名换算为 ofport
+func normalizeFlowActions(actions []string, ports map[string]int) []string {
+	toOFPort := func(ref string) string {
+		if ofport, ok := ports[ref]; ok && ofport >= 0 {
+			return strconv.Itoa(ofport)
+		}
+		return ref
+	}
+	res := mapActionPorts(actions, toOFPort)
+	for i, action := range res {
+		port := action
+		if name, arg, ok := strings.Cut(action, ":"); ok && strings.EqualFold(name, "output") {
+			port = arg
+		}
+		switch upper := strings.ToUpper(port); {
+		case upper == "CONTROLLER":
+			res[i] = "CONTROLLER:65535"
+		case specialPorts[upper]:
+			res[i] = upper
+		case strings.EqualFold(action, "drop"):
+			res[i] = "drop"
+		case port == action && toOFPort(action) != action:
+			res[i] = "output:" + toOFPort(action)
+		}
+	}
+	return res
+}
+
+// DiffFlows 计算期望流表与 bridge 上已安装流表的差异，指定 cookie 时只比较该 cookie 范围内的流表
+func DiffFlows(ctx context.Context, bridge string, desired []string, opts FlowSyncOptions) (*FlowDiff, []string, error) {
+	ports, err := bridgeOFPorts(ctx, bridge)
+	if err != nil {
+		return nil, nil, err
+	}
+	inScope := opts.inScope
+	want := make(map[string]Flow, len(desired))
+	var order []string
+	for i, text := range desired {
+		if errs := checkFlow(text, ports); len(errs) > 0 {
+			e := errs[0]
+			return nil, nil, errorf(CauseInvalidArgument, "flow %d %q: %s: %s", i, text, e.Field, e.Message)
+		}
+		flow, _ := ParseFlow(text)
+		if opts.Cookie != nil {
+			if !flowHasCookie(text) {
+				flow.Cookie = *opts.Cookie
+				flow.Raw = tagFlowCookie(text, flow.Cookie)
+			} else if !inScope(flow.Cookie) {
+				return nil, nil, errorf(CauseInvalidArgument, "flow %d %q: cookie %#x is outside the managed cookie scope", i, text, uint64(flow.Cookie))
+			}
+		}
+		key := flowKey(flow, ports)
+		if _, dup := want[key]; dup {
+			return nil, nil, errorf(CauseInvalidArgument, "flow %d %q: duplicate match %s", i, text, key)
+		}
+		want[key] = flow
+		order = append(order, key)
+	}
+
+	installed, err := dumpFlows(ctx, bridge)
+	if err != nil {
+		return nil, nil, err
+	}
+	diff := &FlowDiff{Added: []string{}, Removed: []string{}, Modified: []FlowChange{}}
+	var lines []string
+	seen := make(map[string]bool)
+	for _, flow := range installed {
+		if !inScope(flow.Cookie) {
+			continue
+		}
+		key := flowKey(flow, ports)
+		seen[key] = true
+		target, ok := want[key]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, flow.Raw)
+			lines = append(lines, "delete_strict "+flow.MatchString())
+		case flowBody(flow, ports) != flowBody(target, ports):
+			diff.Modified = append(diff.Modified, FlowChange{Match: key, Old: flowBody(flow, ports), New: flowBody(target, ports)})
+			if flow.IdleTimeout == target.IdleTimeout && flow.HardTimeout == target.HardTimeout {
+				// 只有动作变化时 modify_strict 保留计数
+				lines = append(lines, "modify_strict "+target.Raw)
+			} else {
+				lines = append(lines, "add "+target.Raw)
+			}
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, key := range order {
+		if !seen[key] {
+			diff.Added = append(diff.Added, want[key].Raw)
+			lines = append(lines, "add "+want[key].Raw)
+		}
+	}
+	return diff, lines, nil
+}
+
+// bundleProtocol OpenFlow bundle 需要 OpenFlow 1.4 及以上
+const bundleProtocol = "OpenFlow14"
+
+// requireProtocols 网桥显式配置了 protocols 且缺少 versions 中的版本时返回 conflict；
+// protocols 属于运维配置，流表调用不会顺带修改，未配置时默认已启用 OpenFlow10-14
+func requireProtocols(ctx context.Context, bridge string, versions ...string) error {
+	protocols, err := bridgeProtocols(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	if len(protocols) == 0 {
+		return nil
+	}
+	var missing []string
+	for _, v := range versions {
+		if !containsString(protocols, v) {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		return errorf(CauseConflict, "bridge %s protocols [%s] do not include %s", bridge, strings.Join(protocols, ","), strings.Join(missing, ","))
+	}
+	return nil
+}
+
+// ReplaceFlows 声明式同步 bridge 流表：计算差异后通过 OpenFlow bundle 一次性原子下发，
+// 只新增、删除或修改有变化的流表，未变化的流表及计数不受影响；启用流表存储时以 desired 替换 cookie 范围内的记录
+func ReplaceFlows(ctx context.Context, bridge string, desired []string, opts FlowSyncOptions) (*FlowDiff, error) {
+	diff, lines, err := DiffFlows(ctx, bridge, desired, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return diff, nil
+	}
+	if !diff.Empty() {
+		if err := requireProtocols(ctx, bridge, bundleProtocol); err != nil {
+			return nil, err
+		}
+		input := []byte(strings.Join(lines, "\n") + "\n")
+		if _, err := runInput(ctx, input, "ovs-ofctl", "-O", bundleProtocol, "--bundle", "add-flows", bridge, "-"); err != nil {
+			return nil, err
+		}
+	}
+	if store, _ := currentFlowStore(); store != nil {
+		stored := make([]string, len(desired))
+		for i, text := range desired {
+			stored[i] = text
+			if opts.Cookie != nil && !flowHasCookie(text) {
+				stored[i] = tagFlowCookie(text, *opts.Cookie)
+			}
+		}
+		return diff, store.replace(bridge, opts.inScope, stored)
+	}
+	return diff, nil
+}
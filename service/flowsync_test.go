@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// expectProtocols 为 bridgeProtocols 的批量查询预设 br0 的 protocols 列
+func expectProtocols(fake *FakeRunner, protocols ...interface{}) {
+	cols := []string{"name", "protocols"}
+	fake.Expect("ovs-vsctl --format=json --data=json -- --columns=name,protocols list Bridge",
+		vsctlList([]map[string]interface{}{{"name": "br0", "protocols": fixtureSet(protocols...)}}, cols))
+}
+
+func TestRequireProtocols(t *testing.T) {
+	tests := []struct {
+		protocols []interface{}
+		wantErr   bool
+	}{
+		{nil, false},
+		{[]interface{}{"OpenFlow13", "OpenFlow14"}, false},
+		{[]interface{}{"OpenFlow10", "OpenFlow13"}, true},
+	}
+	for _, tt := range tests {
+		fake := NewFakeRunner()
+		expectProtocols(fake, tt.protocols...)
+		prev := SetRunner(fake)
+		err := requireProtocols(context.Background(), "br0", bundleProtocol)
+		SetRunner(prev)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("protocols %v: err = %v", tt.protocols, err)
+		}
+		if err != nil && (CauseOf(err) != CauseConflict || !strings.Contains(err.Error(), bundleProtocol)) {
+			t.Errorf("protocols %v: err = %v, cause %v", tt.protocols, err, CauseOf(err))
+		}
+		// 只读取 protocols，不修改网桥配置
+		if calls := fake.Calls(); len(calls) != 1 {
+			t.Errorf("protocols %v: calls = %q", tt.protocols, calls)
+		}
+	}
+}
+
+func TestFlowKeyAliases(t *testing.T) {
+	ports := map[string]int{"eth1": 1}
+	// dump-flows 输出 tun_id，请求中常写作 tunnel_id
+	a, _ := ParseFlow("table=0,priority=10,in_port=eth1,tunnel_id=0x5,eth_type=0x0800,ip_proto=6,actions=drop")
+	b, _ := ParseFlow(" cookie=0x0, duration=1s, table=0, n_packets=0, n_bytes=0, priority=10,tcp,in_port=1,tun_id=0x5 actions=drop")
+	if ka, kb := flowKey(a, ports), flowKey(b, ports); ka != kb {
+		t.Errorf("flowKey(%q) = %q, flowKey(%q) = %q", a.Raw, ka, b.Raw, kb)
+	}
+	if !strings.Contains(flowKey(b, ports), "tun_id=0x5") {
+		t.Errorf("flowKey = %q, want the dump-flows field name", flowKey(b, ports))
+	}
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// groupProtocol 组表需要 OpenFlow 1.1 及以上，与计量器一样统一使用 OpenFlow13
+const groupProtocol = meterProtocol
+
+// maxGroupID OFPG_MAX，更大的编号为保留值
+const maxGroupID = 0xffffff00
+
+// 组类型，fast_failover 在 ovs-ofctl 中写作 ff
+var groupTypes = map[string]string{
+	"all":           "all",
+	"select":        "select",
+	"indirect":      "indirect",
+	"fast_failover": "ff",
+	"ff":            "ff",
+}
+
+// GroupBucket 组表的一个桶：weight 仅用于 select，watchPort/watchGroup 仅用于 fast_failover；actions 为空表示丢弃
+type GroupBucket struct {
+	Weight     int      `json:"weight,omitempty"`
+	WatchPort  string   `json:"watchPort,omitempty"`
+	WatchGroup *int     `json:"watchGroup,omitempty"`
+	Actions    []string `json:"actions"`
+}
+
+// Group OpenFlow 组表项，type 为 all、select、indirect 或 fast_failover
+type Group struct {
+	ID      int           `json:"id"`
+	Type    string        `json:"type"`
+	Buckets []GroupBucket `json:"buckets"`
+}
+
+// GroupBucketStats 桶统计
+type GroupBucketStats struct {
+	PacketCount uint64 `json:"packetCount"`
+	ByteCount   uint64 `json:"byteCount"`
+}
+
+// GroupStats ovs-ofctl dump-group-stats 的一条记录
+type GroupStats struct {
+	ID          int                `json:"id"`
+	Duration    float64            `json:"duration"`
+	RefCount    uint64             `json:"refCount"`
+	PacketCount uint64             `json:"packetCount"`
+	ByteCount   uint64             `json:"byteCount"`
+	Buckets     []GroupBucketStats `json:"buckets"`
+}
+
+// String 按 ovs-ofctl add-group 语法输出
+func (g Group) String() string {
+	parts := []string{fmt.Sprintf("group_id=%d", g.ID), "type=" + groupTypes[g.Type]}
+	for _, b := range g.Buckets {
+		var params []string
+		if b.Weight > 0 {
+			params = append(params, fmt.Sprintf("weight:%d", b.Weight))
+		}
+		if b.WatchPort != "" {
+			params = append(params, "watch_port:"+b.WatchPort)
+		}
+		if b.WatchGroup != nil {
+			params = append(params, fmt.Sprintf("watch_group:%d", *b.WatchGroup))
+		}
+		actions := "drop"
+		if len(b.Actions) > 0 {
+			actions = strings.Join(b.Actions, ",")
+		}
+		params = append(params, "actions="+actions)
+		parts = append(parts, "bucket="+strings.Join(params, ","))
+	}
+	return strings.Join(parts, ",")
+}
+
+// validate 检查组类型与桶参数是否匹配，watch_port 和 output 引用的端口必须存在于网桥
+func (g Group) validate(ports map[string]int) error {
+	if g.ID < 0 || g.ID > maxGroupID {
+		return errorf(CauseInvalidArgument, "group id must be between 0 and %d", maxGroupID)
+	}
+	typ, ok := groupTypes[g.Type]
+	if !ok {
+		return errorf(CauseInvalidArgument, "unsupported group type %q (all, select, indirect, fast_failover)", g.Type)
+	}
+	if typ == "indirect" && len(g.Buckets) != 1 {
+		return errorf(CauseInvalidArgument, "indirect group %d needs exactly one bucket", g.ID)
+	}
+	for i, b := range g.Buckets {
+		if b.Weight < 0 || b.Weight > 0 && typ != "select" {
+			return errorf(CauseInvalidArgument, "bucket %d: weight is only valid for select groups", i)
+		}
+		if typ == "ff" {
+			if b.WatchPort == "" && b.WatchGroup == nil {
+				return errorf(CauseInvalidArgument, "bucket %d of fast_failover group %d needs watchPort or watchGroup", i, g.ID)
+			}
+		} else if b.WatchPort != "" || b.WatchGroup != nil {
+			return errorf(CauseInvalidArgument, "bucket %d: watchPort/watchGroup are only valid for fast_failover groups", i)
+		}
+		if b.WatchPort != "" {
+			if msg := checkPortRef(b.WatchPort, ports); msg != "" {
+				return errorf(CauseInvalidArgument, "bucket %d watchPort: %s", i, msg)
+			}
+		}
+		for _, action := range b.Actions {
+			name, arg, _ := strings.Cut(action, ":")
+			if strings.EqualFold(name, "output") && !strings.Contains(arg, "[") {
+				if msg := checkPortRef(arg, ports); msg != "" {
+					return errorf(CauseInvalidArgument, "bucket %d action %s: %s", i, action, msg)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeGroup 校验后执行 add-group/mod-group
+func writeGroup(ctx context.Context, bridge, command string, g Group) error {
+	ports, err := bridgeOFPorts(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	if err := g.validate(ports); err != nil {
+		return err
+	}
+	if err := requireOpenFlow13(ctx, bridge); err != nil {
+		return err
+	}
+	return run(ctx, "ovs-ofctl", "-O", groupProtocol, command, bridge, g.String())
+}
+
+// AddGroup 新增组表项，网桥需启用 OpenFlow13
+func AddGroup(ctx context.Context, bridge string, g Group) error {
+	return writeGroup(ctx, bridge, "add-group", g)
+}
+
+// ModGroup 整体替换已有组表项的类型和桶，引用它的流表不受影响
+func ModGroup(ctx context.Context, bridge string, g Group) error {
+	return writeGroup(ctx, bridge, "mod-group", g)
+}
+
+// DeleteGroup 删除组表项。交换机会连带删除引用该组的流表，因此仍被流表或其它组引用时拒绝删除
+func DeleteGroup(ctx context.Context, bridge string, id int) error {
+	groups, err := ListGroups(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	found := false
+	var users []string
+	for _, g := range groups {
+		if g.ID == id {
+			found = true
+			continue
+		}
+		for _, b := range g.Buckets {
+			if b.WatchGroup != nil && *b.WatchGroup == id || actionsUseGroup(b.Actions, id) {
+				users = append(users, fmt.Sprintf("group %d", g.ID))
+				break
+			}
+		}
+	}
+	if !found {
+		return errorf(CauseNotFound, "no group %d on bridge %s", id, bridge)
+	}
+	flows, err := groupFlows(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	for _, f := range flows {
+		if actionsUseGroup(f.Actions, id) {
+			users = append(users, "flow "+f.MatchString())
+		}
+	}
+	if len(users) > 0 {
+		return errorf(CauseConflict, "group %d is still used by %s", id, strings.Join(users, "; "))
+	}
+	return run(ctx, "ovs-ofctl", "-O", groupProtocol, "del-groups", bridge, fmt.Sprintf("group_id=%d", id))
+}
+
+// actionsUseGroup 动作列表是否包含 group:id，包括 write_actions、clone 等嵌套动作中的引用
+func actionsUseGroup(actions []string, id int) bool {
+	return containsString(actionArgs(actions, "group"), strconv.Itoa(id))
+}
+
+// actionArgs 返回动作列表中所有 name:arg 动作的参数，递归进入 write_actions(...)、clone(...) 等嵌套动作列表
+func actionArgs(actions []string, name string) []string {
+	var res []string
+	for _, action := range actions {
+		i := strings.IndexAny(action, ":(")
+		if i < 0 {
+			continue
+		}
+		if action[i] == '(' {
+			res = append(res, actionArgs(splitTopLevel(strings.TrimSuffix(action[i+1:], ")")), name)...)
+		} else if strings.EqualFold(action[:i], name) {
+			res = append(res, action[i+1:])
+		}
+	}
+	return res
+}
+
+// groupFlows 以 OpenFlow13 导出流表，OpenFlow10 无法表示 group 动作
+func groupFlows(ctx context.Context, bridge string) ([]Flow, error) {
+	output, err := runOutput(ctx, "ovs-ofctl", "-O", groupProtocol, "dump-flows", bridge)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFlows(string(output))
+}
+
+// ListGroups 查询网桥上的组表项，按 ID 排序
+func ListGroups(ctx context.Context, bridge string) ([]Group, error) {
+	if err := requireOpenFlow13(ctx, bridge); err != nil {
+		return nil, err
+	}
+	output, err := runOutput(ctx, "ovs-ofctl", "-O", groupProtocol, "dump-groups", bridge)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGroups(string(output))
+}
+
+// GetGroupStats 查询网桥上组表项及各桶的统计
+func GetGroupStats(ctx context.Context, bridge string) ([]GroupStats, error) {
+	if err := requireOpenFlow13(ctx, bridge); err != nil {
+		return nil, err
+	}
+	output, err := runOutput(ctx, "ovs-ofctl", "-O", groupProtocol, "dump-group-stats", bridge)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGroupStats(string(output))
+}
+
+// groupLines 去掉回复头后的非空行
+func groupLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, " reply (") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ParseGroups 解析 ovs-ofctl dump-groups 输出，如
+// "group_id=1,type=select,bucket=weight:100,actions=output:1,bucket=weight:100,actions=output:2"；
+// 桶内 actions= 之后直到下一个 bucket= 的内容均为动作
+func ParseGroups(output string) ([]Group, error) {
+	groups := []Group{}
+	for _, line := range groupLines(output) {
+		var g Group
+		var bucket *GroupBucket
+		inActions := false
+		hasID := false
+		for _, tok := range splitTopLevel(line) {
+			if rest, ok := strings.CutPrefix(tok, "bucket="); ok {
+				g.Buckets = append(g.Buckets, GroupBucket{Actions: []string{}})
+				bucket, inActions = &g.Buckets[len(g.Buckets)-1], false
+				tok = rest
+			}
+			if bucket == nil {
+				key, value, _ := strings.Cut(tok, "=")
+				switch key {
+				case "group_id":
+					id, err := strconv.Atoi(value)
+					if err != nil {
+						return nil, errorf(CauseUnknown, "invalid group id %q in dump-groups output", value)
+					}
+					g.ID, hasID = id, true
+				case "type":
+					g.Type = value
+					if value == "ff" {
+						g.Type = "fast_failover"
+					}
+				}
+				continue
+			}
+			if inActions {
+				bucket.Actions = append(bucket.Actions, tok)
+				continue
+			}
+			if rest, ok := strings.CutPrefix(tok, "actions="); ok {
+				inActions = true
+				if rest != "" && rest != "drop" {
+					bucket.Actions = append(bucket.Actions, rest)
+				}
+				continue
+			}
+			key, value, _ := strings.Cut(strings.Replace(tok, ":", "=", 1), "=")
+			switch key {
+			case "weight":
+				bucket.Weight, _ = strconv.Atoi(value)
+			case "watch_port":
+				bucket.WatchPort = value
+			case "watch_group":
+				if n, err := strconv.Atoi(value); err == nil {
+					bucket.WatchGroup = &n
+				}
+			}
+		}
+		if !hasID {
+			continue
+		}
+		if g.Buckets == nil {
+			g.Buckets = []GroupBucket{}
+		}
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups, nil
+}
+
+// ParseGroupStats 解析 ovs-ofctl dump-group-stats 输出，如
+// "group_id=1,duration=5.1s,ref_count=1,packet_count=10,byte_count=980,bucket0:packet_count=4,byte_count=392,bucket1:..."
+func ParseGroupStats(output string) ([]GroupStats, error) {
+	stats := []GroupStats{}
+	for _, line := range groupLines(output) {
+		var s GroupStats
+		var bucket *GroupBucketStats
+		hasID := false
+		for _, tok := range strings.Split(line, ",") {
+			if strings.HasPrefix(tok, "bucket") {
+				if _, rest, ok := strings.Cut(tok, ":"); ok {
+					s.Buckets = append(s.Buckets, GroupBucketStats{})
+					bucket, tok = &s.Buckets[len(s.Buckets)-1], rest
+				}
+			}
+			key, value, _ := strings.Cut(tok, "=")
+			switch key {
+			case "group_id":
+				id, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, errorf(CauseUnknown, "invalid group id %q in dump-group-stats output", value)
+				}
+				s.ID, hasID = id, true
+				continue
+			case "duration":
+				s.Duration, _ = strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+				continue
+			}
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case key == "ref_count":
+				s.RefCount = n
+			case key == "packet_count" && bucket != nil:
+				bucket.PacketCount = n
+			case key == "byte_count" && bucket != nil:
+				bucket.ByteCount = n
+			case key == "packet_count":
+				s.PacketCount = n
+			case key == "byte_count":
+				s.ByteCount = n
+			}
+		}
+		if !hasID {
+			continue
+		}
+		if s.Buckets == nil {
+			s.Buckets = []GroupBucketStats{}
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats, nil
+}
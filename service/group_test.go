@@ -0,0 +1,98 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGroups(t *testing.T) {
+	two := 2
+	tests := []struct {
+		name   string
+		output string
+		want   []Group
+	}{
+		{
+			name: "select",
+			output: "OFPST_GROUP_DESC reply (OF1.3) (xid=0x2):\n" +
+				" group_id=1,type=select,bucket=weight:100,actions=output:1,bucket=weight:50,actions=output:2\n",
+			want: []Group{{ID: 1, Type: "select", Buckets: []GroupBucket{
+				{Weight: 100, Actions: []string{"output:1"}},
+				{Weight: 50, Actions: []string{"output:2"}},
+			}}},
+		},
+		{
+			name: "nested actions",
+			output: "OFPST_GROUP_DESC reply (OF1.3) (xid=0x2):\n" +
+				" group_id=2,type=all,bucket=actions=learn(table=10,hard_timeout=60,NXM_OF_VLAN_TCI[0..11],NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[]),output:3," +
+				"bucket=actions=clone(set_field:10.0.0.9->ip_dst,output:4),mod_vlan_vid:10,output:5\n",
+			want: []Group{{ID: 2, Type: "all", Buckets: []GroupBucket{
+				{Actions: []string{"learn(table=10,hard_timeout=60,NXM_OF_VLAN_TCI[0..11],NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[])", "output:3"}},
+				{Actions: []string{"clone(set_field:10.0.0.9->ip_dst,output:4)", "mod_vlan_vid:10", "output:5"}},
+			}}},
+		},
+		{
+			name: "fast failover and drop bucket, sorted by id",
+			output: "OFPST_GROUP_DESC reply (OF1.3) (xid=0x2):\n" +
+				" group_id=5,type=ff,bucket=watch_port:1,actions=output:1,bucket=watch_port:eth2,watch_group:2,actions=output:eth2\n" +
+				" group_id=3,type=indirect,bucket=actions=drop\n" +
+				" group_id=4,type=all\n",
+			want: []Group{
+				{ID: 3, Type: "indirect", Buckets: []GroupBucket{{Actions: []string{}}}},
+				{ID: 4, Type: "all", Buckets: []GroupBucket{}},
+				{ID: 5, Type: "fast_failover", Buckets: []GroupBucket{
+					{WatchPort: "1", Actions: []string{"output:1"}},
+					{WatchPort: "eth2", WatchGroup: &two, Actions: []string{"output:eth2"}},
+				}},
+			},
+		},
+		{
+			name:   "empty",
+			output: "OFPST_GROUP_DESC reply (OF1.3) (xid=0x2):\n",
+			want:   []Group{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGroups(tt.output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGroups =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			// String 的结果应能再次解析为相同的组表项
+			for _, g := range got {
+				again, err := ParseGroups(g.String())
+				if err != nil || len(again) != 1 || !reflect.DeepEqual(again[0], g) {
+					t.Errorf("round trip of %q = %+v, %v", g.String(), again, err)
+				}
+			}
+		})
+	}
+	if _, err := ParseGroups(" group_id=x,type=all\n"); CauseOf(err) != CauseUnknown {
+		t.Errorf("invalid group id error = %v", err)
+	}
+}
+
+func TestParseGroupStats(t *testing.T) {
+	output := "OFPST_GROUP reply (OF1.3) (xid=0x2):\n" +
+		" group_id=2,duration=3.002s,ref_count=0,packet_count=0,byte_count=0,bucket0:packet_count=0,byte_count=0\n" +
+		" group_id=1,duration=12.345s,ref_count=1,packet_count=10,byte_count=980,bucket0:packet_count=4,byte_count=392,bucket1:packet_count=6,byte_count=588\n"
+	got, err := ParseGroupStats(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GroupStats{
+		{ID: 1, Duration: 12.345, RefCount: 1, PacketCount: 10, ByteCount: 980, Buckets: []GroupBucketStats{
+			{PacketCount: 4, ByteCount: 392}, {PacketCount: 6, ByteCount: 588},
+		}},
+		{ID: 2, Duration: 3.002, Buckets: []GroupBucketStats{{}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseGroupStats =\n%+v\nwant\n%+v", got, want)
+	}
+	if _, err := ParseGroupStats(" group_id=,duration=1s\n"); CauseOf(err) != CauseUnknown {
+		t.Errorf("invalid group id error = %v", err)
+	}
+}
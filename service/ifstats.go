@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"ovs-manager/ovsdb"
+)
+
+// InterfaceStats 接口状态及 statistics 计数（rx/tx packets、bytes、dropped、errors 等）
+type InterfaceStats struct {
+	Name       string           `json:"name"`
+	Port       string           `json:"port,omitempty"`
+	Type       string           `json:"type"`
+	AdminState string           `json:"adminState"`
+	LinkState  string           `json:"linkState"`
+	LinkSpeed  *int             `json:"linkSpeed,omitempty"`
+	LinkResets *int             `json:"linkResets,omitempty"`
+	Duplex     string           `json:"duplex,omitempty"`
+	MTU        *int             `json:"mtu,omitempty"`
+	MacInUse   string           `json:"macInUse"`
+	OFPort     *int             `json:"ofport,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Statistics map[string]int64 `json:"statistics"`
+}
+
+// interfaceStatsColumns 读取接口状态所需的 Interface 列
+var interfaceStatsColumns = []string{
+	"type", "admin_state", "link_state", "link_speed", "link_resets", "duplex",
+	"mtu", "mac_in_use", "ofport", "error", "statistics",
+}
+
+func interfaceStats(i ovsdb.Row) InterfaceStats {
+	return InterfaceStats{
+		Name:       i.String("name"),
+		Type:       i.String("type"),
+		AdminState: i.OptionalString("admin_state"),
+		LinkState:  i.OptionalString("link_state"),
+		LinkSpeed:  i.OptionalInt("link_speed"),
+		LinkResets: i.OptionalInt("link_resets"),
+		Duplex:     i.OptionalString("duplex"),
+		MTU:        i.OptionalInt("mtu"),
+		MacInUse:   i.OptionalString("mac_in_use"),
+		OFPort:     i.OptionalInt("ofport"),
+		Error:      i.OptionalString("error"),
+		Statistics: i.IntMap("statistics"),
+	}
+}
+
+// GetInterfaceStats 查询单个接口的状态和统计计数，状态取自 OVSDB 而不是 ip link 输出
+func GetInterfaceStats(ctx context.Context, name string) (*InterfaceStats, error) {
+	tables, err := queryTables(ctx, tableQuery{Table: "Interface", Columns: append([]string{"name"}, interfaceStatsColumns...)})
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range tables["Interface"] {
+		if iface.String("name") == name {
+			stats := interfaceStats(iface)
+			return &stats, nil
+		}
+	}
+	return nil, errorf(CauseNotFound, "no interface named %s", name)
+}
+
+// ListBridgeInterfaceStats 一次批量查询返回 bridge 上所有接口（含 bond 成员）的状态和统计计数，按端口、接口名排序
+func ListBridgeInterfaceStats(ctx context.Context, bridge string) ([]InterfaceStats, error) {
+	tables, err := portInventory(ctx, interfaceStatsColumns...)
+	if err != nil {
+		return nil, err
+	}
+	ports, err := bridgePorts(tables, bridge)
+	if err != nil {
+		return nil, err
+	}
+	ifaces := indexByUUID(tables["Interface"])
+	res := []InterfaceStats{}
+	for _, p := range ports {
+		var members []InterfaceStats
+		for _, id := range p.UUIDs("interfaces") {
+			if iface, ok := ifaces[id]; ok {
+				stats := interfaceStats(iface)
+				stats.Port = p.String("name")
+				members = append(members, stats)
+			}
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+		res = append(res, members...)
+	}
+	return res, nil
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// netemQosType OVS 的 netem QoS 类型
+const netemQosType = "linux-netem"
+
+// netemExternalPrefix OVS other_config 不支持的 netem 参数保存在 QoS external_ids 中的键前缀
+const netemExternalPrefix = "ovs-manager-netem-"
+
+// netemDefaultLimit netem 队列长度默认值（报文数），与 OVS/tc 一致
+const netemDefaultLimit = 1000
+
+// Impairment 端口网络损伤参数，百分比取值 0-100
+type Impairment struct {
+	LatencyMs float64 `json:"latencyMs"`
+	JitterMs  float64 `json:"jitterMs"`
+	Loss      float64 `json:"loss"`
+	Duplicate float64 `json:"duplicate"`
+	Reorder   float64 `json:"reorder"` // 需要 latencyMs > 0
+	Limit     int     `json:"limit"`   // 队列长度（报文数），0 为默认 1000
+}
+
+// PortImpairment 端口当前的损伤配置，端口 QoS 不是 linux-netem 时 Configured 为 false
+type PortImpairment struct {
+	Port       string `json:"port"`
+	Configured bool   `json:"configured"`
+	QoS        string `json:"qos,omitempty"`
+	Impairment
+	// Drifted tc 补充的参数已丢失（接口重建或 ovs-vswitchd 重新下发 qdisc）的接口，需调用 ReapplyPortImpairment 恢复
+	Drifted []string `json:"drifted,omitempty"`
+}
+
+// validate 检查参数范围
+func (im Impairment) validate() error {
+	for name, v := range map[string]float64{"loss": im.Loss, "duplicate": im.Duplicate, "reorder": im.Reorder} {
+		if v < 0 || v > 100 {
+			return errorf(CauseInvalidArgument, "%s must be a percentage between 0 and 100", name)
+		}
+	}
+	if im.LatencyMs < 0 || im.JitterMs < 0 || im.Limit < 0 {
+		return errorf(CauseInvalidArgument, "latencyMs, jitterMs and limit must not be negative")
+	}
+	if im.LatencyMs == 0 && (im.JitterMs > 0 || im.Reorder > 0) {
+		return errorf(CauseInvalidArgument, "jitterMs and reorder require latencyMs > 0")
+	}
+	return nil
+}
+
+// needsTC OVS 的 linux-netem 只支持 latency/jitter/limit 和整数 loss，其余参数需要再用 tc 补充
+func (im Impairment) needsTC() bool {
+	return im.Duplicate > 0 || im.Reorder > 0 || im.Loss != math.Trunc(im.Loss)
+}
+
+// qosSpec 转换为 linux-netem QoS：OVS 支持的参数写入 other_config（latency/jitter 单位为微秒），
+// 其余参数写入 external_ids 以便读取，并在 tc 配置丢失后由 ReapplyPortImpairment 重新下发
+func (im Impairment) qosSpec() QosSpec {
+	spec := QosSpec{Type: netemQosType, OtherConfig: map[string]string{}, ExternalIDs: map[string]string{}}
+	if im.LatencyMs > 0 {
+		spec.OtherConfig["latency"] = strconv.FormatInt(int64(math.Round(im.LatencyMs*1000)), 10)
+	}
+	if im.JitterMs > 0 {
+		spec.OtherConfig["jitter"] = strconv.FormatInt(int64(math.Round(im.JitterMs*1000)), 10)
+	}
+	if im.Limit > 0 {
+		spec.OtherConfig["limit"] = strconv.Itoa(im.Limit)
+	}
+	if im.Loss >= 1 {
+		spec.OtherConfig["loss"] = strconv.Itoa(int(im.Loss))
+	}
+	if im.Loss != math.Trunc(im.Loss) {
+		spec.ExternalIDs[netemExternalPrefix+"loss"] = formatPercent(im.Loss)
+	}
+	if im.Duplicate > 0 {
+		spec.ExternalIDs[netemExternalPrefix+"duplicate"] = formatPercent(im.Duplicate)
+	}
+	if im.Reorder > 0 {
+		spec.ExternalIDs[netemExternalPrefix+"reorder"] = formatPercent(im.Reorder)
+	}
+	return spec
+}
+
+// tcArgs 生成完整的 tc qdisc change 参数，覆盖 OVS 下发的 netem qdisc
+func (im Impairment) tcArgs(iface string) []string {
+	limit := im.Limit
+	if limit == 0 {
+		limit = netemDefaultLimit
+	}
+	args := []string{"qdisc", "change", "dev", iface, "root", "netem", "limit", strconv.Itoa(limit)}
+	if im.LatencyMs > 0 {
+		args = append(args, "delay", formatMs(im.LatencyMs))
+		if im.JitterMs > 0 {
+			args = append(args, formatMs(im.JitterMs))
+		}
+	}
+	if im.Loss > 0 {
+		args = append(args, "loss", formatPercent(im.Loss)+"%")
+	}
+	if im.Duplicate > 0 {
+		args = append(args, "duplicate", formatPercent(im.Duplicate)+"%")
+	}
+	if im.Reorder > 0 {
+		args = append(args, "reorder", formatPercent(im.Reorder)+"%")
+	}
+	return args
+}
+
+// tcApplied 判断 tc qdisc show 输出的 netem 参数是否已包含 tc 补充的 loss/duplicate/reorder
+func (im Impairment) tcApplied(output string) bool {
+	fields := strings.Fields(output)
+	values := map[string]float64{}
+	for i := 0; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "loss", "duplicate", "reorder":
+			value := fields[i+1]
+			if value == "random" && i+2 < len(fields) {
+				value = fields[i+2]
+			}
+			values[fields[i]] = parseFloatOr(strings.TrimSuffix(value, "%"), 0)
+		}
+	}
+	same := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	return same(values["loss"], im.Loss) && same(values["duplicate"], im.Duplicate) && same(values["reorder"], im.Reorder)
+}
+
+// driftedTC 只读检查端口各接口上的 netem qdisc，返回 tc 补充的参数已丢失的接口；
+// 接口上还没有 netem qdisc 时等待 ovs-vswitchd 按 QoS 重建，不计入
+func (im Impairment) driftedTC(ctx context.Context, portName string) ([]string, error) {
+	ifaces, err := portInterfaceNames(ctx, portName)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, iface := range ifaces {
+		output, err := runOutput(ctx, "tc", "qdisc", "show", "dev", iface, "root")
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(string(output), "netem") || im.tcApplied(string(output)) {
+			continue
+		}
+		res = append(res, iface)
+	}
+	return res, nil
+}
+
+func formatPercent(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatMs(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64) + "ms"
+}
+
+// SetPortImpairment 在端口上设置 netem 损伤（时延、抖动、丢包、重复、乱序），替换端口原有的 QoS；
+// OVS 不支持的参数在 QoS 生效后通过 tc 补充到端口接口的 netem qdisc 上
+func SetPortImpairment(ctx context.Context, portName string, im Impairment) error {
+	if err := im.validate(); err != nil {
+		return err
+	}
+	if err := replacePortQos(ctx, portName, im.qosSpec(), nil); err != nil {
+		return err
+	}
+	if !im.needsTC() {
+		return nil
+	}
+	ifaces, err := portInterfaceNames(ctx, portName)
+	if err != nil {
+		return err
+	}
+	for _, iface := range ifaces {
+		if err := run(ctx, "tc", im.tcArgs(iface)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPortImpairment 读取端口当前的 netem 损伤配置，并报告 tc 补充的参数已丢失的接口，不修改任何配置
+func GetPortImpairment(ctx context.Context, portName string) (*PortImpairment, error) {
+	tables, err := qosTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	port, err := findQosPort(tables, portName)
+	if err != nil {
+		return nil, err
+	}
+	res := &PortImpairment{Port: portName}
+	q, ok := indexByUUID(tables["QoS"])[port.UUID("qos")]
+	if !ok || q.String("type") != netemQosType {
+		return res, nil
+	}
+	res.Configured = true
+	res.QoS = q.UUID("_uuid")
+	config := q.StringMap("other_config")
+	extra := q.StringMap("external_ids")
+	res.LatencyMs = parseFloatOr(config["latency"], 0) / 1000
+	res.JitterMs = parseFloatOr(config["jitter"], 0) / 1000
+	res.Limit = int(parseFloatOr(config["limit"], 0))
+	res.Loss = parseFloatOr(extra[netemExternalPrefix+"loss"], parseFloatOr(config["loss"], 0))
+	res.Duplicate = parseFloatOr(extra[netemExternalPrefix+"duplicate"], 0)
+	res.Reorder = parseFloatOr(extra[netemExternalPrefix+"reorder"], 0)
+	if res.needsTC() {
+		if res.Drifted, err = res.driftedTC(ctx, portName); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ReapplyPortImpairment 将端口 QoS 中记录的 netem 参数重新通过 tc 下发到参数已丢失的接口，返回重新下发的接口；
+// 端口未配置 netem 时返回 CauseNotFound
+func ReapplyPortImpairment(ctx context.Context, portName string) ([]string, error) {
+	current, err := GetPortImpairment(ctx, portName)
+	if err != nil {
+		return nil, err
+	}
+	if !current.Configured {
+		return nil, errorf(CauseNotFound, "port %s has no netem impairment", portName)
+	}
+	for _, iface := range current.Drifted {
+		if err := run(ctx, "tc", current.tcArgs(iface)...); err != nil {
+			return nil, err
+		}
+	}
+	return current.Drifted, nil
+}
+
+// ClearPortImpairment 移除端口上的 netem 损伤并回收不再被引用的 QoS；端口未配置 netem 时不做任何修改
+func ClearPortImpairment(ctx context.Context, portName string) error {
+	tables, err := qosTables(ctx)
+	if err != nil {
+		return err
+	}
+	port, err := findQosPort(tables, portName)
+	if err != nil {
+		return err
+	}
+	q, ok := indexByUUID(tables["QoS"])[port.UUID("qos")]
+	if !ok || q.String("type") != netemQosType {
+		return nil
+	}
+	args := append([]string{"--", "clear", "Port", portName, "qos"}, releasePortQos(tables, port)...)
+	return run(ctx, "ovs-vsctl", args...)
+}
+
+// portInterfaceNames 返回端口下所有接口名
+func portInterfaceNames(ctx context.Context, portName string) ([]string, error) {
+	tables, err := queryTables(ctx,
+		tableQuery{Table: "Port", Columns: []string{"name", "interfaces"}},
+		tableQuery{Table: "Interface", Columns: []string{"_uuid", "name"}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	ifaces := indexByUUID(tables["Interface"])
+	for _, p := range tables["Port"] {
+		if p.String("name") != portName {
+			continue
+		}
+		var names []string
+		for _, id := range p.UUIDs("interfaces") {
+			if iface, ok := ifaces[id]; ok {
+				names = append(names, iface.String("name"))
+			}
+		}
+		return names, nil
+	}
+	return nil, errorf(CauseNotFound, "no port named %s", portName)
+}
+
+func parseFloatOr(s string, def float64) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// impairmentFromParams 由场景参数构造损伤配置
+func impairmentFromParams(params map[string]interface{}) (Impairment, error) {
+	var im Impairment
+	for key, dst := range map[string]*float64{"latencyMs": &im.LatencyMs, "jitterMs": &im.JitterMs, "loss": &im.Loss, "duplicate": &im.Duplicate, "reorder": &im.Reorder} {
+		v, ok := params[key]
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+		if err != nil {
+			return im, errorf(CauseInvalidArgument, "invalid %s: %v", key, v)
+		}
+		*dst = f
+	}
+	if v, ok := params["limit"]; ok {
+		im.Limit, _ = toInt(v)
+	}
+	return im, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// expectNetemPort 预设端口 p1（接口 p1）挂着 0.5% 丢包、1% 重复的 linux-netem QoS，tc 上的补充参数已丢失
+func expectNetemPort(fake *FakeRunner) {
+	portCols := []string{"_uuid", "name", "qos"}
+	qosCols := []string{"_uuid", "type", "other_config", "external_ids"}
+	fake.Expect("ovs-vsctl --format=json --data=json -- --columns=_uuid,name,qos list Port -- list QoS -- list Queue",
+		vsctlList([]map[string]interface{}{{"_uuid": fixtureUUID(1), "name": "p1", "qos": fixtureUUID(3)}}, portCols)+
+			vsctlList([]map[string]interface{}{{"_uuid": fixtureUUID(3), "type": netemQosType,
+				"other_config": fixtureMap("latency", "10000"),
+				"external_ids": fixtureMap(netemExternalPrefix+"loss", "0.5", netemExternalPrefix+"duplicate", "1")}}, qosCols)+
+			vsctlList(nil, []string{"_uuid"}))
+	fake.Expect("ovs-vsctl --format=json --data=json -- --columns=name,interfaces list Port -- --columns=_uuid,name list Interface",
+		vsctlList([]map[string]interface{}{{"name": "p1", "interfaces": fixtureSet(fixtureUUID(2))}}, []string{"name", "interfaces"})+
+			vsctlList([]map[string]interface{}{{"_uuid": fixtureUUID(2), "name": "p1"}}, []string{"_uuid", "name"}))
+	fake.Expect("tc qdisc show dev p1 root", "qdisc netem 8001: root refcnt 2 limit 1000 delay 10ms\n")
+}
+
+func TestGetPortImpairmentReportsDrift(t *testing.T) {
+	fake := NewFakeRunner()
+	expectNetemPort(fake)
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+
+	res, err := GetPortImpairment(context.Background(), "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Configured || res.Loss != 0.5 || res.Duplicate != 1 || !reflect.DeepEqual(res.Drifted, []string{"p1"}) {
+		t.Errorf("GetPortImpairment = %+v", res)
+	}
+	// 查询只读取 tc 状态，不下发任何修改
+	for _, call := range fake.Calls() {
+		if strings.HasPrefix(call, "tc qdisc change") {
+			t.Errorf("GET ran %q", call)
+		}
+	}
+}
+
+func TestReapplyPortImpairment(t *testing.T) {
+	fake := NewFakeRunner()
+	expectNetemPort(fake)
+	fake.Expect("tc qdisc change dev p1 root netem limit 1000 delay 10ms loss 0.5% duplicate 1%", "")
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+
+	reapplied, err := ReapplyPortImpairment(context.Background(), "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reapplied, []string{"p1"}) {
+		t.Errorf("reapplied = %v", reapplied)
+	}
+	calls := fake.Calls()
+	if last := calls[len(calls)-1]; last != "tc qdisc change dev p1 root netem limit 1000 delay 10ms loss 0.5% duplicate 1%" {
+		t.Errorf("last call = %q", last)
+	}
+}
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ovs-manager/metrics"
+	"ovs-manager/ovsdb"
+)
+
+// DefaultMetricsInterval 两次采集 OVS 指标的最小间隔，间隔内的抓取直接返回缓存
+const DefaultMetricsInterval = 15 * time.Second
+
+var (
+	commandsTotal      = metrics.NewCounterVec("ovs_manager_commands_total", "外部命令执行次数", "command")
+	commandErrorsTotal = metrics.NewCounterVec("ovs_manager_command_errors_total", "外部命令执行失败次数（按错误原因）", "command", "cause")
+)
+
+// observeCommand 记录外部命令执行结果
+func observeCommand(name string, err error) {
+	commandsTotal.Inc(name)
+	if err != nil {
+		commandErrorsTotal.Inc(name, string(CauseOf(err)))
+	}
+}
+
+var metricsCache struct {
+	mu       sync.Mutex
+	interval time.Duration
+	at       time.Time
+	families []*metrics.Family
+}
+
+func init() {
+	metricsCache.interval = DefaultMetricsInterval
+}
+
+// SetMetricsInterval 设置 OVS 指标的缓存时长，0 表示每次抓取都重新采集
+func SetMetricsInterval(d time.Duration) {
+	metricsCache.mu.Lock()
+	defer metricsCache.mu.Unlock()
+	metricsCache.interval = d
+	metricsCache.at = time.Time{}
+}
+
+// Metrics 返回 OVS 指标和 ovs-manager 自身的命令计数。OVS 指标在缓存时长内只采集一次，
+// 并发抓取排队等待同一次采集结果，避免每次抓取都产生一批 ovs-vsctl/ovs-ofctl/ovs-appctl 进程
+func Metrics(ctx context.Context) []*metrics.Family {
+	metricsCache.mu.Lock()
+	if metricsCache.families == nil || time.Since(metricsCache.at) >= metricsCache.interval {
+		// 采集结果会被后续抓取复用，不随本次请求取消
+		metricsCache.families = collectOVSMetrics(context.WithoutCancel(ctx))
+		metricsCache.at = time.Now()
+	}
+	families := append([]*metrics.Family(nil), metricsCache.families...)
+	metricsCache.mu.Unlock()
+	return append(families, commandsTotal.Collect(), commandErrorsTotal.Collect())
+}
+
+// metricsCollector 一类 OVS 指标的采集函数
+type metricsCollector struct {
+	name    string
+	collect func(ctx context.Context, tables map[string][]ovsdb.Row, set *familySet) error
+}
+
+var metricsCollectors = []metricsCollector{
+	{"interface", collectInterfaceMetrics},
+	{"mirror", collectMirrorMetrics},
+	{"bond", collectBondMetrics},
+	{"flow_table", collectFlowTableMetrics},
+	{"datapath", collectDatapathMetrics},
+}
+
+// collectOVSMetrics 一次批量查询 OVSDB 后依次运行各采集函数；单个采集失败只影响
+// ovs_manager_collector_up 和对应指标，不影响整个抓取
+func collectOVSMetrics(ctx context.Context) []*metrics.Family {
+	set := newFamilySet()
+	up := set.family("ovs_manager_collector_up", metrics.TypeGauge, "最近一次采集是否成功（1 成功，0 失败）")
+	duration := set.family("ovs_manager_collector_duration_seconds", metrics.TypeGauge, "最近一次采集耗时")
+	tables, err := queryTables(ctx,
+		tableQuery{Table: "Bridge", Columns: []string{"name", "ports", "mirrors"}},
+		tableQuery{Table: "Port", Columns: []string{"_uuid", "name", "interfaces"}},
+		tableQuery{Table: "Interface", Columns: []string{"_uuid", "name", "admin_state", "link_state", "link_speed", "link_resets", "mtu", "statistics"}},
+		tableQuery{Table: "Mirror", Columns: []string{"_uuid", "name", "statistics"}},
+	)
+	up.Add(boolValue(err == nil), "collector", "ovsdb")
+	for _, c := range metricsCollectors {
+		start := time.Now()
+		ok := false
+		if err == nil {
+			ok = c.collect(ctx, tables, set) == nil
+		}
+		up.Add(boolValue(ok), "collector", c.name)
+		duration.Add(time.Since(start).Seconds(), "collector", c.name)
+	}
+	set.family("ovs_manager_metrics_collected_timestamp_seconds", metrics.TypeGauge, "最近一次采集 OVS 指标的时间").
+		Add(float64(time.Now().UnixNano()) / 1e9)
+	return set.list()
+}
+
+// familySet 按名称收集指标族，输出时按名称排序
+type familySet struct {
+	families map[string]*metrics.Family
+}
+
+func newFamilySet() *familySet {
+	return &familySet{families: map[string]*metrics.Family{}}
+}
+
+func (s *familySet) family(name, typ, help string) *metrics.Family {
+	f, ok := s.families[name]
+	if !ok {
+		f = metrics.NewFamily(name, typ, help)
+		s.families[name] = f
+	}
+	return f
+}
+
+func (s *familySet) list() []*metrics.Family {
+	res := make([]*metrics.Family, 0, len(s.families))
+	for _, f := range s.families {
+		res = append(res, f)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// portOwners 返回 Port UUID -> 网桥名 和 Interface UUID -> Port 行
+func portOwners(tables map[string][]ovsdb.Row) (map[string]string, map[string]ovsdb.Row) {
+	bridgeOf := map[string]string{}
+	for _, br := range tables["Bridge"] {
+		for _, id := range br.UUIDs("ports") {
+			bridgeOf[id] = br.String("name")
+		}
+	}
+	portOf := map[string]ovsdb.Row{}
+	for _, p := range tables["Port"] {
+		for _, id := range p.UUIDs("interfaces") {
+			portOf[id] = p
+		}
+	}
+	return bridgeOf, portOf
+}
+
+// collectInterfaceMetrics 接口状态和 statistics 计数，每个 statistics 键输出为 ovs_interface_<键>_total
+func collectInterfaceMetrics(ctx context.Context, tables map[string][]ovsdb.Row, set *familySet) error {
+	bridgeOf, portOf := portOwners(tables)
+	adminUp := set.family("ovs_interface_admin_up", metrics.TypeGauge, "接口管理状态（1 up，0 down）")
+	linkUp := set.family("ovs_interface_link_up", metrics.TypeGauge, "接口链路状态（1 up，0 down）")
+	speed := set.family("ovs_interface_link_speed_bits_per_second", metrics.TypeGauge, "接口协商速率")
+	mtu := set.family("ovs_interface_mtu_bytes", metrics.TypeGauge, "接口 MTU")
+	resets := set.family("ovs_interface_link_resets_total", metrics.TypeCounter, "接口链路状态变化次数")
+	for _, iface := range tables["Interface"] {
+		p := portOf[iface.UUID("_uuid")]
+		labels := []string{"bridge", bridgeOf[p.UUID("_uuid")], "port", p.String("name"), "interface", iface.String("name")}
+		adminUp.Add(boolValue(iface.OptionalString("admin_state") == "up"), labels...)
+		linkUp.Add(boolValue(iface.OptionalString("link_state") == "up"), labels...)
+		if v := iface.OptionalInt("link_speed"); v != nil {
+			speed.Add(float64(*v), labels...)
+		}
+		if v := iface.OptionalInt("mtu"); v != nil {
+			mtu.Add(float64(*v), labels...)
+		}
+		if v := iface.OptionalInt("link_resets"); v != nil {
+			resets.Add(float64(*v), labels...)
+		}
+		stats := iface.IntMap("statistics")
+		for _, k := range sortedStatKeys(stats) {
+			set.family("ovs_interface_"+metrics.SanitizeName(k)+"_total", metrics.TypeCounter, "接口 statistics 计数 "+k).
+				Add(float64(stats[k]), labels...)
+		}
+	}
+	return nil
+}
+
+// collectMirrorMetrics 镜像 statistics 计数（tx_packets、tx_bytes）
+func collectMirrorMetrics(ctx context.Context, tables map[string][]ovsdb.Row, set *familySet) error {
+	mirrors := indexByUUID(tables["Mirror"])
+	for _, br := range tables["Bridge"] {
+		for _, id := range br.UUIDs("mirrors") {
+			m, ok := mirrors[id]
+			if !ok {
+				continue
+			}
+			stats := m.IntMap("statistics")
+			for _, k := range sortedStatKeys(stats) {
+				set.family("ovs_mirror_"+metrics.SanitizeName(k)+"_total", metrics.TypeCounter, "镜像 statistics 计数 "+k).
+					Add(float64(stats[k]), "bridge", br.String("name"), "mirror", m.String("name"))
+			}
+		}
+	}
+	return nil
+}
+
+func sortedStatKeys(stats map[string]int64) []string {
+	keys := make([]string, 0, len(stats))
+	for k := range stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// collectBondMetrics 通过一次 ovs-appctl bond/show 获取所有 bond 成员的启用状态
+func collectBondMetrics(ctx context.Context, tables map[string][]ovsdb.Row, set *familySet) error {
+	out, err := runOutput(ctx, "ovs-appctl", "bond/show")
+	if err != nil {
+		return err
+	}
+	bridgeOf := map[string]string{}
+	portBridge, _ := portOwners(tables)
+	for _, p := range tables["Port"] {
+		bridgeOf[p.String("name")] = portBridge[p.UUID("_uuid")]
+	}
+	enabled := set.family("ovs_bond_member_enabled", metrics.TypeGauge, "bond 成员是否启用（1 enabled，0 disabled）")
+	for _, m := range parseBondMembers(string(out)) {
+		enabled.Add(boolValue(m.Enabled), "bridge", bridgeOf[m.Bond], "bond", m.Bond, "member", m.Member)
+	}
+	return nil
+}
+
+// bondMemberState bond/show 输出中的成员状态
+type bondMemberState struct {
+	Bond    string
+	Member  string
+	Enabled bool
+}
+
+var (
+	bondHeaderRe = regexp.MustCompile(`^---- (\S+) ----$`)
+	bondMemberRe = regexp.MustCompile(`^(?:member|slave) (\S+): (enabled|disabled)`)
+)
+
+// parseBondMembers 解析 ovs-appctl bond/show 的输出，兼容旧版本的 slave 写法
+func parseBondMembers(out string) []bondMemberState {
+	var res []bondMemberState
+	bond := ""
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if m := bondHeaderRe.FindStringSubmatch(line); m != nil {
+			bond = m[1]
+			continue
+		}
+		if m := bondMemberRe.FindStringSubmatch(line); m != nil && bond != "" {
+			res = append(res, bondMemberState{Bond: bond, Member: m[1], Enabled: m[2] == "enabled"})
+		}
+	}
+	return res
+}
+
+// collectFlowTableMetrics 每个网桥一次 ovs-ofctl dump-tables，输出各流表的流条目数和查找/命中计数
+func collectFlowTableMetrics(ctx context.Context, tables map[string][]ovsdb.Row, set *familySet) error {
+	active := set.family("ovs_flow_table_active_flows", metrics.TypeGauge, "流表中的流条目数")
+	lookups := set.family("ovs_flow_table_lookups_total", metrics.TypeCounter, "流表查找次数")
+	matched := set.family("ovs_flow_table_matched_total", metrics.TypeCounter, "流表命中次数")
+	var firstErr error
+	for _, br := range tables["Bridge"] {
+		name := br.String("name")
+		out, err := runOutput(ctx, "ovs-ofctl", "dump-tables", name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, t := range parsetableStats(string(out)) {
+			table := strconv.Itoa(t.Table)
+			active.Add(float64(t.Active), "bridge", name, "table", table)
+			lookups.Add(float64(t.Lookup), "bridge", name, "table", table)
+			matched.Add(float64(t.Matched), "bridge", name, "table", table)
+		}
+	}
+	return firstErr
+}
+
+// tableStats dump-tables 输出中一个流表的计数
+type tableStats struct {
+	Table   int
+	Active  int64
+	Lookup  int64
+	Matched int64
+}
+
+var (
+	tableHeaderRe = regexp.MustCompile(`^(?:table\s+)?(\d+)\b[^=]*:`)
+	counterRe     = regexp.MustCompile(`\b(active|lookup|matched)=(\d+)`)
+)
+
+// parsetableStats 解析 ovs-ofctl dump-tables 的输出（"table N:" 新格式和 "N: name:" 旧格式），
+// "tables 1...253: ditto" 表示与前一个表相同的空表，不输出
+func parsetableStats(out string) []tableStats {
+	var res []tableStats
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if m := tableHeaderRe.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.Atoi(m[1])
+			res = append(res, tableStats{Table: id})
+		}
+		if len(res) == 0 {
+			continue
+		}
+		t := &res[len(res)-1]
+		for _, m := range counterRe.FindAllStringSubmatch(line, -1) {
+			v, _ := strconv.ParseInt(m[2], 10, 64)
+			switch m[1] {
+			case "active":
+				t.Active = v
+			case "lookup":
+				t.Lookup = v
+			case "matched":
+				t.Matched = v
+			}
+		}
+	}
+	return res
+}
+
+// collectDatapathMetrics 通过 ovs-appctl dpctl/show 获取各 datapath 的 hit/missed/lost 和 megaflow 数
+func collectDatapathMetrics(ctx context.Context, tables map[string][]ovsdb.Row, set *familySet) error {
+	out, err := runOutput(ctx, "ovs-appctl", "dpctl/show")
+	if err != nil {
+		return err
+	}
+	hit := set.family("ovs_datapath_lookup_hit_total", metrics.TypeCounter, "datapath 流缓存命中的报文数")
+	missed := set.family("ovs_datapath_lookup_missed_total", metrics.TypeCounter, "datapath 未命中、上送 vswitchd 的报文数")
+	lost := set.family("ovs_datapath_lookup_lost_total", metrics.TypeCounter, "datapath 上送 vswitchd 前被丢弃的报文数")
+	flows := set.family("ovs_datapath_flows", metrics.TypeGauge, "datapath 流条目（megaflow）数")
+	masks := set.family("ovs_datapath_masks", metrics.TypeGauge, "datapath 掩码数")
+	maskHit := set.family("ovs_datapath_masks_hit_total", metrics.TypeCounter, "datapath 查找掩码的总次数")
+	for _, dp := range parsedatapathStats(string(out)) {
+		hit.Add(float64(dp.Hit), "datapath", dp.Name)
+		missed.Add(float64(dp.Missed), "datapath", dp.Name)
+		lost.Add(float64(dp.Lost), "datapath", dp.Name)
+		flows.Add(float64(dp.Flows), "datapath", dp.Name)
+		if dp.MaskTotal != nil {
+			masks.Add(float64(*dp.MaskTotal), "datapath", dp.Name)
+			maskHit.Add(float64(dp.MaskHit), "datapath", dp.Name)
+		}
+	}
+	return nil
+}
+
+// datapathStats dpctl/show 输出中一个 datapath 的计数
+type datapathStats struct {
+	Name      string
+	Hit       int64
+	Missed    int64
+	Lost      int64
+	Flows     int64
+	MaskHit   int64
+	MaskTotal *int64
+}
+
+var dpCounterRe = regexp.MustCompile(`([a-z]+):(\d+)`)
+
+// parsedatapathStats 解析 ovs-appctl dpctl/show 的输出，顶格的 "type@name:" 行开始一个 datapath
+func parsedatapathStats(out string) []datapathStats {
+	var res []datapathStats
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		raw := sc.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if raw[0] != ' ' && raw[0] != '\t' && strings.HasSuffix(line, ":") {
+			res = append(res, datapathStats{Name: strings.TrimSuffix(line, ":")})
+			continue
+		}
+		if len(res) == 0 {
+			continue
+		}
+		dp := &res[len(res)-1]
+		key, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		counters := map[string]int64{}
+		for _, m := range dpCounterRe.FindAllStringSubmatch(rest, -1) {
+			counters[m[1]], _ = strconv.ParseInt(m[2], 10, 64)
+		}
+		switch key {
+		case "lookups":
+			dp.Hit, dp.Missed, dp.Lost = counters["hit"], counters["missed"], counters["lost"]
+		case "flows":
+			dp.Flows, _ = strconv.ParseInt(strings.TrimSpace(rest), 10, 64)
+		case "masks":
+			total := counters["total"]
+			dp.MaskHit, dp.MaskTotal = counters["hit"], &total
+		}
+	}
+	return res
+}
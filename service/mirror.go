@@ -1,48 +1,209 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-// AddMirror 新增端口镜像
-func AddMirror(bridge, name string, selectSrcPorts, selectDstPorts []string, selectVlan *int, outputPort string, outputVlan *int, selectAll bool) error {
-	args := []string{"--", "--id=@m", "create", "Mirror", fmt.Sprintf("name=%s", name)}
+// MirrorUpdate 修改端口镜像的字段，nil 表示保持不变
+type MirrorUpdate struct {
+	SelectAll      *bool
+	SelectSrcPorts *[]string // 空切片表示清空
+	SelectDstPorts *[]string // 空切片表示清空
+	SelectVlan     *[]int    // 空切片表示清空
+	OutputPort     *string   // 空字符串表示清空
+	OutputVlan     *int      // 0 表示清空
+}
+
+// AddMirror 新增端口镜像，追加到 bridge 已有的镜像集合中
+func AddMirror(ctx context.Context, bridge, name string, selectSrcPorts, selectDstPorts []string, selectVlan *int, outputPort string, outputVlan *int, selectAll bool) error {
+	uuid, err := findMirror(ctx, bridge, name)
+	if err != nil {
+		return err
+	}
+	if uuid != "" {
+		return errorf(CauseAlreadyExists, "mirror %s already exists on bridge %s", name, bridge)
+	}
+	var refs []string
+	columns := []string{fmt.Sprintf("name=%s", name)}
 	if selectAll {
-		args = append(args, "select_all=true")
+		columns = append(columns, "select_all=true")
 	}
-	for _, p := range selectSrcPorts {
-		args = append(args, fmt.Sprintf("select-src-port=%s", p))
+	if len(selectSrcPorts) > 0 {
+		ids, args := portRefs("src", selectSrcPorts)
+		refs = append(refs, args...)
+		columns = append(columns, "select_src_port="+ids)
 	}
-	for _, p := range selectDstPorts {
-		args = append(args, fmt.Sprintf("select-dst-port=%s", p))
+	if len(selectDstPorts) > 0 {
+		ids, args := portRefs("dst", selectDstPorts)
+		refs = append(refs, args...)
+		columns = append(columns, "select_dst_port="+ids)
 	}
 	if selectVlan != nil {
-		args = append(args, fmt.Sprintf("select_vlan=%d", *selectVlan))
+		columns = append(columns, fmt.Sprintf("select_vlan=%d", *selectVlan))
 	}
 	if outputPort != "" {
-		args = append(args, fmt.Sprintf("output-port=%s", outputPort))
+		refs = append(refs, "--", "--id=@out", "get", "Port", outputPort)
+		columns = append(columns, "output_port=@out")
 	}
 	if outputVlan != nil {
-		args = append(args, fmt.Sprintf("output_vlan=%d", *outputVlan))
+		columns = append(columns, fmt.Sprintf("output_vlan=%d", *outputVlan))
 	}
-	args = append([]string{"--", "set", "Bridge", bridge, "mirrors=@m"}, args...)
-	cmd := exec.Command("ovs-vsctl", args...)
-	return cmd.Run()
+	args := append(refs, "--", "--id=@m", "create", "Mirror")
+	args = append(args, columns...)
+	args = append(args, "--", "add", "Bridge", bridge, "mirrors", "@m")
+	return run(ctx, "ovs-vsctl", args...)
 }
 
-// DeleteMirror 删除端口镜像
-func DeleteMirror(bridge, name string) error {
-	cmd := exec.Command("ovs-vsctl", "--", "clear", "Bridge", bridge, "mirrors")
-	return cmd.Run()
+// UpdateMirror 原地修改已有端口镜像的选择条件和输出
+func UpdateMirror(ctx context.Context, bridge, name string, update MirrorUpdate) error {
+	uuid, err := findMirror(ctx, bridge, name)
+	if err != nil {
+		return err
+	}
+	if uuid == "" {
+		return errorf(CauseNotFound, "mirror %s not found on bridge %s", name, bridge)
+	}
+	var refs, sets, clears []string
+	if update.SelectAll != nil {
+		sets = append(sets, fmt.Sprintf("select_all=%t", *update.SelectAll))
+	}
+	if update.SelectSrcPorts != nil {
+		if len(*update.SelectSrcPorts) == 0 {
+			clears = append(clears, "select_src_port")
+		} else {
+			ids, args := portRefs("src", *update.SelectSrcPorts)
+			refs = append(refs, args...)
+			sets = append(sets, "select_src_port="+ids)
+		}
+	}
+	if update.SelectDstPorts != nil {
+		if len(*update.SelectDstPorts) == 0 {
+			clears = append(clears, "select_dst_port")
+		} else {
+			ids, args := portRefs("dst", *update.SelectDstPorts)
+			refs = append(refs, args...)
+			sets = append(sets, "select_dst_port="+ids)
+		}
+	}
+	if update.SelectVlan != nil {
+		if len(*update.SelectVlan) == 0 {
+			clears = append(clears, "select_vlan")
+		} else {
+			vlans := make([]string, len(*update.SelectVlan))
+			for i, v := range *update.SelectVlan {
+				vlans[i] = strconv.Itoa(v)
+			}
+			sets = append(sets, "select_vlan=["+strings.Join(vlans, ",")+"]")
+		}
+	}
+	// output_port 与 output_vlan 互斥，设置其中一个时清空另一个
+	if update.OutputPort != nil {
+		if *update.OutputPort == "" {
+			clears = append(clears, "output_port")
+		} else {
+			refs = append(refs, "--", "--id=@out", "get", "Port", *update.OutputPort)
+			sets = append(sets, "output_port=@out")
+			if update.OutputVlan == nil {
+				clears = append(clears, "output_vlan")
+			}
+		}
+	}
+	if update.OutputVlan != nil {
+		if *update.OutputVlan == 0 {
+			clears = append(clears, "output_vlan")
+		} else {
+			sets = append(sets, fmt.Sprintf("output_vlan=%d", *update.OutputVlan))
+			if update.OutputPort == nil {
+				clears = append(clears, "output_port")
+			}
+		}
+	}
+	if len(sets) == 0 && len(clears) == 0 {
+		return nil
+	}
+	args := refs
+	if len(clears) > 0 {
+		args = append(args, "--", "clear", "Mirror", uuid)
+		args = append(args, clears...)
+	}
+	if len(sets) > 0 {
+		args = append(args, "--", "set", "Mirror", uuid)
+		args = append(args, sets...)
+	}
+	return run(ctx, "ovs-vsctl", args...)
 }
 
-// ListMirrors 查询端口镜像
-func ListMirrors(bridge string) (string, error) {
-	cmd := exec.Command("ovs-vsctl", "list", "Mirror")
-	output, err := cmd.Output()
+// DeleteMirror 从 bridge 中删除指定名称的端口镜像，其余镜像不受影响
+func DeleteMirror(ctx context.Context, bridge, name string) error {
+	uuid, err := findMirror(ctx, bridge, name)
+	if err != nil {
+		return err
+	}
+	if uuid == "" {
+		return errorf(CauseNotFound, "mirror %s not found on bridge %s", name, bridge)
+	}
+	return run(ctx, "ovs-vsctl", "--", "remove", "Bridge", bridge, "mirrors", uuid)
+}
+
+// ListMirrors 查询指定 bridge 上的端口镜像，端口 UUID 解析为端口名，并附带 tx_packets/tx_bytes 统计
+func ListMirrors(ctx context.Context, bridge string) ([]MirrorSnapshot, error) {
+	tables, err := selectTables(ctx, "Bridge", "Port", "Mirror")
+	if err != nil {
+		return nil, err
+	}
+	portNames := make(map[string]string, len(tables["Port"]))
+	for _, p := range tables["Port"] {
+		portNames[p.UUID("_uuid")] = p.String("name")
+	}
+	mirrors := indexByUUID(tables["Mirror"])
+	for _, br := range tables["Bridge"] {
+		if br.String("name") != bridge {
+			continue
+		}
+		res := []MirrorSnapshot{}
+		for _, id := range br.UUIDs("mirrors") {
+			if m, ok := mirrors[id]; ok {
+				res = append(res, mirrorSnapshot(m, portNames))
+			}
+		}
+		sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+		return res, nil
+	}
+	return nil, errorf(CauseNotFound, "no bridge named %s", bridge)
+}
+
+// findMirror 返回 bridge 上指定名称镜像的 UUID，不存在时返回空字符串；bridge 不存在时返回错误
+func findMirror(ctx context.Context, bridge, name string) (string, error) {
+	tables, err := selectTables(ctx, "Bridge", "Mirror")
 	if err != nil {
 		return "", err
 	}
-	return string(output), nil
-} 
\ No newline at end of file
+	mirrors := indexByUUID(tables["Mirror"])
+	for _, br := range tables["Bridge"] {
+		if br.String("name") != bridge {
+			continue
+		}
+		for _, id := range br.UUIDs("mirrors") {
+			if m, ok := mirrors[id]; ok && m.String("name") == name {
+				return id, nil
+			}
+		}
+		return "", nil
+	}
+	return "", errorf(CauseNotFound, "no bridge named %s", bridge)
+}
+
+// portRefs 为端口名生成 --id=@xxx get Port 参数，返回引用集合和参数
+func portRefs(prefix string, ports []string) (string, []string) {
+	ids := make([]string, len(ports))
+	var args []string
+	for i, p := range ports {
+		ids[i] = fmt.Sprintf("@%s%d", prefix, i)
+		args = append(args, "--", "--id="+ids[i], "get", "Port", p)
+	}
+	return "[" + strings.Join(ids, ",") + "]", args
+}
@@ -1,26 +1,23 @@
 package service
 
 import (
-	"os/exec"
+	"context"
 	"strings"
 )
 
 // CreateNetns 创建网络命名空间
-func CreateNetns(name string) error {
-	cmd := exec.Command("ip", "netns", "add", name)
-	return cmd.Run()
+func CreateNetns(ctx context.Context, name string) error {
+	return run(ctx, "ip", "netns", "add", name)
 }
 
 // DeleteNetns 删除网络命名空间
-func DeleteNetns(name string) error {
-	cmd := exec.Command("ip", "netns", "del", name)
-	return cmd.Run()
+func DeleteNetns(ctx context.Context, name string) error {
+	return run(ctx, "ip", "netns", "del", name)
 }
 
 // ListNetns 列出所有网络命名空间
-func ListNetns() ([]string, error) {
-	cmd := exec.Command("ip", "netns", "list")
-	output, err := cmd.Output()
+func ListNetns(ctx context.Context) ([]string, error) {
+	output, err := runOutput(ctx, "ip", "netns", "list")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"ovs-manager/ovsdb"
+)
+
+// vswitchDB Open_vSwitch 数据库名
+const vswitchDB = "Open_vSwitch"
+
+var (
+	ovsdbMu       sync.Mutex
+	ovsdbEndpoint string
+	ovsdbNoWait   bool
+	ovsdbConn     *ovsdb.Client
+)
+
+// SetOVSDBEndpoint 设置 ovsdb-server 地址（如 unix:/var/run/openvswitch/db.sock 或 tcp:127.0.0.1:6640），
+// 设置后支持的接口直接通过 OVSDB JSON-RPC 访问数据库，其余 ovs-vsctl 调用带上 --db 访问同一个库；
+// 为空时仍调用 ovs-vsctl 访问默认数据库
+func SetOVSDBEndpoint(endpoint string) {
+	ovsdbMu.Lock()
+	defer ovsdbMu.Unlock()
+	if ovsdbConn != nil {
+		ovsdbConn.Close()
+		ovsdbConn = nil
+	}
+	ovsdbEndpoint = endpoint
+}
+
+// SetOVSDBNoWait 为 true 时 ovs-vsctl 加 --no-wait，不等待 ovs-vswitchd 应用配置，
+// 用于没有 ovs-vswitchd 的数据库（如内存版 OVSDB）
+func SetOVSDBNoWait(noWait bool) {
+	ovsdbMu.Lock()
+	defer ovsdbMu.Unlock()
+	ovsdbNoWait = noWait
+}
+
+// vsctlArgs 配置了 OVSDB 地址时为 ovs-vsctl 加上 --db，保证写操作与批量查询访问同一个数据库
+func vsctlArgs(args []string) []string {
+	ovsdbMu.Lock()
+	endpoint, noWait := ovsdbEndpoint, ovsdbNoWait
+	ovsdbMu.Unlock()
+	if endpoint == "" || (len(args) > 0 && strings.HasPrefix(args[0], "--db=")) {
+		return args
+	}
+	if strings.HasPrefix(endpoint, "/") {
+		endpoint = "unix:" + endpoint
+	}
+	opts := []string{"--db=" + endpoint}
+	if noWait {
+		opts = append(opts, "--no-wait")
+	}
+	return append(opts, args...)
+}
+
+// ovsdbClient 返回共享的 OVSDB 连接，断开后自动重连；未配置地址时返回 nil
+func ovsdbClient(ctx context.Context) (*ovsdb.Client, error) {
+	ovsdbMu.Lock()
+	defer ovsdbMu.Unlock()
+	if ovsdbEndpoint == "" {
+		return nil, nil
+	}
+	if ovsdbConn != nil {
+		select {
+		case <-ovsdbConn.Done():
+			ovsdbConn = nil
+		default:
+			return ovsdbConn, nil
+		}
+	}
+	client, err := ovsdb.Dial(ctx, ovsdbEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	ovsdbConn = client
+	return client, nil
+}
+
+// ovsdbListBridges 通过 OVSDB 查询所有 bridge 名称
+func ovsdbListBridges(ctx context.Context, client *ovsdb.Client) ([]Response, error) {
+	rows, err := client.Select(ctx, vswitchDB, "Bridge", []string{"name"})
+	if err != nil {
+		return nil, err
+	}
+	var data []Response
+	for _, row := range rows {
+		data = append(data, Response{Name: row.String("name")})
+	}
+	return data, nil
+}
+
+// ovsdbAddBridge 通过一个 OVSDB 事务创建 bridge 及同名 internal 端口，等价于 ovs-vsctl add-br
+func ovsdbAddBridge(ctx context.Context, client *ovsdb.Client, name string) error {
+	_, err := client.Transact(ctx, vswitchDB,
+		ovsdb.Operation{Op: "insert", Table: "Interface", UUIDName: "iface",
+			Row: ovsdb.Row{"name": name, "type": "internal"}},
+		ovsdb.Operation{Op: "insert", Table: "Port", UUIDName: "port",
+			Row: ovsdb.Row{"name": name, "interfaces": ovsdb.NamedUUID{Name: "iface"}}},
+		ovsdb.Operation{Op: "insert", Table: "Bridge", UUIDName: "bridge",
+			Row: ovsdb.Row{"name": name, "ports": ovsdb.NewOvsSet(ovsdb.NamedUUID{Name: "port"})}},
+		ovsdb.Operation{Op: "mutate", Table: "Open_vSwitch",
+			Mutations: []ovsdb.Mutation{ovsdb.NewMutation("bridges", "insert", ovsdb.NewOvsSet(ovsdb.NamedUUID{Name: "bridge"}))}},
+	)
+	return err
+}
+
+// ovsdbDeleteBridge 从根表移除 bridge 引用，Port/Interface 等非根表行由 ovsdb-server 自动回收
+func ovsdbDeleteBridge(ctx context.Context, client *ovsdb.Client, name string) error {
+	rows, err := client.Select(ctx, vswitchDB, "Bridge", []string{"_uuid"}, ovsdb.NewCondition("name", "==", name))
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return errorf(CauseNotFound, "no bridge named %s", name)
+	}
+	_, err = client.Transact(ctx, vswitchDB,
+		ovsdb.Operation{Op: "mutate", Table: "Open_vSwitch",
+			Mutations: []ovsdb.Mutation{ovsdb.NewMutation("bridges", "delete", ovsdb.NewOvsSet(ovsdb.UUID{GoUUID: rows[0].UUID("_uuid")}))}},
+	)
+	return err
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ovs-manager/ovsdb"
+)
+
+// vsctlDBRunner 模拟 ovs-vsctl add-port：只写入 --db 指定的数据库，未指定时报错
+type vsctlDBRunner struct {
+	calls []string
+}
+
+func (r *vsctlDBRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	r.calls = append(r.calls, commandLine(name, args))
+	db := ""
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		if strings.HasPrefix(args[0], "--db=") {
+			db = strings.TrimPrefix(args[0], "--db=")
+		}
+		args = args[1:]
+	}
+	if name != "ovs-vsctl" || db == "" || len(args) < 3 || args[0] != "add-port" {
+		return nil, fmt.Errorf("unexpected command %s", commandLine(name, args))
+	}
+	client, err := ovsdb.Dial(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	bridge, port := args[1], args[2]
+	_, err = client.Transact(ctx, vswitchDB,
+		ovsdb.Operation{Op: "insert", Table: "Interface", UUIDName: "iface",
+			Row: ovsdb.Row{"name": port, "type": "internal"}},
+		ovsdb.Operation{Op: "insert", Table: "Port", UUIDName: "port",
+			Row: ovsdb.Row{"name": port, "interfaces": ovsdb.NamedUUID{Name: "iface"}}},
+		ovsdb.Operation{Op: "mutate", Table: "Bridge", Where: []ovsdb.Condition{ovsdb.NewCondition("name", "==", bridge)},
+			Mutations: []ovsdb.Mutation{ovsdb.NewMutation("ports", "insert", ovsdb.NewOvsSet(ovsdb.NamedUUID{Name: "port"}))}},
+	)
+	return nil, err
+}
+
+func (r *vsctlDBRunner) RunInput(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	return r.Run(ctx, name, args...)
+}
+
+func TestOVSDBEndpointWriteThenRead(t *testing.T) {
+	srv := ovsdb.NewServer(ovsdb.OpenvSwitchSchema())
+	endpoint := "unix:" + filepath.Join(t.TempDir(), "db.sock")
+	if err := srv.Listen(endpoint); err != nil {
+		t.Skip(err)
+	}
+	defer srv.Close()
+	SetOVSDBEndpoint(endpoint)
+	SetOVSDBNoWait(true)
+	defer func() {
+		SetOVSDBEndpoint("")
+		SetOVSDBNoWait(false)
+	}()
+	runner := &vsctlDBRunner{}
+	prev := SetRunner(runner)
+	defer SetRunner(prev)
+	ctx := context.Background()
+
+	if err := AddBridge(ctx, "br0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddPort(ctx, "br0", "p1", "internal", ""); err != nil {
+		t.Fatal(err)
+	}
+	want := "ovs-vsctl --db=" + endpoint + " --no-wait add-port br0 p1 -- set Interface p1 type=internal"
+	if len(runner.calls) != 1 || runner.calls[0] != want {
+		t.Errorf("calls = %q, want %q", runner.calls, want)
+	}
+	ports, err := ListPorts(ctx, "br0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 1 || ports[0].Name != "p1" || ports[0].Type != "internal" {
+		t.Errorf("ListPorts after add-port = %+v", ports)
+	}
+}
+
+func TestVsctlArgsWithoutEndpoint(t *testing.T) {
+	args := []string{"add-br", "br0"}
+	if got := vsctlArgs(args); strings.Join(got, " ") != "add-br br0" {
+		t.Errorf("vsctlArgs = %q", got)
+	}
+}
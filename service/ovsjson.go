@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"ovs-manager/ovsdb"
+)
+
+// vsctlTable ovs-vsctl --format=json --data=json 输出的一张表
+type vsctlTable struct {
+	Headings []string        `json:"headings"`
+	Data     [][]interface{} `json:"data"`
+}
+
+// rows 将表格数据转换为按列名索引的行
+func (t vsctlTable) rows() ([]ovsdb.Row, error) {
+	rows := make([]ovsdb.Row, 0, len(t.Data))
+	for _, data := range t.Data {
+		if len(data) != len(t.Headings) {
+			return nil, fmt.Errorf("ovs-vsctl json: row has %d cells, expected %d", len(data), len(t.Headings))
+		}
+		row := make(ovsdb.Row, len(data))
+		for i, cell := range data {
+			v, err := ovsdb.DecodeValue(cell)
+			if err != nil {
+				return nil, err
+			}
+			row[t.Headings[i]] = v
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// decodeTables 解析一次 ovs-vsctl 调用中多条 list/find 命令的 JSON 输出，按顺序对应 names
+func decodeTables(names []string, out []byte) (map[string][]ovsdb.Row, error) {
+	res := make(map[string][]ovsdb.Row, len(names))
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for _, name := range names {
+		var t vsctlTable
+		if err := dec.Decode(&t); err == io.EOF {
+			return nil, fmt.Errorf("ovs-vsctl json: missing output for %s", name)
+		} else if err != nil {
+			return nil, fmt.Errorf("ovs-vsctl json: %v", err)
+		}
+		rows, err := t.rows()
+		if err != nil {
+			return nil, err
+		}
+		res[name] = append(res[name], rows...)
+	}
+	return res, nil
+}
+
+// tableQuery 一张表的查询：表名和需要的列，Columns 为空时返回全部列
+type tableQuery struct {
+	Table   string
+	Columns []string
+}
+
+// selectTables 一次性查询多张表的全部行，见 queryTables
+func selectTables(ctx context.Context, tables ...string) (map[string][]ovsdb.Row, error) {
+	queries := make([]tableQuery, len(tables))
+	for i, t := range tables {
+		queries[i] = tableQuery{Table: t}
+	}
+	return queryTables(ctx, queries...)
+}
+
+// queryTables 一次性查询多张表：配置了 OVSDB 时在同一个事务中 select，
+// 否则只执行一次 ovs-vsctl --format=json list，避免按行逐个调用 ovs-vsctl get
+func queryTables(ctx context.Context, queries ...tableQuery) (map[string][]ovsdb.Row, error) {
+	ctx, cancel := WithDefaultTimeout(ctx)
+	defer cancel()
+	client, err := ovsdbClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(queries))
+	for i, q := range queries {
+		names[i] = q.Table
+	}
+	if client != nil {
+		ops := make([]ovsdb.Operation, len(queries))
+		for i, q := range queries {
+			ops[i] = ovsdb.Operation{Op: "select", Table: q.Table, Columns: q.Columns}
+		}
+		results, err := client.Transact(ctx, vswitchDB, ops...)
+		if err != nil {
+			return nil, err
+		}
+		res := make(map[string][]ovsdb.Row, len(queries))
+		for i, name := range names {
+			res[name] = results[i].Rows
+		}
+		return res, nil
+	}
+	args := []string{"--format=json", "--data=json"}
+	for _, q := range queries {
+		args = append(args, "--")
+		if len(q.Columns) > 0 {
+			args = append(args, "--columns="+strings.Join(q.Columns, ","))
+		}
+		args = append(args, "list", q.Table)
+	}
+	out, err := runOutput(ctx, "ovs-vsctl", args...)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTables(names, out)
+}
+
+// indexByUUID 按 _uuid 建立索引
+func indexByUUID(rows []ovsdb.Row) map[string]ovsdb.Row {
+	idx := make(map[string]ovsdb.Row, len(rows))
+	for _, row := range rows {
+		idx[row.UUID("_uuid")] = row
+	}
+	return idx
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IngressPolicing 接口入方向限速，速率单位 kbps，突发单位 kb；Rate 为 0 表示不限速
+type IngressPolicing struct {
+	Interface string `json:"interface"`
+	Rate      int    `json:"rate"`
+	Burst     int    `json:"burst"`
+}
+
+// SetIngressPolicing 设置接口入方向限速，rate 为 0 时关闭限速
+func SetIngressPolicing(ctx context.Context, iface string, rate, burst int) error {
+	if rate < 0 || burst < 0 {
+		return errorf(CauseInvalidArgument, "rate and burst must not be negative")
+	}
+	return run(ctx, "ovs-vsctl", "set", "Interface", iface,
+		fmt.Sprintf("ingress_policing_rate=%d", rate), fmt.Sprintf("ingress_policing_burst=%d", burst))
+}
+
+// GetIngressPolicing 查询接口入方向限速
+func GetIngressPolicing(ctx context.Context, iface string) (*IngressPolicing, error) {
+	tables, err := queryTables(ctx, tableQuery{Table: "Interface", Columns: []string{"name", "ingress_policing_rate", "ingress_policing_burst"}})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range tables["Interface"] {
+		if row.String("name") == iface {
+			return &IngressPolicing{Interface: iface, Rate: row.Int("ingress_policing_rate"), Burst: row.Int("ingress_policing_burst")}, nil
+		}
+	}
+	return nil, errorf(CauseNotFound, "no interface named %s", iface)
+}
+
+// MeterBand 计量器的一个带：type 为 drop 或 dscp_remark，速率单位由 Meter.Unit 决定
+type MeterBand struct {
+	Type      string `json:"type"`
+	Rate      int    `json:"rate"`
+	BurstSize int    `json:"burstSize,omitempty"`
+	PrecLevel int    `json:"precLevel,omitempty"` // 仅 dscp_remark
+}
+
+// Meter OpenFlow 1.3 计量器
+type Meter struct {
+	ID    int         `json:"id"`
+	Unit  string      `json:"unit"` // kbps 或 pktps
+	Burst bool        `json:"burst"`
+	Stats bool        `json:"stats"`
+	Bands []MeterBand `json:"bands"`
+}
+
+// MeterBandStats 计量器带统计
+type MeterBandStats struct {
+	PacketCount uint64 `json:"packetCount"`
+	ByteCount   uint64 `json:"byteCount"`
+}
+
+// MeterStats ovs-ofctl meter-stats 的一条记录
+type MeterStats struct {
+	ID            int              `json:"id"`
+	FlowCount     uint64           `json:"flowCount"`
+	PacketInCount uint64           `json:"packetInCount"`
+	ByteInCount   uint64           `json:"byteInCount"`
+	Duration      float64          `json:"duration"`
+	Bands         []MeterBandStats `json:"bands"`
+}
+
+// meterProtocol 计量器只在 OpenFlow 1.3 及以上可用
+const meterProtocol = "OpenFlow13"
+
+// String 按 ovs-ofctl add-meter 语法输出
+func (m Meter) String() string {
+	parts := []string{fmt.Sprintf("meter=%d", m.ID), m.Unit}
+	if m.Burst {
+		parts = append(parts, "burst")
+	}
+	if m.Stats {
+		parts = append(parts, "stats")
+	}
+	for i, b := range m.Bands {
+		band := fmt.Sprintf("type=%s,rate=%d", b.Type, b.Rate)
+		if b.BurstSize > 0 {
+			band += fmt.Sprintf(",burst_size=%d", b.BurstSize)
+		}
+		if b.Type == "dscp_remark" {
+			band += fmt.Sprintf(",prec_level=%d", b.PrecLevel)
+		}
+		if i == 0 {
+			band = "bands=" + band
+		}
+		parts = append(parts, band)
+	}
+	return strings.Join(parts, ",")
+}
+
+// validate 检查计量器参数
+func (m Meter) validate() error {
+	if m.ID <= 0 {
+		return errorf(CauseInvalidArgument, "meter id must be positive")
+	}
+	if m.Unit != "kbps" && m.Unit != "pktps" {
+		return errorf(CauseInvalidArgument, "meter unit must be kbps or pktps")
+	}
+	if len(m.Bands) == 0 {
+		return errorf(CauseInvalidArgument, "meter %d needs at least one band", m.ID)
+	}
+	for _, b := range m.Bands {
+		if b.Type != "drop" && b.Type != "dscp_remark" {
+			return errorf(CauseInvalidArgument, "unsupported meter band type %q", b.Type)
+		}
+		if b.Rate <= 0 || b.BurstSize < 0 {
+			return errorf(CauseInvalidArgument, "meter band rate must be positive")
+		}
+		if b.BurstSize > 0 && !m.Burst {
+			return errorf(CauseInvalidArgument, "burstSize requires burst to be enabled on the meter")
+		}
+	}
+	return nil
+}
+
+// bridgeProtocols 返回网桥显式配置的 OpenFlow 协议版本，为空表示使用默认版本
+func bridgeProtocols(ctx context.Context, bridge string) ([]string, error) {
+	tables, err := queryTables(ctx, tableQuery{Table: "Bridge", Columns: []string{"name", "protocols"}})
+	if err != nil {
+		return nil, err
+	}
+	for _, br := range tables["Bridge"] {
+		if br.String("name") == bridge {
+			return br.Strings("protocols"), nil
+		}
+	}
+	return nil, errorf(CauseNotFound, "no bridge named %s", bridge)
+}
+
+// bridgeSupportsOpenFlow13 网桥未显式配置 protocols 或包含 OpenFlow13 时可以读写组表和计量器
+func bridgeSupportsOpenFlow13(ctx context.Context, bridge string) (bool, error) {
+	protocols, err := bridgeProtocols(ctx, bridge)
+	if err != nil {
+		return false, err
+	}
+	return len(protocols) == 0 || containsString(protocols, meterProtocol), nil
+}
+
+// requireOpenFlow13 网桥未启用 OpenFlow13 时返回 invalid-argument，避免 ovs-ofctl 报出难以理解的版本协商错误；
+// protocols 属于运维配置，不自动修改
+func requireOpenFlow13(ctx context.Context, bridge string) error {
+	ok, err := bridgeSupportsOpenFlow13(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorf(CauseInvalidArgument, "bridge %s does not enable %s in its protocols, meters and groups are unavailable", bridge, meterProtocol)
+	}
+	return nil
+}
+
+// AddMeter 新增计量器，网桥需启用 OpenFlow13
+func AddMeter(ctx context.Context, bridge string, m Meter) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	if err := requireOpenFlow13(ctx, bridge); err != nil {
+		return err
+	}
+	return run(ctx, "ovs-ofctl", "-O", meterProtocol, "add-meter", bridge, m.String())
+}
+
+// ModMeter 修改已有计量器，引用它的流表不受影响
+func ModMeter(ctx context.Context, bridge string, m Meter) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	if err := requireOpenFlow13(ctx, bridge); err != nil {
+		return err
+	}
+	return run(ctx, "ovs-ofctl", "-O", meterProtocol, "mod-meter", bridge, m.String())
+}
+
+// DeleteMeter 删除计量器，引用它的流表会被交换机一并删除
+func DeleteMeter(ctx context.Context, bridge string, id int) error {
+	if err := requireOpenFlow13(ctx, bridge); err != nil {
+		return err
+	}
+	if err := run(ctx, "ovs-ofctl", "-O", meterProtocol, "del-meter", bridge, fmt.Sprintf("meter=%d", id)); err != nil {
+		return err
+	}
+	// 交换机连带删除引用该计量器的流表，存储中的记录同步删除
+	if store, _ := currentFlowStore(); store != nil {
+		meter := strconv.Itoa(id)
+		return store.forget(bridge, func(f Flow) bool { return containsString(actionArgs(f.Actions, "meter"), meter) })
+	}
+	return nil
+}
+
+// ListMeters 查询网桥上的计量器配置
+func ListMeters(ctx context.Context, bridge string) ([]Meter, error) {
+	if err := requireOpenFlow13(ctx, bridge); err != nil {
+		return nil, err
+	}
+	output, err := runOutput(ctx, "ovs-ofctl", "-O", meterProtocol, "dump-meters", bridge)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMeters(string(output))
+}
+
+// GetMeterStats 查询网桥上计量器的统计
+func GetMeterStats(ctx context.Context, bridge string) ([]MeterStats, error) {
+	if err := requireOpenFlow13(ctx, bridge); err != nil {
+		return nil, err
+	}
+	output, err := runOutput(ctx, "ovs-ofctl", "-O", meterProtocol, "meter-stats", bridge)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMeterStats(string(output))
+}
+
+// ovsReplyTokens 去掉 OFPST_* 回复头后按空白切分
+func ovsReplyTokens(output string) []string {
+	var tokens []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, " reply (") {
+			continue
+		}
+		tokens = append(tokens, strings.Fields(line)...)
+	}
+	return tokens
+}
+
+// ParseMeters 解析 ovs-ofctl dump-meters 输出，带可能与 meter 在同一行或独占一行
+func ParseMeters(output string) ([]Meter, error) {
+	meters := []Meter{}
+	var cur *Meter
+	var band *MeterBand
+	for _, tok := range ovsReplyTokens(output) {
+		// 带与 meter 在同一行时首个带紧跟在 "bands=" 之后
+		key, value, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(tok, "bands="), ","), "=")
+		if key == "meter" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errorf(CauseUnknown, "invalid meter id %q in dump-meters output", value)
+			}
+			meters = append(meters, Meter{ID: id, Bands: []MeterBand{}})
+			cur, band = &meters[len(meters)-1], nil
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch key {
+		case "kbps", "pktps":
+			cur.Unit = key
+		case "burst":
+			cur.Burst = true
+		case "stats":
+			cur.Stats = true
+		case "type":
+			cur.Bands = append(cur.Bands, MeterBand{Type: value})
+			band = &cur.Bands[len(cur.Bands)-1]
+		case "rate", "burst_size", "prec_level":
+			if band == nil {
+				continue
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errorf(CauseUnknown, "invalid meter band %s %q", key, value)
+			}
+			switch key {
+			case "rate":
+				band.Rate = n
+			case "burst_size":
+				band.BurstSize = n
+			default:
+				band.PrecLevel = n
+			}
+		}
+	}
+	return meters, nil
+}
+
+// ParseMeterStats 解析 ovs-ofctl meter-stats 输出，如
+// "meter:1 flow_count:1 packet_in_count:10 byte_in_count:980 duration:5.1s bands:\n0: packet_count:2 byte_count:196"
+func ParseMeterStats(output string) ([]MeterStats, error) {
+	stats := []MeterStats{}
+	var cur *MeterStats
+	var band *MeterBandStats
+	for _, tok := range ovsReplyTokens(output) {
+		key, value, _ := strings.Cut(tok, ":")
+		if key == "meter" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errorf(CauseUnknown, "invalid meter id %q in meter-stats output", value)
+			}
+			stats = append(stats, MeterStats{ID: id, Bands: []MeterBandStats{}})
+			cur, band = &stats[len(stats)-1], nil
+			continue
+		}
+		if cur == nil || key == "bands" {
+			continue
+		}
+		if _, err := strconv.Atoi(key); err == nil && value == "" {
+			cur.Bands = append(cur.Bands, MeterBandStats{})
+			band = &cur.Bands[len(cur.Bands)-1]
+			continue
+		}
+		if key == "duration" {
+			cur.Duration, _ = strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "flow_count":
+			cur.FlowCount = n
+		case "packet_in_count":
+			cur.PacketInCount = n
+		case "byte_in_count":
+			cur.ByteInCount = n
+		case "packet_count":
+			if band != nil {
+				band.PacketCount = n
+			}
+		case "byte_count":
+			if band != nil {
+				band.ByteCount = n
+			}
+		}
+	}
+	return stats, nil
+}
+
+// flowUsesMeter 流表动作是否包含 meter 指令，此类流表需要以 OpenFlow13 下发
+func flowUsesMeter(flow string) bool {
+	return flowHasAction(flow, "meter")
+}
+
+// flowHasAction 流表文本的动作部分是否包含指定名称的带参动作（如 meter:1、group:2），包括嵌套动作列表
+func flowHasAction(flow, name string) bool {
+	idx := strings.Index(flow, "actions=")
+	if idx < 0 {
+		return false
+	}
+	return len(actionArgs(splitTopLevel(flow[idx+len("actions="):]), name)) > 0
+}
+
+// AddMeteredFlow 添加经过计量器限速的流表：在动作最前面插入 meter 指令（OpenFlow 要求 meter 先于其它指令）
+func AddMeteredFlow(ctx context.Context, bridge string, meterID int, flow string) error {
+	idx := strings.Index(flow, "actions=")
+	if idx < 0 {
+		return errorf(CauseInvalidArgument, "flow %q has no actions", flow)
+	}
+	if flowUsesMeter(flow) {
+		return errorf(CauseInvalidArgument, "flow %q already has a meter instruction", flow)
+	}
+	metered := fmt.Sprintf("%smeter:%d,%s", flow[:idx+len("actions=")], meterID, flow[idx+len("actions="):])
+	errs, err := ValidateFlow(ctx, bridge, metered)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errorf(CauseInvalidArgument, "invalid flow %q: %s: %s", metered, errs[0].Field, errs[0].Message)
+	}
+	return AddFlowV2(ctx, bridge, metered)
+}
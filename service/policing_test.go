@@ -0,0 +1,85 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMeters(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []Meter
+	}{
+		{
+			name: "bands on separate lines",
+			output: "OFPST_METER_CONFIG reply (OF1.3) (xid=0x2):\n" +
+				"meter=1 kbps burst stats bands=\n" +
+				"type=drop rate=1000 burst_size=200\n" +
+				"type=dscp_remark rate=2000 burst_size=400 prec_level=1\n" +
+				"\n" +
+				"meter=2 pktps bands=\n" +
+				"type=drop rate=100\n",
+			want: []Meter{
+				{ID: 1, Unit: "kbps", Burst: true, Stats: true, Bands: []MeterBand{
+					{Type: "drop", Rate: 1000, BurstSize: 200},
+					{Type: "dscp_remark", Rate: 2000, BurstSize: 400, PrecLevel: 1},
+				}},
+				{ID: 2, Unit: "pktps", Bands: []MeterBand{{Type: "drop", Rate: 100}}},
+			},
+		},
+		{
+			name: "band on meter line",
+			output: "OFPST_METER_CONFIG reply (OF1.3) (xid=0x2):\n" +
+				"meter=3 kbps stats bands=type=drop rate=500\n",
+			want: []Meter{{ID: 3, Unit: "kbps", Stats: true, Bands: []MeterBand{{Type: "drop", Rate: 500}}}},
+		},
+		{
+			name:   "empty",
+			output: "OFPST_METER_CONFIG reply (OF1.3) (xid=0x2):\n",
+			want:   []Meter{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMeters(tt.output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMeters =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+	for _, output := range []string{"meter=x kbps bands=\n", "meter=1 kbps bands=\ntype=drop rate=fast\n"} {
+		if _, err := ParseMeters(output); CauseOf(err) != CauseUnknown {
+			t.Errorf("ParseMeters(%q) error = %v", output, err)
+		}
+	}
+}
+
+func TestParseMeterStats(t *testing.T) {
+	output := "OFPST_METER reply (OF1.3) (xid=0x2):\n" +
+		"meter:1 flow_count:2 packet_in_count:10 byte_in_count:980 duration:5.123s bands:\n" +
+		"0: packet_count:2 byte_count:196\n" +
+		"1: packet_count:0 byte_count:0\n" +
+		"\n" +
+		"meter:2 flow_count:0 packet_in_count:0 byte_in_count:0 duration:0.5s bands:\n" +
+		"0: packet_count:0 byte_count:0\n"
+	got, err := ParseMeterStats(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MeterStats{
+		{ID: 1, FlowCount: 2, PacketInCount: 10, ByteInCount: 980, Duration: 5.123, Bands: []MeterBandStats{
+			{PacketCount: 2, ByteCount: 196}, {},
+		}},
+		{ID: 2, Duration: 0.5, Bands: []MeterBandStats{{}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMeterStats =\n%+v\nwant\n%+v", got, want)
+	}
+	if _, err := ParseMeterStats("meter:x flow_count:0\n"); CauseOf(err) != CauseUnknown {
+		t.Errorf("invalid meter id error = %v", err)
+	}
+}
@@ -1,9 +1,12 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
+	"sort"
 	"strings"
+
+	"ovs-manager/ovsdb"
 )
 
 // PortInfo 端口信息结构体
@@ -21,174 +24,168 @@ type PatchPortInfo struct {
 	Peer   string `json:"peer"`
 }
 
-// ListPorts 列出指定 bridge 的所有端口，包含类型信息和状态
-func ListPorts(bridge string) ([]PortInfoResponse, error) {
-	// 获取端口列表
-	cmd := exec.Command("ovs-vsctl", "list-ports", bridge)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
+// portInventory ListPorts/ListAllPatchPorts/ListBonds 共用的批量查询
+func portInventory(ctx context.Context, interfaceColumns ...string) (map[string][]ovsdb.Row, error) {
+	return queryTables(ctx,
+		tableQuery{Table: "Bridge", Columns: []string{"name", "ports"}},
+		tableQuery{Table: "Port", Columns: []string{"_uuid", "name", "interfaces", "bond_mode"}},
+		tableQuery{Table: "Interface", Columns: append([]string{"_uuid", "name"}, interfaceColumns...)},
+	)
+}
 
-	var ports []PortInfoResponse
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if line != "" {
-			// 获取端口类型
-			portType := getPortType(line)
-			// 获取端口状态
-			portUp := getPortStatus(line)
-			portAlias := getPortAlias(line)
-			ports = append(ports, PortInfoResponse{
-				Name:  line,
-				Type:  portType,
-				Up:    portUp,
-				Alias: portAlias,
-			})
+// bridgePorts 返回 bridge 上的 Port 行（按名称排序），bridge 不存在时返回 not-found 错误
+func bridgePorts(tables map[string][]ovsdb.Row, bridge string) ([]ovsdb.Row, error) {
+	for _, br := range tables["Bridge"] {
+		if br.String("name") == bridge {
+			return bridgeRowPorts(br, indexByUUID(tables["Port"])), nil
 		}
 	}
-	return ports, nil
+	return nil, errorf(CauseNotFound, "no bridge named %s", bridge)
 }
 
-// getPortType 获取端口类型
-func getPortType(portName string) string {
-	// 查询端口类型
-	cmd := exec.Command("ovs-vsctl", "get", "Interface", portName, "type")
-	output, err := cmd.Output()
-	if err != nil {
-		// 如果获取类型失败，默认为 normal
-		return "normal"
+// bridgeRowPorts 返回 Bridge 行引用的 Port 行（按名称排序），ports 为按 UUID 索引的 Port 表；
+// 遍历多个网桥时只索引一次 Port 表
+func bridgeRowPorts(br ovsdb.Row, ports map[string]ovsdb.Row) []ovsdb.Row {
+	var res []ovsdb.Row
+	for _, id := range br.UUIDs("ports") {
+		if p, ok := ports[id]; ok {
+			res = append(res, p)
+		}
 	}
+	sort.Slice(res, func(i, j int) bool { return res[i].String("name") < res[j].String("name") })
+	return res
+}
 
-	portType := strings.TrimSpace(string(output))
-	if portType == "" {
-		return "normal"
+// portInterface 返回端口的同名接口（bond 等多接口端口返回第一个）
+func portInterface(port ovsdb.Row, ifaces map[string]ovsdb.Row) (ovsdb.Row, bool) {
+	var first ovsdb.Row
+	for _, id := range port.UUIDs("interfaces") {
+		iface, ok := ifaces[id]
+		if !ok {
+			continue
+		}
+		if iface.String("name") == port.String("name") {
+			return iface, true
+		}
+		if first == nil {
+			first = iface
+		}
 	}
-	return portType
+	return first, first != nil
 }
 
-// getPortStatus 获取端口状态（up/down）
-func getPortStatus(portName string) bool {
-	// 使用 ip link show 命令检查端口状态
-	cmd := exec.Command("ip", "link", "show", portName)
-	output, err := cmd.Output()
+// ListPorts 列出指定 bridge 的所有端口（不含与 bridge 同名的 internal 端口），包含类型信息和状态；
+// 一次批量查询 Bridge/Port/Interface 表，不再按端口逐个调用 ovs-vsctl
+func ListPorts(ctx context.Context, bridge string) ([]PortInfoResponse, error) {
+	tables, err := portInventory(ctx, "type", "admin_state", "external_ids")
 	if err != nil {
-		// 如果命令失败，默认为down状态
-		return false
+		return nil, err
 	}
-
-	// 检查输出中是否包含 "UP" 状态
-	outputStr := string(output)
-	return strings.Contains(outputStr, "UP")
-}
-
-// getPortAlias 获取端口别名（external-ids:ovs-port-name）
-func getPortAlias(portName string) string {
-	cmd := exec.Command("ovs-vsctl", "get", "Interface", portName, "external-ids:ovs-port-name")
-	output, err := cmd.Output()
+	rows, err := bridgePorts(tables, bridge)
 	if err != nil {
-		return ""
+		return nil, err
 	}
-	alias := strings.TrimSpace(string(output))
-	if alias == "[]" {
-		return ""
+	ifaces := indexByUUID(tables["Interface"])
+	ports := []PortInfoResponse{}
+	for _, p := range rows {
+		name := p.String("name")
+		if name == bridge {
+			continue
+		}
+		info := PortInfoResponse{Name: name, Type: "normal"}
+		if iface, ok := portInterface(p, ifaces); ok {
+			if t := iface.String("type"); t != "" {
+				info.Type = t
+			}
+			info.Up = iface.OptionalString("admin_state") == "up"
+			info.Alias = iface.StringMap("external_ids")["ovs-port-name"]
+		}
+		ports = append(ports, info)
 	}
-	alias = strings.Trim(alias, "\"")
-	return alias
+	return ports, nil
 }
 
 // AddPort 向指定 bridge 添加端口，可指定类型
 // 这个函数现在作为通用入口，根据类型调用专门的函数
-func AddPort(bridge, port, portType, nicName string) error {
+func AddPort(ctx context.Context, bridge, port, portType, nicName string) error {
 	switch portType {
 	case "internal":
-		return AddInternalPort(bridge, port)
+		return AddInternalPort(ctx, bridge, port)
 	case "patch":
-		return AddPatchPortWithoutPeer(bridge, port)
+		return AddPatchPortWithoutPeer(ctx, bridge, port)
 	case "vxlan":
-		return AddVxlanPort(bridge, port)
+		return AddVxlanPort(ctx, bridge, port)
 	case "gre":
-		return AddGrePort(bridge, port)
+		return AddGrePort(ctx, bridge, port)
 	case "tap":
-		return AddTapPort(bridge, port)
+		return AddTapPort(ctx, bridge, port)
 	case "tun":
-		return AddTunPort(bridge, port)
+		return AddTunPort(ctx, bridge, port)
 	case "bond":
-		return AddBondPort(bridge, port)
+		return AddBondPort(ctx, bridge, port)
 	case "normal", "":
-		return AddNormalPort(bridge, port, nicName)
+		return AddNormalPort(ctx, bridge, port, nicName)
 	default:
-		return AddCustomTypePort(bridge, port, portType)
+		return AddCustomTypePort(ctx, bridge, port, portType)
 	}
 }
 
 // AddNormalPort 添加普通端口（默认类型）
-func AddNormalPort(bridge, port, nicName string) error {
+func AddNormalPort(ctx context.Context, bridge, port, nicName string) error {
 	if nicName == "" {
-		cmd := exec.Command("ovs-vsctl", "add-port", bridge, port)
-		return cmd.Run()
+		return run(ctx, "ovs-vsctl", "add-port", bridge, port)
 	}
 	// 使用网卡名称添加到网桥，但设置别名
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, nicName, "--", "set", "Interface", nicName, "external-ids:ovs-port-name="+port)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", "add-port", bridge, nicName, "--", "set", "Interface", nicName, "external-ids:ovs-port-name="+port)
 }
 
 // AddInternalPort 添加内部端口
-func AddInternalPort(bridge, port string) error {
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, port, "--", "set", "Interface", port, "type=internal")
-	return cmd.Run()
+func AddInternalPort(ctx context.Context, bridge, port string) error {
+	return run(ctx, "ovs-vsctl", "add-port", bridge, port, "--", "set", "Interface", port, "type=internal")
 }
 
 // AddGrePort 添加GRE隧道端口
-func AddGrePort(bridge, port string) error {
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, port, "--", "set", "Interface", port, "type=gre")
-		return cmd.Run()
-	}
+func AddGrePort(ctx context.Context, bridge, port string) error {
+	return run(ctx, "ovs-vsctl", "add-port", bridge, port, "--", "set", "Interface", port, "type=gre")
+}
 
 // AddCustomTypePort 添加自定义类型端口
-func AddCustomTypePort(bridge, port, portType string) error {
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, port, "--", "set", "Interface", port, "type="+portType)
-	return cmd.Run()
+func AddCustomTypePort(ctx context.Context, bridge, port, portType string) error {
+	return run(ctx, "ovs-vsctl", "add-port", bridge, port, "--", "set", "Interface", port, "type="+portType)
 }
 
 // DeletePort 从指定 bridge 删除端口
-func DeletePort(bridge, port string) error {
-	cmd := exec.Command("ovs-vsctl", "del-port", bridge, port)
-	return cmd.Run()
+func DeletePort(ctx context.Context, bridge, port string) error {
+	return run(ctx, "ovs-vsctl", "del-port", bridge, port)
 }
 
 // BindPortToNetns 将端口绑定到指定命名空间
-func BindPortToNetns(portName, netns string) error {
-	cmd := exec.Command("ip", "link", "set", portName, "netns", netns)
-	return cmd.Run()
+func BindPortToNetns(ctx context.Context, portName, netns string) error {
+	return run(ctx, "ip", "link", "set", portName, "netns", netns)
 }
 
 // UnbindPortFromNetns 将端口解绑到主命名空间
-func UnbindPortFromNetns(portName string) error {
-	cmd := exec.Command("ip", "link", "set", portName, "netns", "1")
-	return cmd.Run()
+func UnbindPortFromNetns(ctx context.Context, portName string) error {
+	return run(ctx, "ip", "link", "set", portName, "netns", "1")
 }
 
 // SetPortUpDown 设置端口 up/down
-func SetPortUpDown(portName string, up bool) error {
+func SetPortUpDown(ctx context.Context, portName string, up bool) error {
 	state := "down"
 	if up {
 		state = "up"
 	}
-	cmd := exec.Command("ip", "link", "set", portName, state)
-	return cmd.Run()
+	return run(ctx, "ip", "link", "set", portName, state)
 }
 
 // SetPortAddr 给端口分配 IP 地址
-func SetPortAddr(portName, ip string) error {
-	cmd := exec.Command("ip", "addr", "add", ip, "dev", portName)
-	return cmd.Run()
+func SetPortAddr(ctx context.Context, portName, ip string) error {
+	return run(ctx, "ip", "addr", "add", ip, "dev", portName)
 }
 
 // GetPortAddrs 获取端口的IP地址列表
-func GetPortAddrs(portName string) ([]string, error) {
-	cmd := exec.Command("ip", "addr", "show", portName)
-	output, err := cmd.Output()
+func GetPortAddrs(ctx context.Context, portName string) ([]string, error) {
+	output, err := runOutput(ctx, "ip", "addr", "show", portName)
 	if err != nil {
 		return nil, err
 	}
@@ -209,35 +206,31 @@ func GetPortAddrs(portName string) ([]string, error) {
 }
 
 // DeletePortAddr 删除端口的指定IP地址
-func DeletePortAddr(portName, ip string) error {
-	cmd := exec.Command("ip", "addr", "del", ip, "dev", portName)
-	return cmd.Run()
+func DeletePortAddr(ctx context.Context, portName, ip string) error {
+	return run(ctx, "ip", "addr", "del", ip, "dev", portName)
 }
 
 // SetPortVlanTag 设置端口 VLAN tag
-func SetPortVlanTag(portName string, tag int) error {
-	cmd := exec.Command("ovs-vsctl", "set", "port", portName, fmt.Sprintf("tag=%d", tag))
-	return cmd.Run()
+func SetPortVlanTag(ctx context.Context, portName string, tag int) error {
+	return run(ctx, "ovs-vsctl", "set", "port", portName, fmt.Sprintf("tag=%d", tag))
 }
 
 // SetPortVlanMode 设置端口 VLAN mode
-func SetPortVlanMode(portName, vlanMode string) error {
-	cmd := exec.Command("ovs-vsctl", "set", "port", portName, fmt.Sprintf("vlan_mode=%s", vlanMode))
-	return cmd.Run()
+func SetPortVlanMode(ctx context.Context, portName, vlanMode string) error {
+	return run(ctx, "ovs-vsctl", "set", "port", portName, fmt.Sprintf("vlan_mode=%s", vlanMode))
 }
 
 // SetPortTrunks 设置端口 trunks
-func SetPortTrunks(portName string, trunks []int) error {
+func SetPortTrunks(ctx context.Context, portName string, trunks []int) error {
 	trunksStr := make([]string, len(trunks))
 	for i, t := range trunks {
 		trunksStr[i] = fmt.Sprintf("%d", t)
 	}
-	cmd := exec.Command("ovs-vsctl", "set", "port", portName, fmt.Sprintf("trunks=%s", strings.Join(trunksStr, ",")))
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", "set", "port", portName, fmt.Sprintf("trunks=%s", strings.Join(trunksStr, ",")))
 }
 
 // RemovePortProperty 移除端口属性
-func RemovePortProperty(portName, property string, value interface{}) error {
+func RemovePortProperty(ctx context.Context, portName, property string, value interface{}) error {
 	var valStr string
 	switch v := value.(type) {
 	case int:
@@ -253,95 +246,83 @@ func RemovePortProperty(portName, property string, value interface{}) error {
 	case []string:
 		valStr = strings.Join(v, ",")
 	default:
-		return fmt.Errorf("unsupported value type")
+		return errorf(CauseInvalidArgument, "unsupported value type")
 	}
-	cmd := exec.Command("ovs-vsctl", "remove", "port", portName, property, valStr)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", "remove", "port", portName, property, valStr)
 }
 
 // AddPatchPort 添加 patch 端口
-func AddPatchPort(bridge, portName, peer string) error {
+func AddPatchPort(ctx context.Context, bridge, portName, peer string) error {
 	if peer == "" {
 		// 创建不设置对端的patch端口
-		cmd := exec.Command("ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=patch")
-		return cmd.Run()
+		return run(ctx, "ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=patch")
 	}
 	// 创建设置对端的patch端口
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=patch", "options:peer="+peer)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=patch", "options:peer="+peer)
 }
 
 // AddPatchPortWithoutPeer 添加不设置对端的 patch 端口
-func AddPatchPortWithoutPeer(bridge, portName string) error {
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=patch")
-	return cmd.Run()
+func AddPatchPortWithoutPeer(ctx context.Context, bridge, portName string) error {
+	return run(ctx, "ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=patch")
 }
 
 // SetPatchPortPeer 为patch端口设置对端
-func SetPatchPortPeer(portName, peer string) error {
-	cmd := exec.Command("ovs-vsctl", "set", "Interface", portName, "options:peer="+peer)
-	return cmd.Run()
+func SetPatchPortPeer(ctx context.Context, portName, peer string) error {
+	return run(ctx, "ovs-vsctl", "set", "Interface", portName, "options:peer="+peer)
 }
 
 // AddPatchPortPair 一键成对创建 patch 端口
-func AddPatchPortPair(bridgeA, portA, bridgeB, portB string) error {
-	if err := AddPatchPort(bridgeA, portA, portB); err != nil {
+func AddPatchPortPair(ctx context.Context, bridgeA, portA, bridgeB, portB string) error {
+	if err := AddPatchPort(ctx, bridgeA, portA, portB); err != nil {
 		return err
 	}
-	if err := AddPatchPort(bridgeB, portB, portA); err != nil {
+	if err := AddPatchPort(ctx, bridgeB, portB, portA); err != nil {
 		return err
 	}
 	return nil
 }
 
 // AddVxlanPort 添加VXLAN隧道端口（基础版本，不设置参数）
-func AddVxlanPort(bridge, port string) error {
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, port, "--", "set", "Interface", port, "type=vxlan")
-	return cmd.Run()
+func AddVxlanPort(ctx context.Context, bridge, port string) error {
+	return run(ctx, "ovs-vsctl", "add-port", bridge, port, "--", "set", "Interface", port, "type=vxlan")
 }
 
 // AddBondPort 添加Bond端口（基础版本，不设置成员）
-func AddBondPort(bridge, port string) error {
+func AddBondPort(ctx context.Context, bridge, port string) error {
 	// 注意：bond端口通常需要成员，这里创建一个空的bond端口
-	cmd := exec.Command("ovs-vsctl", "add-bond", bridge, port)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", "add-bond", bridge, port)
 }
 
 // AddBondPortWithMembers 添加 bond 端口（带成员和模式）
-func AddBondPortWithMembers(bridge, portName string, members []string, mode string) error {
+func AddBondPortWithMembers(ctx context.Context, bridge, portName string, members []string, mode string) error {
 	args := []string{"add-bond", bridge, portName}
 	args = append(args, members...)
 	args = append(args, "bond_mode="+mode)
-	cmd := exec.Command("ovs-vsctl", args...)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", args...)
 }
 
 // AddTunnelPort 添加 GRE/Geneve Tunnel Port
-func AddTunnelPort(bridge, portName, typ string, options map[string]string) error {
+func AddTunnelPort(ctx context.Context, bridge, portName, typ string, options map[string]string) error {
 	args := []string{"add-port", bridge, portName, "--", "set", "interface", portName, fmt.Sprintf("type=%s", typ)}
 	for k, v := range options {
 		args = append(args, fmt.Sprintf("options:%s=%s", k, v))
 	}
-	cmd := exec.Command("ovs-vsctl", args...)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", args...)
 }
 
 // AddTapPort 添加 tap 端口
-func AddTapPort(bridge, portName string) error {
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=tap")
-	return cmd.Run()
+func AddTapPort(ctx context.Context, bridge, portName string) error {
+	return run(ctx, "ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=tap")
 }
 
 // AddTunPort 添加 tun 端口
-func AddTunPort(bridge, portName string) error {
-	cmd := exec.Command("ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=tun")
-	return cmd.Run()
+func AddTunPort(ctx context.Context, bridge, portName string) error {
+	return run(ctx, "ovs-vsctl", "add-port", bridge, portName, "--", "set", "Interface", portName, "type=tun")
 }
 
 // PortInfo 查询端口/interface 详细属性
-func PortInfo(portName string) (string, error) {
-	cmd := exec.Command("ovs-vsctl", "list", "interface", portName)
-	output, err := cmd.Output()
+func PortInfo(ctx context.Context, portName string) (string, error) {
+	output, err := runOutput(ctx, "ovs-vsctl", "list", "interface", portName)
 	if err != nil {
 		return "", err
 	}
@@ -349,120 +330,89 @@ func PortInfo(portName string) (string, error) {
 }
 
 // SetBfd 设置 BFD
-func SetBfd(portName string, bfd map[string]string) error {
+func SetBfd(ctx context.Context, portName string, bfd map[string]string) error {
 	args := []string{"set", "interface", portName}
 	for k, v := range bfd {
 		args = append(args, fmt.Sprintf("bfd:%s=%s", k, v))
 	}
-	cmd := exec.Command("ovs-vsctl", args...)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", args...)
 }
 
 // SetCfm 设置 CFM (802.1ag)
-func SetCfm(portName string, cfm map[string]string) error {
+func SetCfm(ctx context.Context, portName string, cfm map[string]string) error {
 	args := []string{"set", "interface", portName}
 	for k, v := range cfm {
 		args = append(args, fmt.Sprintf("cfm:%s=%s", k, v))
 	}
-	cmd := exec.Command("ovs-vsctl", args...)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", args...)
 }
 
 // SetMcastSnooping 设置组播监听
-func SetMcastSnooping(bridge string, enable bool) error {
+func SetMcastSnooping(ctx context.Context, bridge string, enable bool) error {
 	val := "false"
 	if enable {
 		val = "true"
 	}
-	cmd := exec.Command("ovs-vsctl", "set", "Bridge", bridge, fmt.Sprintf("mcast_snooping_enable=%s", val))
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", "set", "Bridge", bridge, fmt.Sprintf("mcast_snooping_enable=%s", val))
 }
 
-// SetHfscQos 设置 HFSC QoS
-func SetHfscQos(portName, maxRate string, queues map[string]string) error {
-	args := []string{"set", "port", portName, "qos=@newqos"}
-	qosArgs := []string{"--", "--id=@newqos", "create", "qos", "type=hfsc"}
-	if maxRate != "" {
-		qosArgs = append(qosArgs, fmt.Sprintf("other-config:max-rate=%s", maxRate))
-	}
-	if len(queues) > 0 {
-		queueStrs := make([]string, 0, len(queues))
-		for k, v := range queues {
-			queueStrs = append(queueStrs, fmt.Sprintf("%s=%s", k, v))
-		}
-		qosArgs = append(qosArgs, fmt.Sprintf("queues=%s", strings.Join(queueStrs, ",")))
-	}
-	cmd := exec.Command("ovs-vsctl", append(args, qosArgs...)...)
-	return cmd.Run()
+// SetHfscQos 设置 HFSC QoS，queues 为队列号到 max-rate 的映射；端口原有的 QoS 及队列不再被引用时一并删除
+func SetHfscQos(ctx context.Context, portName, maxRate string, queues map[string]string) error {
+	return replacePortQos(ctx, portName, QosSpec{Type: "hfsc", MaxRate: maxRate}, queues)
 }
 
 // SetDatapathType 设置网桥 datapath_type
-func SetDatapathType(bridge, datapathType string) error {
-	cmd := exec.Command("ovs-vsctl", "set", "Bridge", bridge, fmt.Sprintf("datapath_type=%s", datapathType))
-	return cmd.Run()
+func SetDatapathType(ctx context.Context, bridge, datapathType string) error {
+	return run(ctx, "ovs-vsctl", "set", "Bridge", bridge, fmt.Sprintf("datapath_type=%s", datapathType))
 }
 
 // SetPortTypePeer 设置端口类型和 peer
-func SetPortTypePeer(bridge, portName, typ, peer string) error {
-	cmd := exec.Command("ovs-vsctl", "set", "Interface", portName, "type="+typ, "options:peer="+peer)
-	return cmd.Run()
+func SetPortTypePeer(ctx context.Context, bridge, portName, typ, peer string) error {
+	return run(ctx, "ovs-vsctl", "set", "Interface", portName, "type="+typ, "options:peer="+peer)
 }
 
 // SetPortAlias 设置端口别名（external-ids:ovs-port-name）
-func SetPortAlias(portName, alias string) error {
-	cmd := exec.Command("ovs-vsctl", "set", "Interface", portName, "external-ids:ovs-port-name="+alias)
-	return cmd.Run()
+func SetPortAlias(ctx context.Context, portName, alias string) error {
+	return run(ctx, "ovs-vsctl", "set", "Interface", portName, "external-ids:ovs-port-name="+alias)
 }
 
 // ListAllPatchPorts 返回所有 bridge 下的 patch 端口
-func ListAllPatchPorts() ([]PatchPortInfo, error) {
-	cmd := exec.Command("ovs-vsctl", "list-br")
-	output, err := cmd.Output()
+func ListAllPatchPorts(ctx context.Context) ([]PatchPortInfo, error) {
+	tables, err := portInventory(ctx, "type", "options")
 	if err != nil {
 		return nil, err
 	}
-	bridges := strings.Fields(string(output))
-	var result []PatchPortInfo
-	for _, bridge := range bridges {
-		portsCmd := exec.Command("ovs-vsctl", "list-ports", bridge)
-		portsOut, err := portsCmd.Output()
-		if err != nil {
-			continue
-		}
-		ports := strings.Fields(string(portsOut))
-		for _, port := range ports {
-			typeCmd := exec.Command("ovs-vsctl", "get", "interface", port, "type")
-			typeOut, err := typeCmd.Output()
-			if err != nil {
+	ports := indexByUUID(tables["Port"])
+	ifaces := indexByUUID(tables["Interface"])
+	bridges := tables["Bridge"]
+	sort.Slice(bridges, func(i, j int) bool { return bridges[i].String("name") < bridges[j].String("name") })
+	result := []PatchPortInfo{}
+	for _, br := range bridges {
+		bridge := br.String("name")
+		for _, p := range bridgeRowPorts(br, ports) {
+			iface, ok := portInterface(p, ifaces)
+			if !ok || iface.String("type") != "patch" {
 				continue
 			}
-			if strings.TrimSpace(string(typeOut)) == "patch" {
-				peerCmd := exec.Command("ovs-vsctl", "get", "interface", port, "options:peer")
-				peerOut, _ := peerCmd.Output()
-				peer := strings.Trim(strings.TrimSpace(string(peerOut)), "\"")
-				result = append(result, PatchPortInfo{Bridge: bridge, Name: port, Peer: peer})
-			}
+			result = append(result, PatchPortInfo{Bridge: bridge, Name: p.String("name"), Peer: iface.StringMap("options")["peer"]})
 		}
 	}
 	return result, nil
 }
 
 // SetPortRoute 设置端口静态路由
-func SetPortRoute(portName, destination, gateway string) error {
-	cmd := exec.Command("ip", "route", "add", destination, "via", gateway, "dev", portName)
-	return cmd.Run()
+func SetPortRoute(ctx context.Context, portName, destination, gateway string) error {
+	return run(ctx, "ip", "route", "add", destination, "via", gateway, "dev", portName)
 }
 
 // DeletePortRoute 删除端口静态路由
-func DeletePortRoute(portName, destination, gateway string) error {
-	cmd := exec.Command("ip", "route", "del", destination, "via", gateway, "dev", portName)
-	return cmd.Run()
+func DeletePortRoute(ctx context.Context, portName, destination, gateway string) error {
+	return run(ctx, "ip", "route", "del", destination, "via", gateway, "dev", portName)
 }
 
 // GetPortRoutes 获取端口路由列表
-func GetPortRoutes(portName string) ([]string, error) {
-	cmd := exec.Command("ip", "route", "show", "dev", portName)
-	output, err := cmd.Output()
+func GetPortRoutes(ctx context.Context, portName string) ([]string, error) {
+	output, err := runOutput(ctx, "ip", "route", "show", "dev", portName)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// vsctlList 按 ovs-vsctl --format=json --data=json list 的格式输出 rows 中的指定列
+func vsctlList(rows []map[string]interface{}, columns []string) string {
+	data := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		data[i] = make([]interface{}, len(columns))
+		for j, col := range columns {
+			data[i][j] = row[col]
+		}
+	}
+	out, _ := json.Marshal(vsctlTable{Headings: columns, Data: data})
+	return string(out) + "\n"
+}
+
+func fixtureUUID(n int) []interface{} {
+	return []interface{}{"uuid", fmt.Sprintf("%08x-0000-4000-8000-000000000000", n)}
+}
+
+func fixtureSet(values ...interface{}) []interface{} {
+	return []interface{}{"set", append([]interface{}{}, values...)}
+}
+
+func fixtureMap(kv ...string) []interface{} {
+	pairs := []interface{}{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		pairs = append(pairs, []interface{}{kv[i], kv[i+1]})
+	}
+	return []interface{}{"map", pairs}
+}
+
+// portFixture 构造 bridges 个网桥、每个网桥 portsPerBridge 个端口的 Bridge/Port/Interface 表：
+// 每 10 个端口中有一个 patch 端口，每 25 个端口中有一个双成员 bond
+type portFixture struct {
+	bridges, ports, ifaces []map[string]interface{}
+}
+
+func newPortFixture(bridges, portsPerBridge int) *portFixture {
+	f := &portFixture{}
+	n := 0
+	next := func() []interface{} {
+		n++
+		return fixtureUUID(n)
+	}
+	for b := 0; b < bridges; b++ {
+		brName := fmt.Sprintf("br%d", b)
+		var portRefs []interface{}
+		for p := 0; p < portsPerBridge; p++ {
+			name := fmt.Sprintf("%s-p%d", brName, p)
+			portUUID := next()
+			var ifaceRefs []interface{}
+			bondMode := fixtureSet()
+			addIface := func(ifName, typ string, options []interface{}) {
+				uuid := next()
+				ifaceRefs = append(ifaceRefs, uuid)
+				f.ifaces = append(f.ifaces, map[string]interface{}{
+					"_uuid": uuid, "name": ifName, "type": typ, "options": options,
+					"admin_state": "up", "external_ids": fixtureMap(),
+				})
+			}
+			switch {
+			case p%25 == 24:
+				addIface(name+"-a", "", fixtureMap())
+				addIface(name+"-b", "", fixtureMap())
+				bondMode = fixtureSet("balance-slb")
+			case p%10 == 9:
+				peer := fmt.Sprintf("br%d-p%d", (b+1)%bridges, p)
+				addIface(name, "patch", fixtureMap("peer", peer))
+			default:
+				addIface(name, "internal", fixtureMap())
+			}
+			f.ports = append(f.ports, map[string]interface{}{
+				"_uuid": portUUID, "name": name, "interfaces": fixtureSet(ifaceRefs...), "bond_mode": bondMode,
+			})
+			portRefs = append(portRefs, portUUID)
+		}
+		f.bridges = append(f.bridges, map[string]interface{}{"name": brName, "ports": fixtureSet(portRefs...)})
+	}
+	return f
+}
+
+// expectInventory 为 portInventory(ctx, interfaceColumns...) 的 ovs-vsctl 调用预设输出
+func (f *portFixture) expectInventory(fake *FakeRunner, interfaceColumns ...string) {
+	bridgeCols := []string{"name", "ports"}
+	portCols := []string{"_uuid", "name", "interfaces", "bond_mode"}
+	ifaceCols := append([]string{"_uuid", "name"}, interfaceColumns...)
+	cmdline := fmt.Sprintf("ovs-vsctl --format=json --data=json -- --columns=%s list Bridge -- --columns=%s list Port -- --columns=%s list Interface",
+		strings.Join(bridgeCols, ","), strings.Join(portCols, ","), strings.Join(ifaceCols, ","))
+	fake.Expect(cmdline, vsctlList(f.bridges, bridgeCols)+vsctlList(f.ports, portCols)+vsctlList(f.ifaces, ifaceCols))
+}
+
+// benchmarkInventory 在约 400 个端口的假数据上运行 fn，并报告每次调用执行的外部命令数
+func benchmarkInventory(b *testing.B, interfaceColumns []string, fn func(ctx context.Context) error) {
+	fixture := newPortFixture(4, 100)
+	fake := NewFakeRunner()
+	fixture.expectInventory(fake, interfaceColumns...)
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := fn(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(len(fake.Calls()))/float64(b.N), "commands/op")
+}
+
+func BenchmarkListPorts(b *testing.B) {
+	benchmarkInventory(b, []string{"type", "admin_state", "external_ids"}, func(ctx context.Context) error {
+		_, err := ListPorts(ctx, "br2")
+		return err
+	})
+}
+
+func BenchmarkListAllPatchPorts(b *testing.B) {
+	benchmarkInventory(b, []string{"type", "options"}, func(ctx context.Context) error {
+		_, err := ListAllPatchPorts(ctx)
+		return err
+	})
+}
+
+func BenchmarkListBonds(b *testing.B) {
+	benchmarkInventory(b, nil, func(ctx context.Context) error {
+		_, err := ListBonds(ctx)
+		return err
+	})
+}
+
+func TestListAllPatchPorts(t *testing.T) {
+	fake := NewFakeRunner()
+	newPortFixture(2, 20).expectInventory(fake, "type", "options")
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+	ports, err := ListAllPatchPorts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PatchPortInfo{
+		{Bridge: "br0", Name: "br0-p19", Peer: "br1-p19"},
+		{Bridge: "br0", Name: "br0-p9", Peer: "br1-p9"},
+		{Bridge: "br1", Name: "br1-p19", Peer: "br0-p19"},
+		{Bridge: "br1", Name: "br1-p9", Peer: "br0-p9"},
+	}
+	if fmt.Sprint(ports) != fmt.Sprint(want) {
+		t.Errorf("ListAllPatchPorts = %v, want %v", ports, want)
+	}
+	if calls := fake.Calls(); len(calls) != 1 {
+		t.Errorf("expected a single ovs-vsctl call, got %d: %v", len(calls), calls)
+	}
+}
+
+func TestGetPortAddrsReplay(t *testing.T) {
+	replay(t, "port_addrs.jsonl")
+	addrs, err := GetPortAddrs(context.Background(), "lo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只取 IPv4 地址，inet6 行忽略
+	if want := []string{"127.0.0.1/8"}; fmt.Sprint(addrs) != fmt.Sprint(want) {
+		t.Errorf("GetPortAddrs(lo) = %v, want %v", addrs, want)
+	}
+	if _, err := GetPortAddrs(context.Background(), "veth-missing"); CauseOf(err) != CauseNotFound {
+		t.Errorf("GetPortAddrs(veth-missing) error = %v, cause %v", err, CauseOf(err))
+	}
+}
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ovs-manager/ovsdb"
+)
+
+// qosOwnerKey 本服务创建的 QoS/Queue 行在 external_ids 中带有该键，CleanupQos 默认只回收这些行
+const qosOwnerKey = "ovs-manager"
+
+// qosOwnerArg 创建 QoS/Queue 行时附加的归属标记
+var qosOwnerArg = "external-ids:" + qosOwnerKey + "=true"
+
+// QueueSpec 队列参数，速率单位为 bit/s
+type QueueSpec struct {
+	MinRate     string
+	MaxRate     string
+	Burst       string
+	Priority    string
+	DSCP        *int
+	OtherConfig map[string]string // 其它 other_config 键值
+}
+
+// QosSpec QoS 参数，Queues 为队列号到 Queue UUID 的映射
+type QosSpec struct {
+	Type        string
+	MaxRate     string
+	OtherConfig map[string]string
+	ExternalIDs map[string]string
+	Queues      map[string]string
+}
+
+// QueueInfo 队列及引用它的 QoS
+type QueueInfo struct {
+	QueueSnapshot
+	UsedBy []string `json:"usedBy"`
+}
+
+// QosInfo QoS 及使用它的端口
+type QosInfo struct {
+	QoSSnapshot
+	Ports []string `json:"ports"`
+}
+
+// QosCleanupResult 清理未被引用的 QoS/Queue 行的结果
+type QosCleanupResult struct {
+	QoS    []string `json:"qos"`
+	Queues []string `json:"queues"`
+}
+
+// qosTables 查询 Port/QoS/Queue 三张表
+func qosTables(ctx context.Context) (map[string][]ovsdb.Row, error) {
+	return queryTables(ctx,
+		tableQuery{Table: "Port", Columns: []string{"_uuid", "name", "qos"}},
+		tableQuery{Table: "QoS"},
+		tableQuery{Table: "Queue"},
+	)
+}
+
+// qosQueueUUIDs 返回 QoS 行引用的全部 Queue UUID
+func qosQueueUUIDs(q ovsdb.Row) []string {
+	var ids []string
+	if m, ok := q["queues"].(ovsdb.OvsMap); ok {
+		for _, v := range m.GoMap {
+			if u, ok := v.(ovsdb.UUID); ok {
+				ids = append(ids, u.GoUUID)
+			}
+		}
+	}
+	return ids
+}
+
+// queueArgs 生成 Queue 列参数
+func queueArgs(spec QueueSpec) []string {
+	var args []string
+	config := map[string]string{}
+	for k, v := range spec.OtherConfig {
+		config[k] = v
+	}
+	for k, v := range map[string]string{"min-rate": spec.MinRate, "max-rate": spec.MaxRate, "burst": spec.Burst, "priority": spec.Priority} {
+		if v != "" {
+			config[k] = v
+		}
+	}
+	for _, k := range sortedKeys(config) {
+		args = append(args, fmt.Sprintf("other-config:%s=%s", k, config[k]))
+	}
+	if spec.DSCP != nil {
+		args = append(args, fmt.Sprintf("dscp=%d", *spec.DSCP))
+	}
+	return args
+}
+
+// qosArgs 生成 QoS 列参数，queues 的值可以是 Queue UUID 或 @id 引用
+func qosArgs(spec QosSpec) ([]string, error) {
+	args := []string{fmt.Sprintf("type=%s", spec.Type)}
+	config := map[string]string{}
+	for k, v := range spec.OtherConfig {
+		config[k] = v
+	}
+	if spec.MaxRate != "" {
+		config["max-rate"] = spec.MaxRate
+	}
+	for _, k := range sortedKeys(config) {
+		args = append(args, fmt.Sprintf("other-config:%s=%s", k, config[k]))
+	}
+	for _, k := range sortedKeys(spec.ExternalIDs) {
+		args = append(args, fmt.Sprintf("external-ids:%s=%s", k, spec.ExternalIDs[k]))
+	}
+	for _, k := range sortedKeys(spec.Queues) {
+		if _, err := strconv.ParseUint(k, 10, 32); err != nil {
+			return nil, errorf(CauseInvalidArgument, "invalid queue number %q", k)
+		}
+		args = append(args, fmt.Sprintf("queues:%s=%s", k, spec.Queues[k]))
+	}
+	return args, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ListQueues 列出所有 Queue 及引用它们的 QoS
+func ListQueues(ctx context.Context) ([]QueueInfo, error) {
+	tables, err := qosTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usedBy := map[string][]string{}
+	for _, q := range tables["QoS"] {
+		for _, id := range qosQueueUUIDs(q) {
+			usedBy[id] = append(usedBy[id], q.UUID("_uuid"))
+		}
+	}
+	res := []QueueInfo{}
+	for _, row := range tables["Queue"] {
+		id := row.UUID("_uuid")
+		info := QueueInfo{QueueSnapshot: queueSnapshot(id, row), UsedBy: usedBy[id]}
+		if info.UsedBy == nil {
+			info.UsedBy = []string{}
+		}
+		sort.Strings(info.UsedBy)
+		res = append(res, info)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].UUID < res[j].UUID })
+	return res, nil
+}
+
+// CreateQueue 创建 Queue，返回新行 UUID
+func CreateQueue(ctx context.Context, spec QueueSpec) (string, error) {
+	args := append([]string{"create", "Queue", qosOwnerArg}, queueArgs(spec)...)
+	out, err := runOutput(ctx, "ovs-vsctl", args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// UpdateQueue 用 spec 整体替换 Queue 的配置
+func UpdateQueue(ctx context.Context, uuid string, spec QueueSpec) error {
+	if err := requireRow(ctx, "Queue", uuid); err != nil {
+		return err
+	}
+	args := []string{"--", "clear", "Queue", uuid, "other_config", "dscp"}
+	if cols := queueArgs(spec); len(cols) > 0 {
+		args = append(args, "--", "set", "Queue", uuid)
+		args = append(args, cols...)
+	}
+	return run(ctx, "ovs-vsctl", args...)
+}
+
+// DeleteQueue 删除 Queue，仍被 QoS 引用时拒绝删除
+func DeleteQueue(ctx context.Context, uuid string) error {
+	queues, err := ListQueues(ctx)
+	if err != nil {
+		return err
+	}
+	for _, q := range queues {
+		if q.UUID != uuid {
+			continue
+		}
+		if len(q.UsedBy) > 0 {
+			return errorf(CauseConflict, "queue %s is used by qos %s", uuid, strings.Join(q.UsedBy, ","))
+		}
+		return run(ctx, "ovs-vsctl", "destroy", "Queue", uuid)
+	}
+	return errorf(CauseNotFound, "no queue %s", uuid)
+}
+
+// ListQos 列出所有 QoS 及使用它们的端口
+func ListQos(ctx context.Context) ([]QosInfo, error) {
+	tables, err := qosTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ports := map[string][]string{}
+	for _, p := range tables["Port"] {
+		if id := p.UUID("qos"); id != "" {
+			ports[id] = append(ports[id], p.String("name"))
+		}
+	}
+	queues := indexByUUID(tables["Queue"])
+	res := []QosInfo{}
+	for _, q := range tables["QoS"] {
+		info := QosInfo{QoSSnapshot: *qosSnapshot(q, queues), Ports: ports[q.UUID("_uuid")]}
+		if info.Ports == nil {
+			info.Ports = []string{}
+		}
+		sort.Strings(info.Ports)
+		res = append(res, info)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].UUID < res[j].UUID })
+	return res, nil
+}
+
+// CreateQos 创建 QoS 并引用已有的 Queue，返回新行 UUID
+func CreateQos(ctx context.Context, spec QosSpec) (string, error) {
+	cols, err := qosArgs(spec)
+	if err != nil {
+		return "", err
+	}
+	out, err := runOutput(ctx, "ovs-vsctl", append([]string{"create", "QoS", qosOwnerArg}, cols...)...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// UpdateQos 用 spec 整体替换 QoS 的类型、other_config 和队列映射，使用它的端口立即生效
+func UpdateQos(ctx context.Context, uuid string, spec QosSpec) error {
+	if err := requireRow(ctx, "QoS", uuid); err != nil {
+		return err
+	}
+	cols, err := qosArgs(spec)
+	if err != nil {
+		return err
+	}
+	args := []string{"--", "clear", "QoS", uuid, "other_config", "queues", "--", "set", "QoS", uuid}
+	return run(ctx, "ovs-vsctl", append(args, cols...)...)
+}
+
+// DeleteQos 删除 QoS，仍被端口使用时拒绝删除；它引用的 Queue 保留，可通过 CleanupQos 回收
+func DeleteQos(ctx context.Context, uuid string) error {
+	list, err := ListQos(ctx)
+	if err != nil {
+		return err
+	}
+	for _, q := range list {
+		if q.UUID != uuid {
+			continue
+		}
+		if len(q.Ports) > 0 {
+			return errorf(CauseConflict, "qos %s is used by ports %s", uuid, strings.Join(q.Ports, ","))
+		}
+		return run(ctx, "ovs-vsctl", "destroy", "QoS", uuid)
+	}
+	return errorf(CauseNotFound, "no qos %s", uuid)
+}
+
+// AttachQos 将已有 QoS 应用到端口，同一个 QoS 可被多个端口共享
+func AttachQos(ctx context.Context, portName, uuid string) error {
+	if err := requireRow(ctx, "QoS", uuid); err != nil {
+		return err
+	}
+	return run(ctx, "ovs-vsctl", "set", "Port", portName, "qos="+uuid)
+}
+
+// DetachQos 移除端口上的 QoS，QoS 行本身保留
+func DetachQos(ctx context.Context, portName string) error {
+	return run(ctx, "ovs-vsctl", "clear", "Port", portName, "qos")
+}
+
+// CleanupQos 查找并删除未被任何端口使用的 QoS，以及未被任何剩余 QoS 引用的 Queue；默认只回收本服务创建的行，
+// all 为 true 时也回收其它程序创建的行；dryRun 时只返回将被删除的行
+func CleanupQos(ctx context.Context, dryRun, all bool) (*QosCleanupResult, error) {
+	tables, err := qosTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	used := map[string]bool{}
+	for _, p := range tables["Port"] {
+		if id := p.UUID("qos"); id != "" {
+			used[id] = true
+		}
+	}
+	owned := func(row ovsdb.Row) bool {
+		return all || row.StringMap("external_ids")[qosOwnerKey] == "true"
+	}
+	res := &QosCleanupResult{QoS: []string{}, Queues: []string{}}
+	usedQueues := map[string]bool{}
+	for _, q := range tables["QoS"] {
+		id := q.UUID("_uuid")
+		if !used[id] && owned(q) {
+			res.QoS = append(res.QoS, id)
+			continue
+		}
+		// 保留的 QoS（包括不归本服务管理的）引用的队列不能删除
+		for _, qid := range qosQueueUUIDs(q) {
+			usedQueues[qid] = true
+		}
+	}
+	for _, q := range tables["Queue"] {
+		if id := q.UUID("_uuid"); !usedQueues[id] && owned(q) {
+			res.Queues = append(res.Queues, id)
+		}
+	}
+	sort.Strings(res.QoS)
+	sort.Strings(res.Queues)
+	if dryRun || len(res.QoS)+len(res.Queues) == 0 {
+		return res, nil
+	}
+	var args []string
+	for _, id := range res.QoS {
+		args = append(args, "--", "destroy", "QoS", id)
+	}
+	for _, id := range res.Queues {
+		args = append(args, "--", "destroy", "Queue", id)
+	}
+	if err := run(ctx, "ovs-vsctl", args...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// requireRow 确认表中存在指定 UUID 的行
+func requireRow(ctx context.Context, table, uuid string) error {
+	tables, err := queryTables(ctx, tableQuery{Table: table, Columns: []string{"_uuid"}})
+	if err != nil {
+		return err
+	}
+	for _, row := range tables[table] {
+		if row.UUID("_uuid") == uuid {
+			return nil
+		}
+	}
+	return errorf(CauseNotFound, "no %s %s", table, uuid)
+}
+
+// replacePortQos 为端口新建 QoS（spec.Queues 被忽略）及队列（queueRates 的值为各队列 max-rate），并在同一个
+// ovs-vsctl 事务中删除端口原来的 QoS 及其队列（仅当它们不再被其它端口或 QoS 引用时），避免反复设置留下孤立行
+func replacePortQos(ctx context.Context, portName string, spec QosSpec, queueRates map[string]string) error {
+	tables, err := qosTables(ctx)
+	if err != nil {
+		return err
+	}
+	port, err := findQosPort(tables, portName)
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	spec.Queues = map[string]string{}
+	for i, k := range sortedKeys(queueRates) {
+		id := fmt.Sprintf("@q%d", i)
+		spec.Queues[k] = id
+		args = append(args, "--", "--id="+id, "create", "Queue", qosOwnerArg)
+		args = append(args, queueArgs(QueueSpec{MaxRate: queueRates[k]})...)
+	}
+	cols, err := qosArgs(spec)
+	if err != nil {
+		return err
+	}
+	args = append(args, "--", "--id=@newqos", "create", "QoS", qosOwnerArg)
+	args = append(args, cols...)
+	args = append(args, "--", "set", "Port", portName, "qos=@newqos")
+	args = append(args, releasePortQos(tables, port)...)
+	return run(ctx, "ovs-vsctl", args...)
+}
+
+// findQosPort 在 qosTables 结果中按名称查找端口
+func findQosPort(tables map[string][]ovsdb.Row, portName string) (ovsdb.Row, error) {
+	for _, p := range tables["Port"] {
+		if p.String("name") == portName {
+			return p, nil
+		}
+	}
+	return nil, errorf(CauseNotFound, "no port named %s", portName)
+}
+
+// releasePortQos 返回删除端口当前 QoS 及其队列的 ovs-vsctl 参数，QoS 被其它端口共享或队列被其它 QoS 引用时保留
+func releasePortQos(tables map[string][]ovsdb.Row, port ovsdb.Row) []string {
+	old := port.UUID("qos")
+	if old == "" {
+		return nil
+	}
+	for _, p := range tables["Port"] {
+		if p.UUID("qos") == old && p.UUID("_uuid") != port.UUID("_uuid") {
+			return nil
+		}
+	}
+	args := []string{"--", "destroy", "QoS", old}
+	queueRefs := map[string]int{}
+	for _, q := range tables["QoS"] {
+		for _, qid := range qosQueueUUIDs(q) {
+			queueRefs[qid]++
+		}
+	}
+	if q, ok := indexByUUID(tables["QoS"])[old]; ok {
+		for _, qid := range qosQueueUUIDs(q) {
+			if queueRefs[qid] == 1 {
+				args = append(args, "--", "destroy", "Queue", qid)
+			}
+		}
+	}
+	return args
+}
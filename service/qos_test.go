@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// expectQosTables 预设端口 eth1 使用 QoS 1；QoS 2 未被使用，QoS 3 未被使用且不是本服务创建的；
+// Queue 11-13 分别被 QoS 1-3 引用，Queue 14、15 未被引用，其中 14 不是本服务创建的
+func expectQosTables(fake *FakeRunner) {
+	owned := fixtureMap(qosOwnerKey, "true")
+	queues := func(n int) []interface{} {
+		return []interface{}{"map", []interface{}{[]interface{}{0, fixtureUUID(n)}}}
+	}
+	fake.Expect("ovs-vsctl --format=json --data=json -- --columns=_uuid,name,qos list Port -- list QoS -- list Queue",
+		vsctlList([]map[string]interface{}{
+			{"_uuid": fixtureUUID(100), "name": "eth1", "qos": fixtureUUID(1)},
+			{"_uuid": fixtureUUID(101), "name": "eth2", "qos": fixtureSet()},
+		}, []string{"_uuid", "name", "qos"})+
+			vsctlList([]map[string]interface{}{
+				{"_uuid": fixtureUUID(1), "type": "linux-htb", "queues": queues(11), "external_ids": owned},
+				{"_uuid": fixtureUUID(2), "type": "linux-htb", "queues": queues(12), "external_ids": owned},
+				{"_uuid": fixtureUUID(3), "type": "linux-htb", "queues": queues(13), "external_ids": fixtureMap()},
+			}, []string{"_uuid", "type", "queues", "external_ids"})+
+			vsctlList([]map[string]interface{}{
+				{"_uuid": fixtureUUID(11), "external_ids": owned},
+				{"_uuid": fixtureUUID(12), "external_ids": owned},
+				{"_uuid": fixtureUUID(13), "external_ids": owned},
+				{"_uuid": fixtureUUID(14), "external_ids": fixtureMap()},
+				{"_uuid": fixtureUUID(15), "external_ids": owned},
+			}, []string{"_uuid", "external_ids"}))
+}
+
+func TestCleanupQos(t *testing.T) {
+	uuid := func(n int) string { return fixtureUUID(n)[1].(string) }
+	tests := []struct {
+		name string
+		all  bool
+		want *QosCleanupResult
+	}{
+		{
+			name: "owned rows only",
+			want: &QosCleanupResult{QoS: []string{uuid(2)}, Queues: []string{uuid(12), uuid(15)}},
+		},
+		{
+			name: "all rows",
+			all:  true,
+			want: &QosCleanupResult{QoS: []string{uuid(2), uuid(3)}, Queues: []string{uuid(12), uuid(13), uuid(14), uuid(15)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeRunner()
+			expectQosTables(fake)
+			prev := SetRunner(fake)
+			defer SetRunner(prev)
+
+			res, err := CleanupQos(context.Background(), true, tt.all)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res, tt.want) {
+				t.Errorf("CleanupQos = %+v, want %+v", res, tt.want)
+			}
+			if calls := fake.Calls(); len(calls) != 1 {
+				t.Errorf("dry run executed %q", calls[1:])
+			}
+		})
+	}
+
+	fake := NewFakeRunner()
+	expectQosTables(fake)
+	fake.Expect("ovs-vsctl -- destroy QoS "+uuid(2)+" -- destroy Queue "+uuid(12)+" -- destroy Queue "+uuid(15), "")
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+	if _, err := CleanupQos(context.Background(), false, false); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runner 外部命令（ovs-vsctl、ovs-ofctl、ovs-appctl、ip 等）的执行抽象，
+// service 包中的所有命令都通过它执行，便于替换为脚本化的假实现或录制/回放实现
+type Runner interface {
+	// Run 执行命令并返回标准输出，ctx 取消或超时后应尽快返回
+	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+	// RunInput 执行命令，input 作为标准输入（如 ovs-ofctl add-flows br -）
+	RunInput(ctx context.Context, input []byte, name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner 直接调用系统命令
+type ExecRunner struct{}
+
+// Run 执行真实命令，失败时返回带标准错误输出的 *CommandError
+func (r ExecRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return r.RunInput(ctx, nil, name, args...)
+}
+
+// RunInput 执行真实命令并写入标准输入；ctx 带截止时间时 ovs-vsctl 额外加上 --timeout，
+// 使其在等待 ovsdb-server 时自行退出而不是被直接杀掉
+func (ExecRunner) RunInput(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	execArgs := args
+	if deadline, ok := ctx.Deadline(); ok && name == "ovs-vsctl" {
+		secs := int(math.Ceil(time.Until(deadline).Seconds()))
+		if secs < 1 {
+			secs = 1
+		}
+		execArgs = append([]string{fmt.Sprintf("--timeout=%d", secs)}, args...)
+	}
+	cmd := exec.CommandContext(ctx, name, execArgs...)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("%w (%v)", ctxErr, err)
+		}
+		return out, newCommandError(name, args, err, stderr.String())
+	}
+	return out, nil
+}
+
+var (
+	runnerMu sync.RWMutex
+	runner   Runner = ExecRunner{}
+)
+
+// SetRunner 替换全局命令执行器，返回之前的执行器以便恢复
+func SetRunner(r Runner) Runner {
+	runnerMu.Lock()
+	defer runnerMu.Unlock()
+	prev := runner
+	runner = r
+	return prev
+}
+
+func currentRunner() Runner {
+	runnerMu.RLock()
+	defer runnerMu.RUnlock()
+	return runner
+}
+
+// run 执行命令，只关心是否成功
+func run(ctx context.Context, name string, args ...string) error {
+	_, err := runOutput(ctx, name, args...)
+	return err
+}
+
+// runOutput 执行命令并返回标准输出，错误统一为 *CommandError
+func runOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return runInput(ctx, nil, name, args...)
+}
+
+// runInput 执行命令并通过标准输入传入数据；ctx 没有截止时间时使用默认超时，
+// 执行结果记录到 ctx 的命令日志中
+func runInput(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	ctx, cancel := WithDefaultTimeout(ctx)
+	defer cancel()
+	if name == "ovs-vsctl" {
+		args = vsctlArgs(args)
+	}
+	var (
+		out []byte
+		err error
+	)
+	if input == nil {
+		out, err = currentRunner().Run(ctx, name, args...)
+	} else {
+		out, err = currentRunner().RunInput(ctx, input, name, args...)
+	}
+	if err == nil && ctx.Err() != nil {
+		// 执行器未感知取消（如假实现），以 ctx 状态为准
+		err = ctx.Err()
+	}
+	err = wrapCommandError(name, args, err)
+	logCommand(ctx, commandLine(name, args), err)
+	observeCommand(name, err)
+	return out, err
+}
+
+// commandLine 拼接命令行，作为假实现和录制文件中的匹配键
+func commandLine(name string, args []string) string {
+	return strings.Join(append([]string{name}, args...), " ")
+}
+
+// FakeResponse 假执行器对某条命令的预设返回
+type FakeResponse struct {
+	Output string
+	Err    error
+}
+
+// FakeRunner 脚本化的假执行器：按完整命令行匹配预设输出，并记录所有调用
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses map[string][]FakeResponse
+	calls     []string
+	inputs    []string
+}
+
+// NewFakeRunner 创建假执行器
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{responses: make(map[string][]FakeResponse)}
+}
+
+// Expect 预设命令行的输出；同一命令多次预设时按顺序返回，最后一条会被重复使用
+func (f *FakeRunner) Expect(cmdline, output string) *FakeRunner {
+	return f.ExpectResponse(cmdline, FakeResponse{Output: output})
+}
+
+// ExpectError 预设命令行返回错误
+func (f *FakeRunner) ExpectError(cmdline string, err error) *FakeRunner {
+	return f.ExpectResponse(cmdline, FakeResponse{Err: err})
+}
+
+// ExpectResponse 预设命令行的完整返回
+func (f *FakeRunner) ExpectResponse(cmdline string, resp FakeResponse) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[cmdline] = append(f.responses[cmdline], resp)
+	return f
+}
+
+// Run 返回预设输出，未预设的命令返回错误
+func (f *FakeRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return f.RunInput(ctx, nil, name, args...)
+}
+
+// RunInput 同 Run，标准输入内容记录在 Inputs 中
+func (f *FakeRunner) RunInput(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	cmdline := commandLine(name, args)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, cmdline)
+	f.inputs = append(f.inputs, string(input))
+	queue := f.responses[cmdline]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("fake runner: unexpected command: %s", cmdline)
+	}
+	resp := queue[0]
+	if len(queue) > 1 {
+		f.responses[cmdline] = queue[1:]
+	}
+	return []byte(resp.Output), resp.Err
+}
+
+// Calls 返回已执行的命令行
+func (f *FakeRunner) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// Inputs 返回每次调用的标准输入，与 Calls 一一对应
+func (f *FakeRunner) Inputs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.inputs...)
+}
+
+// Recording 录制文件中的一条命令记录
+type Recording struct {
+	Command  string   `json:"command"`
+	Args     []string `json:"args"`
+	Stdin    string   `json:"stdin,omitempty"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exitCode,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// RecordingRunner 包装真实执行器，记录每次调用的命令与输出；
+// 指定 golden 文件路径时每条记录以 JSON Lines 格式实时追加写入，供 LoadReplayRunner 回放
+type RecordingRunner struct {
+	Inner Runner
+	Path  string
+
+	mu      sync.Mutex
+	records []Recording
+}
+
+// NewRecordingRunner 创建录制执行器，inner 为空时使用 ExecRunner，path 为空时只保存在内存中
+func NewRecordingRunner(inner Runner, path string) *RecordingRunner {
+	if inner == nil {
+		inner = ExecRunner{}
+	}
+	return &RecordingRunner{Inner: inner, Path: path}
+}
+
+// Run 执行并记录
+func (r *RecordingRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return r.record(nil, name, args, func() ([]byte, error) { return r.Inner.Run(ctx, name, args...) })
+}
+
+// RunInput 执行并记录，标准输入一并写入录制文件
+func (r *RecordingRunner) RunInput(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	return r.record(input, name, args, func() ([]byte, error) { return r.Inner.RunInput(ctx, input, name, args...) })
+}
+
+func (r *RecordingRunner) record(input []byte, name string, args []string, do func() ([]byte, error)) ([]byte, error) {
+	out, err := do()
+	rec := Recording{Command: name, Args: append([]string{}, args...), Stdin: string(input), Stdout: string(out)}
+	if err != nil {
+		rec.Error = err.Error()
+		var ce *CommandError
+		if errors.As(err, &ce) {
+			rec.Error = ce.Err.Error()
+			rec.Stderr = ce.Stderr
+			rec.ExitCode = ce.ExitCode
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, rec)
+	if r.Path != "" {
+		if werr := appendRecording(r.Path, rec); werr != nil {
+			return out, fmt.Errorf("record %s: %v", commandLine(name, args), werr)
+		}
+	}
+	return out, err
+}
+
+// Records 返回已录制的记录
+func (r *RecordingRunner) Records() []Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Recording(nil), r.records...)
+}
+
+// Save 将全部录制结果写入 golden 文件
+func (r *RecordingRunner) Save(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range r.Records() {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func appendRecording(path string, rec Recording) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(rec)
+}
+
+// LoadReplayRunner 读取 golden 文件并返回按记录回放的假执行器
+func LoadReplayRunner(path string) (*FakeRunner, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fake := NewFakeRunner()
+	dec := json.NewDecoder(f)
+	for {
+		var rec Recording
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid recording %s: %v", path, err)
+		}
+		resp := FakeResponse{Output: rec.Stdout}
+		if rec.Error != "" {
+			resp.Err = newCommandError(rec.Command, rec.Args, errors.New(rec.Error), rec.Stderr)
+			resp.Err.(*CommandError).ExitCode = rec.ExitCode
+		}
+		fake.ExpectResponse(commandLine(rec.Command, rec.Args), resp)
+	}
+	return fake, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// replay 加载 testdata 下的录制文件并替换全局执行器，测试结束后恢复
+func replay(t *testing.T, name string) *FakeRunner {
+	t.Helper()
+	fake, err := LoadReplayRunner(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := SetRunner(fake)
+	t.Cleanup(func() { SetRunner(prev) })
+	return fake
+}
+
+func TestFakeRunner(t *testing.T) {
+	fake := NewFakeRunner().
+		Expect("ovs-vsctl br-exists br0", "").
+		ExpectError("ovs-vsctl br-exists br0", errors.New("exit status 2")).
+		Expect("ovs-ofctl add-flows br0 -", "")
+	prev := SetRunner(fake)
+	defer SetRunner(prev)
+	ctx := context.Background()
+
+	if err := run(ctx, "ovs-vsctl", "br-exists", "br0"); err != nil {
+		t.Fatalf("first response: %v", err)
+	}
+	// 队列中最后一个响应会被重复使用
+	for i := 0; i < 2; i++ {
+		var ce *CommandError
+		if err := run(ctx, "ovs-vsctl", "br-exists", "br0"); !errors.As(err, &ce) {
+			t.Fatalf("call %d: expected CommandError, got %v", i, err)
+		}
+	}
+	if _, err := runInput(ctx, []byte("table=0,actions=drop\n"), "ovs-ofctl", "add-flows", "br0", "-"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(ctx, "ovs-vsctl", "del-br", "br0"); err == nil {
+		t.Error("unexpected command should fail")
+	}
+	if calls := fake.Calls(); len(calls) != 5 || calls[4] != "ovs-vsctl del-br br0" {
+		t.Errorf("Calls() = %q", calls)
+	}
+	if inputs := fake.Inputs(); len(inputs) != 5 || inputs[3] != "table=0,actions=drop\n" {
+		t.Errorf("Inputs() = %q", inputs)
+	}
+}
+
+func TestRecordingRunnerRoundTrip(t *testing.T) {
+	inner := NewFakeRunner().
+		Expect("ovs-vsctl get Bridge br0 sflow", "[]\n").
+		ExpectResponse("ovs-vsctl get Bridge nope sflow", FakeResponse{
+			Err: &CommandError{Command: "ovs-vsctl", ExitCode: 1, Stderr: "ovs-vsctl: no row \"nope\" in table Bridge", Err: errors.New("exit status 1")},
+		})
+	rec := NewRecordingRunner(inner, "")
+	prev := SetRunner(rec)
+	defer SetRunner(prev)
+	ctx := context.Background()
+	runOutput(ctx, "ovs-vsctl", "get", "Bridge", "br0", "sflow")
+	runOutput(ctx, "ovs-vsctl", "get", "Bridge", "nope", "sflow")
+
+	path := filepath.Join(t.TempDir(), "golden.jsonl")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	fake, err := LoadReplayRunner(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetRunner(fake)
+	if out, err := runOutput(ctx, "ovs-vsctl", "get", "Bridge", "br0", "sflow"); err != nil || string(out) != "[]\n" {
+		t.Errorf("replayed output = %q, %v", out, err)
+	}
+	_, err = runOutput(ctx, "ovs-vsctl", "get", "Bridge", "nope", "sflow")
+	var ce *CommandError
+	if !errors.As(err, &ce) || ce.ExitCode != 1 || CauseOf(err) != CauseNotFound {
+		t.Errorf("replayed error = %#v (cause %v)", err, CauseOf(err))
+	}
+}
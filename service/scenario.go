@@ -1,45 +1,46 @@
 package service
 
 import (
+	"context"
 	"fmt"
 )
 
 // ExecuteScenarioStep 统一调度场景步骤
 // 返回 error, output
-func ExecuteScenarioStep(action string, params map[string]interface{}) (error, interface{}) {
+func ExecuteScenarioStep(ctx context.Context, action string, params map[string]interface{}) (error, interface{}) {
 	switch action {
 	case "add_bridge":
 		name, _ := params["name"].(string)
-		return AddBridge(name), nil
+		return AddBridge(ctx, name), nil
 	case "delete_bridge":
 		name, _ := params["name"].(string)
-		return DeleteBridge(name), nil
+		return DeleteBridge(ctx, name), nil
 	case "add_port":
 		bridge, _ := params["bridge"].(string)
 		portName, _ := params["portName"].(string)
 		portType, _ := params["type"].(string)
-		return AddPort(bridge, portName, portType, ""), nil
+		return AddPort(ctx, bridge, portName, portType, ""), nil
 	case "delete_port":
 		bridge, _ := params["bridge"].(string)
 		portName, _ := params["portName"].(string)
-		return DeletePort(bridge, portName), nil
+		return DeletePort(ctx, bridge, portName), nil
 	case "set_port_vlan":
 		portName, _ := params["portName"].(string)
 		tag, _ := toInt(params["tag"])
-		return SetPortVlanTag(portName, tag), nil
+		return SetPortVlanTag(ctx, portName, tag), nil
 	case "set_port_vlan_mode":
 		portName, _ := params["portName"].(string)
 		vlanMode, _ := params["vlanMode"].(string)
-		return SetPortVlanMode(portName, vlanMode), nil
+		return SetPortVlanMode(ctx, portName, vlanMode), nil
 	case "set_port_trunks":
 		portName, _ := params["portName"].(string)
 		trunks, _ := toIntSlice(params["trunks"])
-		return SetPortTrunks(portName, trunks), nil
+		return SetPortTrunks(ctx, portName, trunks), nil
 	case "add_patch_port":
 		bridge, _ := params["bridge"].(string)
 		portName, _ := params["portName"].(string)
 		peer, _ := params["peer"].(string)
-		return AddPatchPort(bridge, portName, peer), nil
+		return AddPatchPort(ctx, bridge, portName, peer), nil
 	case "add_bond":
 		bridge, _ := params["bridge"].(string)
 		bondName, _ := params["bondName"].(string)
@@ -47,37 +48,47 @@ func ExecuteScenarioStep(action string, params map[string]interface{}) (error, i
 		bondMode, _ := params["bondMode"].(string)
 		lacp, _ := params["lacp"].(string)
 		otherOptions, _ := toStringMap(params["otherOptions"])
-		return AddBond(bridge, bondName, slaves, bondMode, lacp, otherOptions), nil
+		return AddBond(ctx, bridge, bondName, slaves, bondMode, lacp, otherOptions), nil
 	case "set_bfd":
 		portName, _ := params["portName"].(string)
 		bfd, _ := toStringMap(params["bfd"])
-		return SetBfd(portName, bfd), nil
+		return SetBfd(ctx, portName, bfd), nil
 	case "set_cfm":
 		portName, _ := params["portName"].(string)
 		cfm, _ := toStringMap(params["cfm"])
-		return SetCfm(portName, cfm), nil
+		return SetCfm(ctx, portName, cfm), nil
 	case "set_qos":
 		portName, _ := params["portName"].(string)
 		typeStr, _ := params["type"].(string)
 		maxRate, _ := params["maxRate"].(string)
 		queues, _ := toStringMap(params["queues"])
-		return SetQos(portName, typeStr, maxRate, queues), nil
+		return SetQos(ctx, portName, typeStr, maxRate, queues), nil
 	case "set_hfsc_qos":
 		portName, _ := params["portName"].(string)
 		maxRate, _ := params["maxRate"].(string)
 		queues, _ := toStringMap(params["queues"])
-		return SetHfscQos(portName, maxRate, queues), nil
+		return SetHfscQos(ctx, portName, maxRate, queues), nil
+	case "set_impairment":
+		portName, _ := params["portName"].(string)
+		im, err := impairmentFromParams(params)
+		if err != nil {
+			return err, nil
+		}
+		return SetPortImpairment(ctx, portName, im), nil
+	case "clear_impairment":
+		portName, _ := params["portName"].(string)
+		return ClearPortImpairment(ctx, portName), nil
 	case "add_tunnel_port":
 		bridge, _ := params["bridge"].(string)
 		portName, _ := params["portName"].(string)
 		typeStr, _ := params["type"].(string)
 		options, _ := toStringMap(params["options"])
-		return AddTunnelPort(bridge, portName, typeStr, options), nil
+		return AddTunnelPort(ctx, bridge, portName, typeStr, options), nil
 	case "set_netflow":
 		bridge, _ := params["bridge"].(string)
 		target, _ := params["target"].(string)
 		engineID, _ := toInt(params["engineID"])
-		return SetNetFlow(bridge, target, engineID), nil
+		return SetNetFlow(ctx, bridge, target, engineID), nil
 	case "set_sflow":
 		bridge, _ := params["bridge"].(string)
 		targets, _ := toStringSlice(params["targets"])
@@ -85,30 +96,30 @@ func ExecuteScenarioStep(action string, params map[string]interface{}) (error, i
 		header, _ := toInt(params["header"])
 		polling, _ := toInt(params["polling"])
 		agent, _ := params["agent"].(string)
-		return SetSFlow(bridge, targets, sampling, header, polling, agent), nil
+		return SetSFlow(ctx, bridge, targets, sampling, header, polling, agent), nil
 	case "set_stp":
 		bridge, _ := params["bridge"].(string)
 		enable, _ := params["enable"].(bool)
-		return SetStp(bridge, enable), nil
+		return SetStp(ctx, bridge, enable), nil
 	case "set_rstp":
 		bridge, _ := params["bridge"].(string)
 		enable, _ := params["enable"].(bool)
-		return SetRstp(bridge, enable), nil
+		return SetRstp(ctx, bridge, enable), nil
 	case "set_ipfix":
 		bridge, _ := params["bridge"].(string)
 		targets, _ := toStringSlice(params["targets"])
 		sampling, _ := toInt(params["sampling"])
 		obsDomainID, _ := toInt(params["obsDomainID"])
 		obsPointID, _ := toInt(params["obsPointID"])
-		return SetIpfix(bridge, targets, sampling, obsDomainID, obsPointID), nil
+		return SetIpfix(ctx, bridge, targets, sampling, obsDomainID, obsPointID), nil
 	case "set_mcast_snooping":
 		bridge, _ := params["bridge"].(string)
 		enable, _ := params["enable"].(bool)
-		return SetMcastSnooping(bridge, enable), nil
+		return SetMcastSnooping(ctx, bridge, enable), nil
 	case "set_datapath_type":
 		bridge, _ := params["bridge"].(string)
 		datapathType, _ := params["datapathType"].(string)
-		return SetDatapathType(bridge, datapathType), nil
+		return SetDatapathType(ctx, bridge, datapathType), nil
 	case "add_mirror":
 		bridge, _ := params["bridge"].(string)
 		name, _ := params["name"].(string)
@@ -126,27 +137,69 @@ func ExecuteScenarioStep(action string, params map[string]interface{}) (error, i
 			outputVlan = &vint
 		}
 		selectAll, _ := params["selectAll"].(bool)
-		return AddMirror(bridge, name, srcPorts, dstPorts, selectVlan, outputPort, outputVlan, selectAll), nil
+		return AddMirror(ctx, bridge, name, srcPorts, dstPorts, selectVlan, outputPort, outputVlan, selectAll), nil
+	case "update_mirror":
+		bridge, _ := params["bridge"].(string)
+		name, _ := params["name"].(string)
+		var update MirrorUpdate
+		if v, ok := params["selectAll"].(bool); ok {
+			update.SelectAll = &v
+		}
+		if v, ok := toStringSlice(params["selectSrcPorts"]); ok {
+			update.SelectSrcPorts = &v
+		}
+		if v, ok := toStringSlice(params["selectDstPorts"]); ok {
+			update.SelectDstPorts = &v
+		}
+		if v, ok := toIntSlice(params["selectVlan"]); ok {
+			update.SelectVlan = &v
+		}
+		if v, ok := params["outputPort"].(string); ok {
+			update.OutputPort = &v
+		}
+		if v, ok := params["outputVlan"]; ok {
+			vint, _ := toInt(v)
+			update.OutputVlan = &vint
+		}
+		return UpdateMirror(ctx, bridge, name, update), nil
 	case "delete_mirror":
 		bridge, _ := params["bridge"].(string)
 		name, _ := params["name"].(string)
-		return DeleteMirror(bridge, name), nil
+		return DeleteMirror(ctx, bridge, name), nil
 	case "add_flow":
 		bridge, _ := params["bridge"].(string)
 		flow, _ := params["flow"].(string)
-		return AddFlowV2(bridge, flow), nil
+		return AddFlowV2(ctx, bridge, flow), nil
+	case "replace_flows":
+		bridge, _ := params["bridge"].(string)
+		flows, _ := toStringSlice(params["flows"])
+		var opts FlowSyncOptions
+		if v, ok := params["cookie"]; ok {
+			// JSON 数字解码为 float64，超过 2^53 的 cookie 会丢失精度，因此只接受字符串
+			str, isString := v.(string)
+			if !isString {
+				return errorf(CauseInvalidArgument, "cookie must be a string such as \"0x6f76736d00000000\", got %v", v), nil
+			}
+			cookie, err := parseCookie(str)
+			if err != nil {
+				return errorf(CauseInvalidArgument, "invalid cookie: %q", str), nil
+			}
+			opts.Cookie = &cookie
+		}
+		diff, err := ReplaceFlows(ctx, bridge, flows, opts)
+		return err, diff
 	case "delete_flow":
 		bridge, _ := params["bridge"].(string)
 		match, _ := params["match"].(string)
-		return DeleteFlowV2(bridge, match), nil
+		return DeleteFlowV2(ctx, bridge, match), nil
 	case "create_netns":
 		name, _ := params["name"].(string)
-		return CreateNetns(name), nil
+		return CreateNetns(ctx, name), nil
 	case "delete_netns":
 		name, _ := params["name"].(string)
-		return DeleteNetns(name), nil
+		return DeleteNetns(ctx, name), nil
 	default:
-		return fmt.Errorf("unsupported action: %s", action), nil
+		return errorf(CauseInvalidArgument, "unsupported action: %s", action), nil
 	}
 }
 
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"ovs-manager/ovsdb"
+)
+
+// OVSSnapshot 整个 Open_vSwitch 实例的结构化快照，对应 ovs-vsctl show 的全部信息
+type OVSSnapshot struct {
+	UUID          string           `json:"uuid"`
+	OvsVersion    string           `json:"ovsVersion"`
+	DBVersion     string           `json:"dbVersion"`
+	SystemType    string           `json:"systemType"`
+	SystemVersion string           `json:"systemVersion"`
+	DatapathTypes []string         `json:"datapathTypes"`
+	IfaceTypes    []string         `json:"ifaceTypes"`
+	Bridges       []BridgeSnapshot `json:"bridges"`
+}
+
+// BridgeSnapshot 网桥快照
+type BridgeSnapshot struct {
+	UUID          string               `json:"uuid"`
+	Name          string               `json:"name"`
+	FailMode      string               `json:"failMode"`
+	DatapathType  string               `json:"datapathType"`
+	DatapathID    string               `json:"datapathId"`
+	Protocols     []string             `json:"protocols"`
+	StpEnable     bool                 `json:"stpEnable"`
+	RstpEnable    bool                 `json:"rstpEnable"`
+	McastSnooping bool                 `json:"mcastSnooping"`
+	Controllers   []ControllerSnapshot `json:"controllers"`
+	Ports         []PortSnapshot       `json:"ports"`
+	Mirrors       []MirrorSnapshot     `json:"mirrors"`
+	NetFlow       *NetFlowSnapshot     `json:"netflow,omitempty"`
+	SFlow         *SFlowSnapshot       `json:"sflow,omitempty"`
+	IPFIX         *IPFIXSnapshot       `json:"ipfix,omitempty"`
+}
+
+// ControllerSnapshot OpenFlow 控制器
+type ControllerSnapshot struct {
+	Target      string `json:"target"`
+	IsConnected bool   `json:"isConnected"`
+	Role        string `json:"role"`
+}
+
+// PortSnapshot 端口快照，包含 VLAN、Bond、QoS 配置和所属接口
+type PortSnapshot struct {
+	UUID       string              `json:"uuid"`
+	Name       string              `json:"name"`
+	Tag        *int                `json:"tag,omitempty"`
+	Trunks     []int               `json:"trunks"`
+	VlanMode   string              `json:"vlanMode"`
+	Bond       bool                `json:"bond"`
+	BondMode   string              `json:"bondMode,omitempty"`
+	Lacp       string              `json:"lacp,omitempty"`
+	QoS        *QoSSnapshot        `json:"qos,omitempty"`
+	Interfaces []InterfaceSnapshot `json:"interfaces"`
+}
+
+// InterfaceSnapshot 接口快照
+type InterfaceSnapshot struct {
+	UUID       string            `json:"uuid"`
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Options    map[string]string `json:"options"`
+	AdminState string            `json:"adminState"`
+	LinkState  string            `json:"linkState"`
+	OFPort     *int              `json:"ofport,omitempty"`
+	MacInUse   string            `json:"macInUse"`
+	MTU        *int              `json:"mtu,omitempty"`
+	LinkSpeed  *int              `json:"linkSpeed,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// QoSSnapshot QoS 配置
+type QoSSnapshot struct {
+	UUID        string                   `json:"uuid"`
+	Type        string                   `json:"type"`
+	OtherConfig map[string]string        `json:"otherConfig"`
+	Queues      map[string]QueueSnapshot `json:"queues"`
+}
+
+// QueueSnapshot 队列配置，MinRate/MaxRate/Burst/Priority 取自 other_config
+type QueueSnapshot struct {
+	UUID        string            `json:"uuid"`
+	MinRate     string            `json:"minRate,omitempty"`
+	MaxRate     string            `json:"maxRate,omitempty"`
+	Burst       string            `json:"burst,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	DSCP        *int              `json:"dscp,omitempty"`
+	OtherConfig map[string]string `json:"otherConfig"`
+}
+
+// MirrorSnapshot 端口镜像
+type MirrorSnapshot struct {
+	UUID           string           `json:"uuid"`
+	Name           string           `json:"name"`
+	SelectAll      bool             `json:"selectAll"`
+	SelectSrcPorts []string         `json:"selectSrcPorts"`
+	SelectDstPorts []string         `json:"selectDstPorts"`
+	SelectVlan     []int            `json:"selectVlan"`
+	OutputPort     string           `json:"outputPort,omitempty"`
+	OutputVlan     *int             `json:"outputVlan,omitempty"`
+	Statistics     map[string]int64 `json:"statistics"`
+}
+
+// NetFlowSnapshot NetFlow 导出配置
+type NetFlowSnapshot struct {
+	UUID          string   `json:"uuid"`
+	Targets       []string `json:"targets"`
+	EngineType    *int     `json:"engineType,omitempty"`
+	EngineID      *int     `json:"engineId,omitempty"`
+	ActiveTimeout int      `json:"activeTimeout"`
+}
+
+// SFlowSnapshot sFlow 导出配置
+type SFlowSnapshot struct {
+	UUID     string   `json:"uuid"`
+	Targets  []string `json:"targets"`
+	Sampling *int     `json:"sampling,omitempty"`
+	Header   *int     `json:"header,omitempty"`
+	Polling  *int     `json:"polling,omitempty"`
+	Agent    string   `json:"agent,omitempty"`
+}
+
+// IPFIXSnapshot IPFIX 导出配置
+type IPFIXSnapshot struct {
+	UUID        string   `json:"uuid"`
+	Targets     []string `json:"targets"`
+	Sampling    *int     `json:"sampling,omitempty"`
+	ObsDomainID *int     `json:"obsDomainId,omitempty"`
+	ObsPointID  *int     `json:"obsPointId,omitempty"`
+}
+
+// showTables ShowOVS 需要查询的表
+var showTables = []string{
+	"Open_vSwitch", "Bridge", "Controller", "Port", "Interface",
+	"Mirror", "QoS", "Queue", "NetFlow", "sFlow", "IPFIX",
+}
+
+// ShowOVS 一次查询返回整个 Open_vSwitch 实例的结构化快照
+func ShowOVS(ctx context.Context) (*OVSSnapshot, error) {
+	tables, err := selectTables(ctx, showTables...)
+	if err != nil {
+		return nil, err
+	}
+	snap := &OVSSnapshot{Bridges: []BridgeSnapshot{}}
+	for _, row := range tables["Open_vSwitch"] {
+		snap.UUID = row.UUID("_uuid")
+		snap.OvsVersion = row.OptionalString("ovs_version")
+		snap.DBVersion = row.OptionalString("db_version")
+		snap.SystemType = row.OptionalString("system_type")
+		snap.SystemVersion = row.OptionalString("system_version")
+		snap.DatapathTypes = row.Strings("datapath_types")
+		snap.IfaceTypes = row.Strings("iface_types")
+	}
+
+	controllers := indexByUUID(tables["Controller"])
+	ports := indexByUUID(tables["Port"])
+	ifaces := indexByUUID(tables["Interface"])
+	mirrors := indexByUUID(tables["Mirror"])
+	qoses := indexByUUID(tables["QoS"])
+	queues := indexByUUID(tables["Queue"])
+	netflows := indexByUUID(tables["NetFlow"])
+	sflows := indexByUUID(tables["sFlow"])
+	ipfixes := indexByUUID(tables["IPFIX"])
+	portNames := make(map[string]string, len(ports))
+	for uuid, p := range ports {
+		portNames[uuid] = p.String("name")
+	}
+
+	for _, br := range tables["Bridge"] {
+		b := BridgeSnapshot{
+			UUID:          br.UUID("_uuid"),
+			Name:          br.String("name"),
+			FailMode:      br.OptionalString("fail_mode"),
+			DatapathType:  br.String("datapath_type"),
+			DatapathID:    br.OptionalString("datapath_id"),
+			Protocols:     br.Strings("protocols"),
+			StpEnable:     br.Bool("stp_enable"),
+			RstpEnable:    br.Bool("rstp_enable"),
+			McastSnooping: br.Bool("mcast_snooping_enable"),
+			Controllers:   []ControllerSnapshot{},
+			Ports:         []PortSnapshot{},
+			Mirrors:       []MirrorSnapshot{},
+		}
+		for _, id := range br.UUIDs("controller") {
+			if c, ok := controllers[id]; ok {
+				b.Controllers = append(b.Controllers, ControllerSnapshot{
+					Target:      c.String("target"),
+					IsConnected: c.Bool("is_connected"),
+					Role:        c.OptionalString("role"),
+				})
+			}
+		}
+		for _, id := range br.UUIDs("ports") {
+			if p, ok := ports[id]; ok {
+				b.Ports = append(b.Ports, portSnapshot(p, ifaces, qoses, queues))
+			}
+		}
+		sort.Slice(b.Ports, func(i, j int) bool { return b.Ports[i].Name < b.Ports[j].Name })
+		for _, id := range br.UUIDs("mirrors") {
+			if m, ok := mirrors[id]; ok {
+				b.Mirrors = append(b.Mirrors, mirrorSnapshot(m, portNames))
+			}
+		}
+		if nf, ok := netflows[br.UUID("netflow")]; ok {
+			b.NetFlow = &NetFlowSnapshot{
+				UUID:          nf.UUID("_uuid"),
+				Targets:       nf.Strings("targets"),
+				EngineType:    nf.OptionalInt("engine_type"),
+				EngineID:      nf.OptionalInt("engine_id"),
+				ActiveTimeout: nf.Int("active_timeout"),
+			}
+		}
+		if sf, ok := sflows[br.UUID("sflow")]; ok {
+			b.SFlow = &SFlowSnapshot{
+				UUID:     sf.UUID("_uuid"),
+				Targets:  sf.Strings("targets"),
+				Sampling: sf.OptionalInt("sampling"),
+				Header:   sf.OptionalInt("header"),
+				Polling:  sf.OptionalInt("polling"),
+				Agent:    sf.OptionalString("agent"),
+			}
+		}
+		if ipf, ok := ipfixes[br.UUID("ipfix")]; ok {
+			b.IPFIX = &IPFIXSnapshot{
+				UUID:        ipf.UUID("_uuid"),
+				Targets:     ipf.Strings("targets"),
+				Sampling:    ipf.OptionalInt("sampling"),
+				ObsDomainID: ipf.OptionalInt("obs_domain_id"),
+				ObsPointID:  ipf.OptionalInt("obs_point_id"),
+			}
+		}
+		snap.Bridges = append(snap.Bridges, b)
+	}
+	sort.Slice(snap.Bridges, func(i, j int) bool { return snap.Bridges[i].Name < snap.Bridges[j].Name })
+	return snap, nil
+}
+
+// portSnapshot 由 Port 行及其引用的 Interface/QoS/Queue 行构造端口快照
+func portSnapshot(p ovsdb.Row, ifaces, qoses, queues map[string]ovsdb.Row) PortSnapshot {
+	ps := PortSnapshot{
+		UUID:       p.UUID("_uuid"),
+		Name:       p.String("name"),
+		Tag:        p.OptionalInt("tag"),
+		Trunks:     p.Ints("trunks"),
+		VlanMode:   p.OptionalString("vlan_mode"),
+		BondMode:   p.OptionalString("bond_mode"),
+		Lacp:       p.OptionalString("lacp"),
+		Interfaces: []InterfaceSnapshot{},
+	}
+	ifaceIDs := p.UUIDs("interfaces")
+	ps.Bond = len(ifaceIDs) > 1
+	for _, id := range ifaceIDs {
+		if i, ok := ifaces[id]; ok {
+			ps.Interfaces = append(ps.Interfaces, interfaceSnapshot(i))
+		}
+	}
+	sort.Slice(ps.Interfaces, func(a, b int) bool { return ps.Interfaces[a].Name < ps.Interfaces[b].Name })
+	if q, ok := qoses[p.UUID("qos")]; ok {
+		ps.QoS = qosSnapshot(q, queues)
+	}
+	return ps
+}
+
+func interfaceSnapshot(i ovsdb.Row) InterfaceSnapshot {
+	return InterfaceSnapshot{
+		UUID:       i.UUID("_uuid"),
+		Name:       i.String("name"),
+		Type:       i.String("type"),
+		Options:    i.StringMap("options"),
+		AdminState: i.OptionalString("admin_state"),
+		LinkState:  i.OptionalString("link_state"),
+		OFPort:     i.OptionalInt("ofport"),
+		MacInUse:   i.OptionalString("mac_in_use"),
+		MTU:        i.OptionalInt("mtu"),
+		LinkSpeed:  i.OptionalInt("link_speed"),
+		Error:      i.OptionalString("error"),
+	}
+}
+
+func qosSnapshot(q ovsdb.Row, queues map[string]ovsdb.Row) *QoSSnapshot {
+	qs := &QoSSnapshot{
+		UUID:        q.UUID("_uuid"),
+		Type:        q.String("type"),
+		OtherConfig: q.StringMap("other_config"),
+		Queues:      map[string]QueueSnapshot{},
+	}
+	if m, ok := q["queues"].(ovsdb.OvsMap); ok {
+		for k, v := range m.GoMap {
+			u, _ := v.(ovsdb.UUID)
+			qs.Queues[queueKey(k)] = queueSnapshot(u.GoUUID, queues[u.GoUUID])
+		}
+	}
+	return qs
+}
+
+// queueSnapshot 由 Queue 行构造快照，row 为空（引用的行不存在）时只保留 UUID
+func queueSnapshot(uuid string, row ovsdb.Row) QueueSnapshot {
+	queue := QueueSnapshot{UUID: uuid, OtherConfig: map[string]string{}}
+	if row != nil {
+		queue.DSCP = row.OptionalInt("dscp")
+		queue.OtherConfig = row.StringMap("other_config")
+		queue.MinRate = queue.OtherConfig["min-rate"]
+		queue.MaxRate = queue.OtherConfig["max-rate"]
+		queue.Burst = queue.OtherConfig["burst"]
+		queue.Priority = queue.OtherConfig["priority"]
+	}
+	return queue
+}
+
+// queueKey 队列号在 JSON 中解码为 float64，转换为整数字符串
+func queueKey(k interface{}) string {
+	if f, ok := k.(float64); ok {
+		return strconv.Itoa(int(f))
+	}
+	s, _ := k.(string)
+	return s
+}
+
+func mirrorSnapshot(m ovsdb.Row, portNames map[string]string) MirrorSnapshot {
+	names := func(ids []string) []string {
+		res := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if n, ok := portNames[id]; ok {
+				res = append(res, n)
+			}
+		}
+		sort.Strings(res)
+		return res
+	}
+	return MirrorSnapshot{
+		UUID:           m.UUID("_uuid"),
+		Name:           m.String("name"),
+		SelectAll:      m.Bool("select_all"),
+		SelectSrcPorts: names(m.UUIDs("select_src_port")),
+		SelectDstPorts: names(m.UUIDs("select_dst_port")),
+		SelectVlan:     m.Ints("select_vlan"),
+		OutputPort:     portNames[m.UUID("output_port")],
+		OutputVlan:     m.OptionalInt("output_vlan"),
+		Statistics:     m.IntMap("statistics"),
+	}
+}
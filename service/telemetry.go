@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"ovs-manager/telemetry"
+)
+
+var (
+	collectorMu sync.RWMutex
+	collector   *telemetry.Collector
+)
+
+// SetCollector 设置内嵌流量收集器，nil 表示关闭；返回之前的收集器
+func SetCollector(c *telemetry.Collector) *telemetry.Collector {
+	collectorMu.Lock()
+	defer collectorMu.Unlock()
+	old := collector
+	collector = c
+	return old
+}
+
+// currentCollector 返回当前收集器，未启用时返回 not-found 错误
+func currentCollector() (*telemetry.Collector, error) {
+	collectorMu.RLock()
+	defer collectorMu.RUnlock()
+	if collector == nil {
+		return nil, errorf(CauseNotFound, "telemetry collector is not enabled (start with -collector)")
+	}
+	return collector, nil
+}
+
+// StartCollector 创建收集器并在每个 UDP 地址上接收 sFlow/NetFlow/IPFIX 报文
+func StartCollector(addrs []string, window time.Duration) (*telemetry.Collector, error) {
+	c := telemetry.NewCollector(window)
+	for _, addr := range addrs {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		go func(addr string) {
+			if err := c.Serve(conn); err != nil {
+				log.Printf("telemetry collector on %s stopped: %v", addr, err)
+			}
+		}(addr)
+	}
+	SetCollector(c)
+	return c, nil
+}
+
+// TelemetryPortTraffic 按端口统计的流量，附带解析出的接口名
+type TelemetryPortTraffic struct {
+	telemetry.PortTraffic
+	Name string `json:"name,omitempty"`
+}
+
+// TelemetryStats 返回收集器计数
+func TelemetryStats(ctx context.Context) (*telemetry.Stats, error) {
+	c, err := currentCollector()
+	if err != nil {
+		return nil, err
+	}
+	stats := c.Stats()
+	return &stats, nil
+}
+
+// TopTalkers 返回最近窗口内按字节或报文数排序的 top talkers
+func TopTalkers(ctx context.Context, q telemetry.Query) ([]telemetry.Talker, error) {
+	c, err := currentCollector()
+	if err != nil {
+		return nil, err
+	}
+	if !telemetry.ValidGroupBy(q.GroupBy) {
+		return nil, errorf(CauseInvalidArgument, "invalid groupBy %q", q.GroupBy)
+	}
+	return c.TopTalkers(q), nil
+}
+
+// TrafficByPort 返回最近窗口内按端口统计的流量，sFlow 的 ifindex 和 NetFlow/IPFIX 的 OpenFlow 端口号
+// 都通过 Interface 表解析为接口名；查询 OVSDB 失败时只返回端口号
+func TrafficByPort(ctx context.Context, q telemetry.Query) ([]TelemetryPortTraffic, error) {
+	c, err := currentCollector()
+	if err != nil {
+		return nil, err
+	}
+	ports := c.PortBreakdown(q)
+	byOFPort, byIfIndex := map[uint32]string{}, map[uint32]string{}
+	if tables, err := queryTables(ctx, tableQuery{Table: "Interface", Columns: []string{"name", "ofport", "ifindex"}}); err == nil {
+		for _, iface := range tables["Interface"] {
+			if p := iface.OptionalInt("ofport"); p != nil && *p > 0 {
+				byOFPort[uint32(*p)] = iface.String("name")
+			}
+			if idx := iface.OptionalInt("ifindex"); idx != nil && *idx > 0 {
+				byIfIndex[uint32(*idx)] = iface.String("name")
+			}
+		}
+	}
+	res := make([]TelemetryPortTraffic, 0, len(ports))
+	for _, p := range ports {
+		t := TelemetryPortTraffic{PortTraffic: p, Name: byOFPort[p.Port]}
+		if p.IfIndex {
+			t.Name = byIfIndex[p.Port]
+		}
+		res = append(res, t)
+	}
+	return res, nil
+}
+
+// TrafficByVlan 返回最近窗口内按 VLAN 统计的流量
+func TrafficByVlan(ctx context.Context, q telemetry.Query) ([]telemetry.VlanTraffic, error) {
+	c, err := currentCollector()
+	if err != nil {
+		return nil, err
+	}
+	return c.VlanBreakdown(q), nil
+}
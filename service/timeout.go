@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultTimeout 未设置截止时间的调用使用的默认超时，可通过 SetDefaultTimeout 修改
+var (
+	timeoutMu      sync.RWMutex
+	defaultTimeout = 30 * time.Second
+)
+
+// SetDefaultTimeout 设置默认超时，d <= 0 表示不限制
+func SetDefaultTimeout(d time.Duration) {
+	timeoutMu.Lock()
+	defer timeoutMu.Unlock()
+	defaultTimeout = d
+}
+
+// DefaultTimeout 返回当前默认超时
+func DefaultTimeout() time.Duration {
+	timeoutMu.RLock()
+	defer timeoutMu.RUnlock()
+	return defaultTimeout
+}
+
+// WithDefaultTimeout ctx 没有截止时间时附加默认超时
+func WithDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	d := DefaultTimeout()
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+type commandLogKey struct{}
+
+// CommandLog 记录一次请求中已执行的外部命令，超时时用于指出执行到哪一步
+type CommandLog struct {
+	mu        sync.Mutex
+	completed []string
+	failed    string
+}
+
+// WithCommandLog 在 ctx 中附加命令日志
+func WithCommandLog(ctx context.Context) (context.Context, *CommandLog) {
+	log := &CommandLog{}
+	return context.WithValue(ctx, commandLogKey{}, log), log
+}
+
+// Completed 返回已成功执行的命令
+func (l *CommandLog) Completed() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.completed...)
+}
+
+// Failed 返回最后一条失败的命令
+func (l *CommandLog) Failed() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.failed
+}
+
+// logCommand 将命令执行结果写入 ctx 中的命令日志（如有）
+func logCommand(ctx context.Context, cmdline string, err error) {
+	log, ok := ctx.Value(commandLogKey{}).(*CommandLog)
+	if !ok {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if err != nil {
+		log.failed = cmdline
+		return
+	}
+	log.completed = append(log.completed, cmdline)
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TracePacket 报文跟踪参数：fields 为 ovs-ofctl 匹配语法的报文字段（如 tcp,nw_dst=10.0.0.2,tp_dst=80），
+// packet 为十六进制的完整以太网帧，二者互斥
+type TracePacket struct {
+	InPort string `json:"inPort"`
+	Fields string `json:"fields"`
+	Packet string `json:"packet"`
+}
+
+// TraceStep 报文经过的一张流表：命中的流表项及执行的动作
+type TraceStep struct {
+	Bridge   string      `json:"bridge"`
+	Table    int         `json:"table"`
+	Miss     bool        `json:"miss"` // 没有命中任何流表项
+	Match    string      `json:"match"`
+	Priority *int        `json:"priority,omitempty"`
+	Cookie   *FlowCookie `json:"cookie,omitempty"`
+	Actions  []string    `json:"actions"`
+	Notes    []string    `json:"notes,omitempty"` // "->" 说明及 ">>" 告警
+}
+
+// TraceHop 跟踪经过 patch 端口进入另一个网桥
+type TraceHop struct {
+	FromBridge string `json:"fromBridge"`
+	FromPort   string `json:"fromPort,omitempty"`
+	ToBridge   string `json:"toBridge"`
+	ToPort     string `json:"toPort,omitempty"`
+}
+
+// FlowTrace ofproto/trace 的结构化结果
+type FlowTrace struct {
+	Flow            string      `json:"flow"`
+	Steps           []TraceStep `json:"steps"`
+	Hops            []TraceHop  `json:"hops"`
+	FinalFlow       string      `json:"finalFlow,omitempty"`
+	Megaflow        string      `json:"megaflow,omitempty"`
+	DatapathActions string      `json:"datapathActions"`
+	Warnings        []string    `json:"warnings,omitempty"`
+	Dropped         bool        `json:"dropped"`
+	DropReason      string      `json:"dropReason,omitempty"`
+	Raw             string      `json:"raw"`
+}
+
+// traceFlow 校验跟踪参数并生成 ofproto/trace 的流和报文参数，in_port 为端口名时换算为 ofport
+func (r TracePacket) traceFlow(ports map[string]int) (string, string, error) {
+	inPort := r.InPort
+	if msg := checkPortRef(inPort, ports); msg != "" {
+		return "", "", errorf(CauseInvalidArgument, "inPort: %s", msg)
+	}
+	if ofport, ok := ports[inPort]; ok {
+		if ofport < 0 {
+			return "", "", errorf(CauseInvalidArgument, "inPort: port %s has no ofport assigned", inPort)
+		}
+		inPort = strconv.Itoa(ofport)
+	}
+	fields := strings.Trim(strings.TrimSpace(r.Fields), ",")
+	packet := strings.Join(strings.Fields(r.Packet), "")
+	if fields != "" && packet != "" {
+		return "", "", errorf(CauseInvalidArgument, "fields and packet are mutually exclusive")
+	}
+	for _, tok := range splitTopLevel(fields) {
+		if key, _, _ := strings.Cut(tok, "="); key == "in_port" {
+			return "", "", errorf(CauseInvalidArgument, "fields must not set in_port, use inPort")
+		}
+	}
+	if packet != "" {
+		packet = strings.TrimPrefix(strings.ToLower(packet), "0x")
+		if _, err := hex.DecodeString(packet); err != nil || len(packet) < 28 {
+			return "", "", errorf(CauseInvalidArgument, "packet must be a hex encoded ethernet frame")
+		}
+	}
+	flow := "in_port=" + inPort
+	if fields != "" {
+		flow += "," + fields
+	}
+	return flow, packet, nil
+}
+
+// TraceFlow 在 bridge 上执行 ofproto/trace，返回经过的流表、命中的流表项和动作、最终的 datapath 动作及丢包原因；
+// 报文经 patch 端口进入的网桥（见 ListAllPatchPorts）由 ovs-vswitchd 一并跟踪，结果中以 hops 标出
+func TraceFlow(ctx context.Context, bridge string, pkt TracePacket) (*FlowTrace, error) {
+	ports, err := bridgeOFPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	flow, packet, err := pkt.traceFlow(ports)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"ofproto/trace", bridge, flow}
+	if packet != "" {
+		args = append(args, packet)
+	}
+	output, err := runOutput(ctx, "ovs-appctl", args...)
+	if err != nil {
+		return nil, err
+	}
+	trace := ParseTrace(string(output))
+	if len(trace.Hops) > 0 {
+		patches, err := ListAllPatchPorts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resolveTraceHops(trace, patches)
+	}
+	return trace, nil
+}
+
+// traceFrame 解析时的嵌套层次：resubmit、patch 端口输出等会在动作下缩进输出被调用的流表
+type traceFrame struct {
+	step         int
+	tableIndent  int
+	actionIndent int // 首个动作行的缩进，-1 表示尚未出现
+}
+
+// ParseTrace 解析 ovs-appctl ofproto/trace 输出，如
+//
+//	bridge("br0")
+//	-------------
+//	 0. ip,in_port=1, priority 100, cookie 0x5
+//	    output:2
+//	 1. No match.
+//	    drop
+//
+// 表项行为 "N. 匹配, priority P, cookie C"，其下缩进的行为动作，"->" 开头为说明，">>" 开头为告警
+func ParseTrace(output string) *FlowTrace {
+	trace := &FlowTrace{Steps: []TraceStep{}, Hops: []TraceHop{}, Raw: output}
+	var stack []traceFrame
+	pendingBridge, lastBridge := "", ""
+	for _, line := range strings.Split(output, "\n") {
+		text := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case text == "" || strings.Trim(text, "-=") == "":
+			continue
+		case indent == 0 && strings.HasPrefix(text, "Flow: "):
+			if trace.Flow == "" {
+				trace.Flow = strings.TrimPrefix(text, "Flow: ")
+			}
+			stack, pendingBridge, lastBridge = nil, "", ""
+			continue
+		case indent == 0 && strings.HasPrefix(text, "Final flow: "):
+			trace.FinalFlow = strings.TrimPrefix(text, "Final flow: ")
+			stack = nil
+			continue
+		case indent == 0 && strings.HasPrefix(text, "Megaflow: "):
+			trace.Megaflow = strings.TrimPrefix(text, "Megaflow: ")
+			continue
+		case indent == 0 && strings.HasPrefix(text, "Datapath actions: "):
+			// 经过 recirc 的跟踪会输出多段结果，以最后一段为准
+			trace.DatapathActions = strings.TrimPrefix(text, "Datapath actions: ")
+			stack = nil
+			continue
+		case strings.HasPrefix(text, `bridge("`) && strings.HasSuffix(text, `")`):
+			pendingBridge = strings.TrimSuffix(strings.TrimPrefix(text, `bridge("`), `")`)
+			continue
+		}
+
+		if table, rest, ok := traceTableLine(text); ok {
+			tableIndent := strings.Index(line, ".") - 2
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				if top.actionIndent >= 0 && top.actionIndent <= tableIndent || top.actionIndent < 0 && top.tableIndent < tableIndent {
+					break
+				}
+				lastBridge = trace.Steps[top.step].Bridge
+				stack = stack[:len(stack)-1]
+			}
+			parent := lastBridge
+			if len(stack) > 0 {
+				parent = trace.Steps[stack[len(stack)-1].step].Bridge
+			}
+			step := parseTraceTable(table, rest)
+			step.Bridge = parent
+			if pendingBridge != "" {
+				step.Bridge, pendingBridge = pendingBridge, ""
+				if parent != "" && parent != step.Bridge {
+					trace.Hops = append(trace.Hops, TraceHop{FromBridge: parent, ToBridge: step.Bridge})
+				}
+			}
+			trace.Steps = append(trace.Steps, step)
+			stack = append(stack, traceFrame{step: len(trace.Steps) - 1, tableIndent: tableIndent, actionIndent: -1})
+			lastBridge = step.Bridge
+			continue
+		}
+
+		note := strings.HasPrefix(text, "->") || strings.HasPrefix(text, ">>")
+		warning := strings.HasPrefix(text, ">>") || strings.HasPrefix(text, "Translation failed")
+		if warning {
+			trace.Warnings = append(trace.Warnings, strings.TrimSpace(strings.Trim(text, "<> ")))
+		}
+		for len(stack) > 1 && stack[len(stack)-1].actionIndent > indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			continue
+		}
+		top := &stack[len(stack)-1]
+		step := &trace.Steps[top.step]
+		if note || warning {
+			step.Notes = append(step.Notes, text)
+			continue
+		}
+		if top.actionIndent < 0 {
+			top.actionIndent = indent
+		}
+		step.Actions = append(step.Actions, text)
+	}
+	trace.Dropped = trace.DatapathActions == "" || trace.DatapathActions == "drop"
+	if trace.Dropped {
+		trace.DropReason = traceDropReason(trace)
+	}
+	return trace
+}
+
+// traceTableLine 识别 "N. ..." 形式的表项行
+func traceTableLine(text string) (int, string, bool) {
+	num, rest, ok := strings.Cut(text, ". ")
+	if !ok {
+		num, ok = strings.CutSuffix(text, ".")
+	}
+	if !ok {
+		return 0, "", false
+	}
+	table, err := strconv.Atoi(num)
+	if err != nil || table < 0 || table > 255 {
+		return 0, "", false
+	}
+	return table, rest, true
+}
+
+// parseTraceTable 解析表项行的匹配、优先级和 cookie
+func parseTraceTable(table int, rest string) TraceStep {
+	step := TraceStep{Table: table, Actions: []string{}}
+	if strings.HasPrefix(rest, "No match") {
+		step.Miss = true
+		step.Notes = append(step.Notes, rest)
+		return step
+	}
+	match, tail, ok := strings.Cut(rest, ", priority ")
+	if !ok {
+		if tail, ok = strings.CutPrefix(rest, "priority "); !ok {
+			// 如 "Packets are IP fragments and the fragment handling mode is "drop"."
+			step.Notes = append(step.Notes, rest)
+			return step
+		}
+		match = ""
+	}
+	step.Match = match
+	priority, cookie, _ := strings.Cut(tail, ", cookie ")
+	if n, err := strconv.Atoi(strings.TrimSpace(priority)); err == nil {
+		step.Priority = &n
+	}
+	if cookie != "" {
+		if v, err := parseUint(strings.TrimSpace(cookie)); err == nil {
+			c := FlowCookie(v)
+			step.Cookie = &c
+		}
+	}
+	return step
+}
+
+// traceDropReason 推断报文被丢弃的原因
+func traceDropReason(trace *FlowTrace) string {
+	if len(trace.Warnings) > 0 {
+		return trace.Warnings[0]
+	}
+	if len(trace.Steps) == 0 {
+		return "no flow table was visited"
+	}
+	last := trace.Steps[len(trace.Steps)-1]
+	if last.Miss {
+		return fmt.Sprintf("no matching flow in table %d of bridge %s", last.Table, last.Bridge)
+	}
+	if len(last.Actions) == 0 || containsString(last.Actions, "drop") {
+		return fmt.Sprintf("flow %q in table %d of bridge %s drops the packet", last.Match, last.Table, last.Bridge)
+	}
+	return "no datapath actions were produced"
+}
+
+// resolveTraceHops 根据 patch 端口列表补全跨网桥跳转经过的端口，优先选择上一跳动作中出现的端口
+func resolveTraceHops(trace *FlowTrace, patches []PatchPortInfo) {
+	owners := make(map[string]string, len(patches))
+	for _, p := range patches {
+		owners[p.Name] = p.Bridge
+	}
+	for i := range trace.Hops {
+		hop := &trace.Hops[i]
+		var candidates []PatchPortInfo
+		for _, p := range patches {
+			if p.Bridge == hop.FromBridge && owners[p.Peer] == hop.ToBridge {
+				candidates = append(candidates, p)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		chosen := candidates[0]
+		for _, p := range candidates {
+			if traceMentionsPort(trace, hop.FromBridge, p.Name) {
+				chosen = p
+				break
+			}
+		}
+		hop.FromPort, hop.ToPort = chosen.Name, chosen.Peer
+	}
+}
+
+// traceMentionsPort 判断 bridge 上的某个表项动作是否按名称输出到 port
+func traceMentionsPort(trace *FlowTrace, bridge, port string) bool {
+	for _, s := range trace.Steps {
+		if s.Bridge != bridge {
+			continue
+		}
+		for _, a := range s.Actions {
+			if a == "output:"+port || a == port {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func loadTrace(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", "trace", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// traceStepSummary 以 "网桥 表号 匹配 -> 动作" 概括一个步骤，便于整体比较
+func traceStepSummary(s TraceStep) string {
+	match := s.Match
+	if s.Miss {
+		match = "<miss>"
+	}
+	return s.Bridge + " " + strconv.Itoa(s.Table) + " " + match + " -> " + strings.Join(s.Actions, " ")
+}
+
+func TestParseTrace(t *testing.T) {
+	tests := []struct {
+		file            string
+		steps           []string
+		hops            []TraceHop
+		finalFlow       string
+		datapathActions string
+		dropReason      string
+		warnings        []string
+	}{
+		{
+			file: "resubmit.txt",
+			steps: []string{
+				"br0 0 in_port=1 -> resubmit(,1)",
+				"br0 1  -> learn(table=10,NXM_OF_VLAN_TCI[0..11],NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],load:NXM_OF_IN_PORT[]->NXM_NX_REG0[0..15]) resubmit(,10) resubmit(,2)",
+				"br0 10 <miss> -> drop",
+				"br0 2 tcp,tp_dst=80 -> output:2",
+			},
+			hops:            []TraceHop{},
+			finalFlow:       "unchanged",
+			datapathActions: "2",
+		},
+		{
+			file: "patch.txt",
+			steps: []string{
+				"br0 0 ip,in_port=1 -> mod_vlan_vid:10 output:3 output:2",
+				"br1 0 in_port=1,dl_vlan=10 -> strip_vlan NORMAL",
+			},
+			hops:            []TraceHop{{FromBridge: "br0", ToBridge: "br1"}},
+			finalFlow:       "ip,in_port=1,dl_vlan=10,dl_vlan_pcp=0,vlan_tci1=0x0000,dl_src=00:00:00:00:00:00,dl_dst=00:00:00:00:00:00,nw_src=10.0.0.1,nw_dst=10.0.0.2,nw_proto=0,nw_tos=0,nw_ecn=0,nw_ttl=0",
+			datapathActions: "4,5,push_vlan(vid=10,pcp=0),2",
+		},
+		{
+			file: "nomatch.txt",
+			steps: []string{
+				"br0 0 in_port=1 -> resubmit(,1)",
+				"br0 1 <miss> -> drop",
+			},
+			hops:            []TraceHop{},
+			finalFlow:       "unchanged",
+			datapathActions: "drop",
+			dropReason:      "no matching flow in table 1 of bridge br0",
+		},
+		{
+			file: "drop_warning.txt",
+			steps: []string{
+				"br0 0 udp,tp_dst=67 -> output:9",
+			},
+			hops:            []TraceHop{},
+			finalFlow:       "unchanged",
+			datapathActions: "drop",
+			dropReason:      "Nonexistent output port",
+			warnings:        []string{"Nonexistent output port"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			output := loadTrace(t, tt.file)
+			trace := ParseTrace(output)
+			var steps []string
+			for _, s := range trace.Steps {
+				steps = append(steps, traceStepSummary(s))
+			}
+			if !reflect.DeepEqual(steps, tt.steps) {
+				t.Errorf("steps =\n%s\nwant\n%s", strings.Join(steps, "\n"), strings.Join(tt.steps, "\n"))
+			}
+			if !reflect.DeepEqual(trace.Hops, tt.hops) {
+				t.Errorf("hops = %+v, want %+v", trace.Hops, tt.hops)
+			}
+			if !strings.HasPrefix(output, "Flow: "+trace.Flow+"\n") || trace.Flow == "" {
+				t.Errorf("flow = %q", trace.Flow)
+			}
+			if trace.FinalFlow != tt.finalFlow || trace.DatapathActions != tt.datapathActions || trace.Megaflow == "" {
+				t.Errorf("final flow = %q, megaflow = %q, datapath actions = %q", trace.FinalFlow, trace.Megaflow, trace.DatapathActions)
+			}
+			if trace.Dropped != (tt.dropReason != "") || trace.DropReason != tt.dropReason {
+				t.Errorf("dropped = %v, reason = %q, want %q", trace.Dropped, trace.DropReason, tt.dropReason)
+			}
+			if !reflect.DeepEqual(trace.Warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", trace.Warnings, tt.warnings)
+			}
+			if trace.Raw != output {
+				t.Error("raw output not kept")
+			}
+		})
+	}
+}
+
+func TestParseTraceStepDetails(t *testing.T) {
+	trace := ParseTrace(loadTrace(t, "resubmit.txt"))
+	if len(trace.Steps) != 4 {
+		t.Fatalf("steps = %d", len(trace.Steps))
+	}
+	first, learn, last := trace.Steps[0], trace.Steps[1], trace.Steps[3]
+	if first.Priority == nil || *first.Priority != 99 || first.Cookie == nil || *first.Cookie != 0x10 {
+		t.Errorf("first step = %+v", first)
+	}
+	if learn.Priority == nil || *learn.Priority != 0 || learn.Cookie != nil {
+		t.Errorf("priority-only step = %+v", learn)
+	}
+	if len(learn.Notes) != 1 || !strings.HasPrefix(learn.Notes[0], "-> table=10 ") {
+		t.Errorf("learn notes = %q", learn.Notes)
+	}
+	if last.Cookie == nil || *last.Cookie != 0xabc {
+		t.Errorf("last step = %+v", last)
+	}
+}
+
+// 截断或无关的输出不应导致崩溃，截断后仍能保留已解析的部分
+func TestParseTraceMalformed(t *testing.T) {
+	inputs := []string{
+		"",
+		"garbage",
+		"ovs-appctl: br9: unknown bridge\n",
+		"   12.\n 300. priority 1\n\t0. x\n    output:1\n",
+		"-> orphan note\n>> orphan warning\n    bridge(\"br1\")\n",
+		" 0. ip, priority\n 1. , cookie 0xzz\n",
+	}
+	for _, name := range []string{"resubmit.txt", "patch.txt", "nomatch.txt", "drop_warning.txt"} {
+		output := loadTrace(t, name)
+		for n := 0; n <= len(output); n++ {
+			inputs = append(inputs, output[:n])
+		}
+	}
+	for _, in := range inputs {
+		trace := ParseTrace(in)
+		if trace.DatapathActions == "" && !trace.Dropped {
+			t.Errorf("ParseTrace(%q): missing datapath actions not reported as dropped", in)
+		}
+	}
+	trace := ParseTrace("garbage")
+	if len(trace.Steps) != 0 || trace.DropReason != "no flow table was visited" {
+		t.Errorf("garbage = %+v", trace)
+	}
+}
@@ -1,12 +1,12 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 )
 
 // AddVxlanPort 添加 VXLAN 端口
-func AddVxlanPortCustom(bridge, portName, remoteIP string, vni int, key, localIP string) error {
+func AddVxlanPortCustom(ctx context.Context, bridge, portName, remoteIP string, vni int, key, localIP string) error {
 	args := []string{"add-port", bridge, portName, "--", "set", "interface", portName, "type=vxlan"}
 	if remoteIP != "" {
 		args = append(args, fmt.Sprintf("options:remote_ip=%s", remoteIP))
@@ -20,6 +20,5 @@ func AddVxlanPortCustom(bridge, portName, remoteIP string, vni int, key, localIP
 	if localIP != "" {
 		args = append(args, fmt.Sprintf("options:local_ip=%s", localIP))
 	}
-	cmd := exec.Command("ovs-vsctl", args...)
-	return cmd.Run()
+	return run(ctx, "ovs-vsctl", args...)
 }
@@ -0,0 +1,189 @@
+package telemetry
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Query 聚合查询参数
+type Query struct {
+	Window  time.Duration // 统计最近多长时间，<=0 为收集器保留时长
+	Limit   int           // 返回条数，<=0 不限制
+	GroupBy string        // top talkers 分组：src（默认）、dst、pair、flow
+	OrderBy string        // bytes（默认）或 packets
+}
+
+// Talker top talkers 的一项
+type Talker struct {
+	SrcAddr string `json:"srcAddr,omitempty"`
+	DstAddr string `json:"dstAddr,omitempty"`
+	SrcPort uint16 `json:"srcPort,omitempty"`
+	DstPort uint16 `json:"dstPort,omitempty"`
+	IPProto uint8  `json:"ipProto,omitempty"`
+	Bytes   uint64 `json:"bytes"`
+	Packets uint64 `json:"packets"`
+	Flows   uint64 `json:"flows"`
+}
+
+// PortTraffic 按端口统计的流量：Rx 为以该端口为入端口的流量，Tx 为以该端口为出端口的流量
+type PortTraffic struct {
+	Port      uint32 `json:"port"`
+	IfIndex   bool   `json:"ifIndex"`
+	RxBytes   uint64 `json:"rxBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	TxBytes   uint64 `json:"txBytes"`
+	TxPackets uint64 `json:"txPackets"`
+}
+
+// VlanTraffic 按 VLAN 统计的流量，VLAN 0 表示未打标签
+type VlanTraffic struct {
+	VLAN    uint16 `json:"vlan"`
+	Bytes   uint64 `json:"bytes"`
+	Packets uint64 `json:"packets"`
+	Flows   uint64 `json:"flows"`
+}
+
+// ValidGroupBy 是否为支持的 top talkers 分组方式
+func ValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case "", "src", "dst", "pair", "flow":
+		return true
+	}
+	return false
+}
+
+// TopTalkers 按 q.GroupBy 聚合最近的流记录，按字节或报文数降序返回
+func (c *Collector) TopTalkers(q Query) []Talker {
+	groups := make(map[string]*Talker)
+	var order []string
+	for _, r := range c.Records(q.Window) {
+		var t Talker
+		switch q.GroupBy {
+		case "dst":
+			t = Talker{DstAddr: r.DstAddr}
+		case "pair":
+			t = Talker{SrcAddr: r.SrcAddr, DstAddr: r.DstAddr}
+		case "flow":
+			t = Talker{SrcAddr: r.SrcAddr, DstAddr: r.DstAddr, SrcPort: r.SrcPort, DstPort: r.DstPort, IPProto: r.IPProto}
+		default:
+			t = Talker{SrcAddr: r.SrcAddr}
+		}
+		key := fmt.Sprintf("%s|%s|%d|%d|%d", t.SrcAddr, t.DstAddr, t.SrcPort, t.DstPort, t.IPProto)
+		g, ok := groups[key]
+		if !ok {
+			g = &t
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.Bytes += r.Bytes
+		g.Packets += r.Packets
+		g.Flows++
+	}
+	res := make([]Talker, 0, len(order))
+	for _, key := range order {
+		res = append(res, *groups[key])
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if q.OrderBy == "packets" {
+			return res[i].Packets > res[j].Packets
+		}
+		return res[i].Bytes > res[j].Bytes
+	})
+	if q.Limit > 0 && len(res) > q.Limit {
+		res = res[:q.Limit]
+	}
+	return res
+}
+
+// PortBreakdown 按入/出端口统计最近的流量，按收发字节总数降序
+func (c *Collector) PortBreakdown(q Query) []PortTraffic {
+	type portKey struct {
+		port    uint32
+		ifIndex bool
+	}
+	ports := make(map[portKey]*PortTraffic)
+	get := func(port uint32, ifIndex bool) *PortTraffic {
+		k := portKey{port, ifIndex}
+		if p, ok := ports[k]; ok {
+			return p
+		}
+		p := &PortTraffic{Port: port, IfIndex: ifIndex}
+		ports[k] = p
+		return p
+	}
+	for _, r := range c.Records(q.Window) {
+		if validPort(r.InPort, r.IfIndex) {
+			p := get(r.InPort, r.IfIndex)
+			p.RxBytes += r.Bytes
+			p.RxPackets += r.Packets
+		}
+		if validPort(r.OutPort, r.IfIndex) {
+			p := get(r.OutPort, r.IfIndex)
+			p.TxBytes += r.Bytes
+			p.TxPackets += r.Packets
+		}
+	}
+	res := make([]PortTraffic, 0, len(ports))
+	for _, p := range ports {
+		res = append(res, *p)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if q.OrderBy == "packets" {
+			if a.RxPackets+a.TxPackets != b.RxPackets+b.TxPackets {
+				return a.RxPackets+a.TxPackets > b.RxPackets+b.TxPackets
+			}
+		} else if a.RxBytes+a.TxBytes != b.RxBytes+b.TxBytes {
+			return a.RxBytes+a.TxBytes > b.RxBytes+b.TxBytes
+		}
+		return a.Port < b.Port
+	})
+	if q.Limit > 0 && len(res) > q.Limit {
+		res = res[:q.Limit]
+	}
+	return res
+}
+
+// VlanBreakdown 按 VLAN 统计最近的流量，按字节或报文数降序
+func (c *Collector) VlanBreakdown(q Query) []VlanTraffic {
+	vlans := make(map[uint16]*VlanTraffic)
+	for _, r := range c.Records(q.Window) {
+		v, ok := vlans[r.VLAN]
+		if !ok {
+			v = &VlanTraffic{VLAN: r.VLAN}
+			vlans[r.VLAN] = v
+		}
+		v.Bytes += r.Bytes
+		v.Packets += r.Packets
+		v.Flows++
+	}
+	res := make([]VlanTraffic, 0, len(vlans))
+	for _, v := range vlans {
+		res = append(res, *v)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if q.OrderBy == "packets" && res[i].Packets != res[j].Packets {
+			return res[i].Packets > res[j].Packets
+		}
+		if q.OrderBy != "packets" && res[i].Bytes != res[j].Bytes {
+			return res[i].Bytes > res[j].Bytes
+		}
+		return res[i].VLAN < res[j].VLAN
+	})
+	if q.Limit > 0 && len(res) > q.Limit {
+		res = res[:q.Limit]
+	}
+	return res
+}
+
+// validPort 过滤未知端口：sFlow 的 0 表示未知；OpenFlow 端口 0 和 0xfff0 以上为保留端口（OVS NetFlow 以 65535 表示无出端口）
+func validPort(port uint32, ifIndex bool) bool {
+	if port == 0 {
+		return false
+	}
+	if ifIndex {
+		return true
+	}
+	return port < 0xfff0 || (port > 0xffff && port < 0xffffff00)
+}
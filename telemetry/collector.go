@@ -0,0 +1,202 @@
+// Package telemetry 实现内嵌的 UDP 流量收集器，解码 OVS 导出的 sFlow v5、NetFlow v5/v9 和 IPFIX 报文，
+// 在内存中保留最近一段时间的流记录，用于统计 top talkers 及按端口/VLAN 的流量分布。
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 默认参数
+const (
+	DefaultWindow       = 5 * time.Minute
+	DefaultMaxRecords   = 100000
+	DefaultMaxTemplates = 4096
+)
+
+// FlowRecord 解码后的一条流记录，字节和报文数已按采样率换算
+type FlowRecord struct {
+	Time     time.Time `json:"time"`
+	Exporter string    `json:"exporter"`
+	Protocol string    `json:"protocol"` // sflow5、netflow5、netflow9、ipfix
+	SrcAddr  string    `json:"srcAddr,omitempty"`
+	DstAddr  string    `json:"dstAddr,omitempty"`
+	SrcPort  uint16    `json:"srcPort,omitempty"`
+	DstPort  uint16    `json:"dstPort,omitempty"`
+	IPProto  uint8     `json:"ipProto,omitempty"`
+	InPort   uint32    `json:"inPort,omitempty"`
+	OutPort  uint32    `json:"outPort,omitempty"`
+	IfIndex  bool      `json:"ifIndex"` // 为 true 时 InPort/OutPort 是内核 ifindex（sFlow），否则是 OpenFlow 端口号
+	VLAN     uint16    `json:"vlan,omitempty"`
+	Bytes    uint64    `json:"bytes"`
+	Packets  uint64    `json:"packets"`
+}
+
+// Stats 收集器计数
+type Stats struct {
+	Datagrams map[string]uint64 `json:"datagrams"` // 按协议统计的报文数
+	Records   uint64            `json:"records"`   // 当前保留的流记录数
+	Errors    uint64            `json:"errors"`    // 解码失败的报文数
+	Templates int               `json:"templates"` // 已学习的 NetFlow v9/IPFIX 模板数，超过上限时淘汰最早学习的模板
+}
+
+// Collector 流量收集器，可并发调用
+type Collector struct {
+	mu            sync.Mutex
+	window        time.Duration
+	maxRecords    int
+	maxTemplates  int
+	records       []FlowRecord
+	templates     map[templateKey]template
+	templateOrder []templateKey // 按学习顺序排列，用于超过 maxTemplates 时淘汰
+	datagrams     map[string]uint64
+	errors        uint64
+	now           func() time.Time
+}
+
+// NewCollector 创建收集器，window 为流记录保留时长，<=0 时使用 DefaultWindow
+func NewCollector(window time.Duration) *Collector {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Collector{
+		window:       window,
+		maxRecords:   DefaultMaxRecords,
+		maxTemplates: DefaultMaxTemplates,
+		templates:    make(map[templateKey]template),
+		datagrams:    make(map[string]uint64),
+		now:          time.Now,
+	}
+}
+
+// Window 流记录保留时长
+func (c *Collector) Window() time.Duration {
+	return c.window
+}
+
+// ListenAndServe 在 UDP 地址上接收报文，直到连接出错
+func (c *Collector) ListenAndServe(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return c.Serve(conn)
+}
+
+// Serve 从 conn 读取报文并解码，单个报文解码失败只计入 Stats.Errors，不记日志也不退出
+func (c *Collector) Serve(conn net.PacketConn) error {
+	defer conn.Close()
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		exporter := ""
+		if udp, ok := from.(*net.UDPAddr); ok {
+			exporter = udp.IP.String()
+		} else if from != nil {
+			exporter = from.String()
+		}
+		c.HandleDatagram(exporter, buf[:n])
+	}
+}
+
+// HandleDatagram 解码一个 UDP 报文（按版本号自动识别协议）并保存其中的流记录，返回记录数；
+// exporter 为发送方地址，用于区分不同交换机的 NetFlow v9/IPFIX 模板
+func (c *Collector) HandleDatagram(exporter string, data []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	var (
+		proto   string
+		records []FlowRecord
+		err     error
+	)
+	switch {
+	case len(data) >= 4 && data[0] == 0 && data[1] == 0 && data[2] == 0 && data[3] == 5:
+		proto = "sflow5"
+		records, err = decodeSFlow(data)
+	case len(data) >= 2 && data[0] == 0 && data[1] == 5:
+		proto = "netflow5"
+		records, err = decodeNetFlow5(data)
+	case len(data) >= 2 && data[0] == 0 && data[1] == 9:
+		proto = "netflow9"
+		records, err = c.decodeNetFlow9(exporter, data)
+	case len(data) >= 2 && data[0] == 0 && data[1] == 10:
+		proto = "ipfix"
+		records, err = c.decodeIPFIX(exporter, data)
+	default:
+		c.errors++
+		return 0, errors.New("unknown datagram format")
+	}
+	c.datagrams[proto]++
+	if err != nil {
+		c.errors++
+		return 0, fmt.Errorf("%s: %w", proto, err)
+	}
+	for i := range records {
+		records[i].Time = now
+		records[i].Exporter = exporter
+		records[i].Protocol = proto
+	}
+	c.records = append(c.records, records...)
+	c.prune(now)
+	return len(records), nil
+}
+
+// prune 丢弃过期记录，超过上限时丢弃最旧的记录；只移动切片起点，
+// 底层数组在 append 扩容时才复制存活的记录，避免每个报文都复制整个窗口
+func (c *Collector) prune(now time.Time) {
+	cutoff := now.Add(-c.window)
+	i := sort.Search(len(c.records), func(i int) bool { return !c.records[i].Time.Before(cutoff) })
+	if over := len(c.records) - i - c.maxRecords; over > 0 {
+		i += over
+	}
+	if i > 0 {
+		clear(c.records[:i])
+		c.records = c.records[i:]
+	}
+}
+
+// storeTemplate 保存学习到的模板，模板数达到上限时淘汰最早学习的模板
+func (c *Collector) storeTemplate(key templateKey, tmpl template) {
+	if _, ok := c.templates[key]; !ok {
+		if len(c.templateOrder) >= c.maxTemplates {
+			delete(c.templates, c.templateOrder[0])
+			c.templateOrder = c.templateOrder[1:]
+		}
+		c.templateOrder = append(c.templateOrder, key)
+	}
+	c.templates[key] = tmpl
+}
+
+// Stats 返回收集器计数
+func (c *Collector) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune(c.now())
+	s := Stats{Datagrams: make(map[string]uint64), Records: uint64(len(c.records)), Errors: c.errors, Templates: len(c.templates)}
+	for k, v := range c.datagrams {
+		s.Datagrams[k] = v
+	}
+	return s
+}
+
+// Records 返回最近 window 内的流记录副本，window<=0 时使用收集器的保留时长
+func (c *Collector) Records(window time.Duration) []FlowRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.prune(now)
+	if window <= 0 || window > c.window {
+		window = c.window
+	}
+	cutoff := now.Add(-window)
+	i := sort.Search(len(c.records), func(i int) bool { return !c.records[i].Time.Before(cutoff) })
+	return append([]FlowRecord{}, c.records[i:]...)
+}
@@ -0,0 +1,231 @@
+package telemetry
+
+import (
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 测试报文按 OVS 导出格式构造，字段说明见各常量注释
+const (
+	// sFlow v5，agent 10.0.0.1：一个流样本（采样率 64，ifindex 3 -> 5，802.1Q VLAN 100 上
+	// 192.168.1.10:12345 -> 192.168.1.20:80 的 TCP 帧，帧长 1518，附 extended switch 记录）
+	// 和一个无记录的计数样本
+	sflowPacket = "00000005000000010a000001000000000000002a0001e24000000002" +
+		"000000010000008c0000000700000003000000400000190000000000000000030000000500000002" +
+		"000000010000004c00000001000005ee000000040000003a525400000002525400000001810000640800450005dc1c46400040060000c0a8010ac0a8011430390050000000000000000000000000000000000000" +
+		"000003e90000001000000064000000000000006400000000" +
+		"000000020000000c000000080000000300000000"
+	// NetFlow v5，采样间隔 10：10.1.1.1:5353 -> 10.1.1.2:53 UDP（3 包 4500 字节，端口 1 -> 2）
+	// 和 10.1.1.3:40000 -> 10.1.1.4:443 TCP（1 包 60 字节，端口 2 -> 1）
+	netflow5Packet = "00050002000186a06553f10000000000000000070000400a" +
+		"0a0101010a01010200000000000100020000000300001194000000000000000014e90035000011000000000000000000" +
+		"0a0101030a0101040000000000020001000000010000003c00000000000000009c4001bb001206000000000000000000"
+	// NetFlow v9，source id 0x0102：模板 256（v4 地址、端口、协议、字节、包、输入/输出接口、VLAN）
+	// 及一条 172.16.0.1:33000 -> 172.16.0.2:22 TCP（20 包 8000 字节，端口 1 -> 2，VLAN 200）的数据
+	netflow9Packet = "00090002000186a06553f1000000000100000102" +
+		"000000300100000a00080004000c000400070002000b0002000400010001000400020004000a0002000e0002003a0002" +
+		"01000020ac100001ac10000280e800160600001f40000000140001000200c800"
+	// NetFlow v9，只含模板 256 的数据
+	netflow9DataPacket = "00090001000187046553f101000000020000010201000020ac100001ac10000280e800160600001f40000000140001000200c800"
+	// IPFIX，observation domain 5：模板 257（含 8 字节计数器和一个 VMware 企业字段）
+	// 及一条 192.0.2.1:5000 -> 198.51.100.7:4789 UDP（100 包 150000 字节，端口 4 -> 7，VLAN 300）的数据
+	ipfixPacket = "000a00746553f1000000000900000005" +
+		"000200380101000b00080004000c000400070002000b0002000400010055000800560008000a0004000e000400f30002837b000100001adc" +
+		"0101002cc0000201c6336407138812b51100000000000249f000000000000000640000000400000007012c01"
+)
+
+func packet(t testing.TB, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var testNow = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestCollector() *Collector {
+	c := NewCollector(time.Minute)
+	c.now = func() time.Time { return testNow }
+	return c
+}
+
+func TestHandleDatagram(t *testing.T) {
+	tests := []struct {
+		name    string
+		packets []string
+		want    []FlowRecord
+	}{
+		{"sflow5", []string{sflowPacket}, []FlowRecord{
+			{SrcAddr: "192.168.1.10", DstAddr: "192.168.1.20", SrcPort: 12345, DstPort: 80, IPProto: 6,
+				InPort: 3, OutPort: 5, IfIndex: true, VLAN: 100, Bytes: 1518 * 64, Packets: 64},
+		}},
+		{"netflow5", []string{netflow5Packet}, []FlowRecord{
+			{SrcAddr: "10.1.1.1", DstAddr: "10.1.1.2", SrcPort: 5353, DstPort: 53, IPProto: 17,
+				InPort: 1, OutPort: 2, Bytes: 45000, Packets: 30},
+			{SrcAddr: "10.1.1.3", DstAddr: "10.1.1.4", SrcPort: 40000, DstPort: 443, IPProto: 6,
+				InPort: 2, OutPort: 1, Bytes: 600, Packets: 10},
+		}},
+		{"netflow9", []string{netflow9Packet}, []FlowRecord{
+			{SrcAddr: "172.16.0.1", DstAddr: "172.16.0.2", SrcPort: 33000, DstPort: 22, IPProto: 6,
+				InPort: 1, OutPort: 2, VLAN: 200, Bytes: 8000, Packets: 20},
+		}},
+		{"ipfix", []string{ipfixPacket}, []FlowRecord{
+			{SrcAddr: "192.0.2.1", DstAddr: "198.51.100.7", SrcPort: 5000, DstPort: 4789, IPProto: 17,
+				InPort: 4, OutPort: 7, VLAN: 300, Bytes: 150000, Packets: 100},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector()
+			for _, p := range tt.packets {
+				if _, err := c.HandleDatagram("10.0.0.1", packet(t, p)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := range tt.want {
+				tt.want[i].Time = testNow
+				tt.want[i].Exporter = "10.0.0.1"
+				tt.want[i].Protocol = tt.name
+			}
+			if got := c.Records(0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetFlow9DataBeforeTemplate(t *testing.T) {
+	c := newTestCollector()
+	// 模板未到达前的数据被跳过，不算解码错误
+	if n, err := c.HandleDatagram("10.0.0.1", packet(t, netflow9DataPacket)); n != 0 || err != nil {
+		t.Fatalf("data before template = %d, %v", n, err)
+	}
+	c.HandleDatagram("10.0.0.1", packet(t, netflow9Packet))
+	if n, err := c.HandleDatagram("10.0.0.1", packet(t, netflow9DataPacket)); n != 1 || err != nil {
+		t.Errorf("data after template = %d, %v", n, err)
+	}
+	// 模板按导出方区分
+	if n, _ := c.HandleDatagram("10.0.0.2", packet(t, netflow9DataPacket)); n != 0 {
+		t.Errorf("template of 10.0.0.1 used for 10.0.0.2")
+	}
+	if s := c.Stats(); s.Errors != 0 || s.Datagrams["netflow9"] != 4 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestTruncatedDatagrams(t *testing.T) {
+	for _, p := range []string{sflowPacket, netflow5Packet, netflow9Packet, ipfixPacket} {
+		data := packet(t, p)
+		c := newTestCollector()
+		var failed uint64
+		for n := 0; n < len(data); n++ {
+			if _, err := c.HandleDatagram("10.0.0.1", append([]byte{}, data[:n]...)); err != nil {
+				failed++
+			} else if n == len(data)-1 {
+				t.Errorf("%x...: truncated by one byte decoded without error", data[:4])
+			}
+		}
+		if s := c.Stats(); s.Errors != failed {
+			t.Errorf("%x...: Stats.Errors = %d, want %d", data[:4], s.Errors, failed)
+		}
+	}
+}
+
+func TestTemplateLimit(t *testing.T) {
+	c := newTestCollector()
+	c.maxTemplates = 2
+	withDomain := func(s string, domain byte) []byte {
+		b := packet(t, s)
+		b[19] = domain
+		return b
+	}
+	for d := byte(1); d <= 3; d++ {
+		c.HandleDatagram("10.0.0.1", withDomain(netflow9Packet, d))
+	}
+	if s := c.Stats(); s.Templates != 2 {
+		t.Errorf("Templates = %d, want 2", s.Templates)
+	}
+	if n, _ := c.HandleDatagram("10.0.0.1", withDomain(netflow9DataPacket, 1)); n != 0 {
+		t.Error("oldest template was not evicted")
+	}
+	if n, _ := c.HandleDatagram("10.0.0.1", withDomain(netflow9DataPacket, 3)); n != 1 {
+		t.Error("newest template was evicted")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := newTestCollector()
+	now := testNow
+	c.now = func() time.Time { return now }
+	nf5 := packet(t, netflow5Packet)
+	c.HandleDatagram("10.0.0.1", nf5)
+	now = now.Add(30 * time.Second)
+	c.HandleDatagram("10.0.0.1", nf5)
+	if n := len(c.Records(0)); n != 4 {
+		t.Fatalf("records = %d, want 4", n)
+	}
+	if n := len(c.Records(10 * time.Second)); n != 2 {
+		t.Errorf("records in last 10s = %d, want 2", n)
+	}
+	now = now.Add(45 * time.Second)
+	if s := c.Stats(); s.Records != 2 {
+		t.Errorf("records after first batch expired = %d, want 2", s.Records)
+	}
+	c.maxRecords = 3
+	c.HandleDatagram("10.0.0.1", nf5)
+	got := c.Records(0)
+	if len(got) != 3 || !got[0].Time.Equal(testNow.Add(30*time.Second)) || got[0].DstPort != 443 {
+		t.Errorf("records over limit = %+v", got)
+	}
+}
+
+func TestServeCountsErrors(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	c := NewCollector(time.Minute)
+	go c.Serve(conn)
+	defer conn.Close()
+	out, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	out.Write([]byte("garbage"))
+	out.Write(packet(t, netflow5Packet))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s := c.Stats()
+		if s.Errors == 1 && s.Records == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stats = %+v", s)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// BenchmarkHandleDatagram 窗口已满、每个报文都会淘汰旧记录时的处理开销
+func BenchmarkHandleDatagram(b *testing.B) {
+	c := NewCollector(time.Second)
+	now := testNow
+	c.now = func() time.Time { return now }
+	nf5 := packet(b, netflow5Packet)
+	for i := 0; i < 10000; i++ {
+		now = now.Add(100 * time.Microsecond)
+		c.HandleDatagram("10.0.0.1", nf5)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		now = now.Add(100 * time.Microsecond)
+		c.HandleDatagram("10.0.0.1", nf5)
+	}
+}
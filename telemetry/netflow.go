@@ -0,0 +1,252 @@
+package telemetry
+
+import (
+	"fmt"
+)
+
+// NetFlow v9 / IPFIX 字段（信息元素）编号
+const (
+	fieldOctets        = 1
+	fieldPackets       = 2
+	fieldProtocol      = 4
+	fieldSrcPort       = 7
+	fieldSrcIPv4       = 8
+	fieldInputIf       = 10
+	fieldDstPort       = 11
+	fieldDstIPv4       = 12
+	fieldOutputIf      = 14
+	fieldSrcIPv6       = 27
+	fieldDstIPv6       = 28
+	fieldSampling      = 34
+	fieldVlanID        = 58
+	fieldOctetTotal    = 85
+	fieldPacketTotal   = 86
+	fieldDot1qVlanID   = 243
+	fieldSamplingIntvl = 305
+)
+
+// templateKey 模板按导出方、协议版本、source id/observation domain 和模板号区分
+type templateKey struct {
+	exporter string
+	version  uint16
+	domain   uint32
+	id       uint16
+}
+
+// templateField 模板中的一个字段，length 为 0xffff 表示变长
+type templateField struct {
+	id         uint16
+	length     uint16
+	enterprise bool
+}
+
+type template []templateField
+
+// decodeNetFlow5 解码 NetFlow v5 报文，固定 24 字节头和 48 字节记录
+func decodeNetFlow5(data []byte) ([]FlowRecord, error) {
+	r := &reader{buf: data}
+	r.u16() // version
+	count := int(r.u16())
+	r.bytes(18) // uptime, secs, nsecs, sequence, engine type/id
+	interval := uint64(r.u16() & 0x3fff)
+	if interval == 0 {
+		interval = 1
+	}
+	if r.err != nil || r.remaining() < count*48 {
+		return nil, errShort
+	}
+	records := make([]FlowRecord, 0, count)
+	for i := 0; i < count; i++ {
+		src, dst := r.bytes(4), r.bytes(4)
+		r.bytes(4) // nexthop
+		in, out := r.u16(), r.u16()
+		pkts, octets := r.u32(), r.u32()
+		r.bytes(8) // first, last
+		sport, dport := r.u16(), r.u16()
+		r.u8()
+		r.u8() // tcp flags
+		proto := r.u8()
+		r.bytes(9) // tos, as, mask, pad
+		records = append(records, FlowRecord{
+			SrcAddr: ipString(src), DstAddr: ipString(dst),
+			SrcPort: sport, DstPort: dport, IPProto: proto,
+			InPort: uint32(in), OutPort: uint32(out),
+			Bytes: uint64(octets) * interval, Packets: uint64(pkts) * interval,
+		})
+	}
+	return records, r.err
+}
+
+// decodeNetFlow9 解码 NetFlow v9 报文：flowset 0 为模板，1 为选项模板（忽略），>=256 为数据
+func (c *Collector) decodeNetFlow9(exporter string, data []byte) ([]FlowRecord, error) {
+	r := &reader{buf: data}
+	r.u16() // version
+	r.u16() // count
+	r.bytes(12)
+	domain := r.u32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	var records []FlowRecord
+	for r.remaining() >= 4 {
+		id := r.u16()
+		length := int(r.u16())
+		if length < 4 {
+			return records, fmt.Errorf("invalid flowset length %d", length)
+		}
+		set := r.sub(length - 4)
+		if r.err != nil {
+			return records, r.err
+		}
+		switch {
+		case id == 0:
+			for set.remaining() >= 4 {
+				tid := set.u16()
+				n := int(set.u16())
+				tmpl := make(template, 0, n)
+				for j := 0; j < n; j++ {
+					tmpl = append(tmpl, templateField{id: set.u16(), length: set.u16()})
+				}
+				if set.err != nil {
+					return records, set.err
+				}
+				c.storeTemplate(templateKey{exporter, 9, domain, tid}, tmpl)
+			}
+		case id >= 256:
+			tmpl, ok := c.templates[templateKey{exporter, 9, domain, id}]
+			if !ok {
+				continue
+			}
+			records = append(records, decodeDataSet(set, tmpl)...)
+		}
+	}
+	return records, nil
+}
+
+// decodeIPFIX 解码 IPFIX 报文：set 2 为模板，3 为选项模板（忽略），>=256 为数据
+func (c *Collector) decodeIPFIX(exporter string, data []byte) ([]FlowRecord, error) {
+	r := &reader{buf: data}
+	r.u16() // version
+	total := int(r.u16())
+	r.bytes(8) // export time, sequence
+	domain := r.u32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if total < 16 || total > len(data) {
+		return nil, fmt.Errorf("invalid message length %d", total)
+	}
+	r.buf = r.buf[:total]
+	var records []FlowRecord
+	for r.remaining() >= 4 {
+		id := r.u16()
+		length := int(r.u16())
+		if length < 4 {
+			return records, fmt.Errorf("invalid set length %d", length)
+		}
+		set := r.sub(length - 4)
+		if r.err != nil {
+			return records, r.err
+		}
+		switch {
+		case id == 2:
+			for set.remaining() >= 4 {
+				tid := set.u16()
+				n := int(set.u16())
+				tmpl := make(template, 0, n)
+				for j := 0; j < n; j++ {
+					f := templateField{id: set.u16(), length: set.u16()}
+					if f.id&0x8000 != 0 {
+						f.id &= 0x7fff
+						f.enterprise = true
+						set.u32() // enterprise number
+					}
+					tmpl = append(tmpl, f)
+				}
+				if set.err != nil {
+					return records, set.err
+				}
+				c.storeTemplate(templateKey{exporter, 10, domain, tid}, tmpl)
+			}
+		case id >= 256:
+			tmpl, ok := c.templates[templateKey{exporter, 10, domain, id}]
+			if !ok {
+				continue
+			}
+			records = append(records, decodeDataSet(set, tmpl)...)
+		}
+	}
+	return records, nil
+}
+
+// decodeDataSet 按模板解码数据 set 中的所有记录，末尾不足一条记录的部分为填充
+func decodeDataSet(set *reader, tmpl template) []FlowRecord {
+	var records []FlowRecord
+	for set.remaining() > 0 {
+		start := set.off
+		var rec FlowRecord
+		var totalOctets, totalPackets, sampling uint64
+		for _, f := range tmpl {
+			length := int(f.length)
+			if f.length == 0xffff {
+				length = int(set.u8())
+				if length == 255 {
+					length = int(set.u16())
+				}
+			}
+			b := set.bytes(length)
+			if set.err != nil {
+				break
+			}
+			if f.enterprise {
+				continue
+			}
+			switch f.id {
+			case fieldOctets:
+				rec.Bytes = uintN(b)
+			case fieldPackets:
+				rec.Packets = uintN(b)
+			case fieldOctetTotal:
+				totalOctets = uintN(b)
+			case fieldPacketTotal:
+				totalPackets = uintN(b)
+			case fieldProtocol:
+				rec.IPProto = uint8(uintN(b))
+			case fieldSrcPort:
+				rec.SrcPort = uint16(uintN(b))
+			case fieldDstPort:
+				rec.DstPort = uint16(uintN(b))
+			case fieldSrcIPv4, fieldSrcIPv6:
+				rec.SrcAddr = ipString(b)
+			case fieldDstIPv4, fieldDstIPv6:
+				rec.DstAddr = ipString(b)
+			case fieldInputIf:
+				rec.InPort = uint32(uintN(b))
+			case fieldOutputIf:
+				rec.OutPort = uint32(uintN(b))
+			case fieldVlanID, fieldDot1qVlanID:
+				if v := uint16(uintN(b)) & 0x0fff; v != 0 {
+					rec.VLAN = v
+				}
+			case fieldSampling, fieldSamplingIntvl:
+				sampling = uintN(b)
+			}
+		}
+		if set.err != nil || set.off == start {
+			// 剩余字节不足一条记录，视为填充
+			break
+		}
+		if rec.Bytes == 0 {
+			rec.Bytes = totalOctets
+		}
+		if rec.Packets == 0 {
+			rec.Packets = totalPackets
+		}
+		if sampling > 1 {
+			rec.Bytes *= sampling
+			rec.Packets *= sampling
+		}
+		records = append(records, rec)
+	}
+	return records
+}
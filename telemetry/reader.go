@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// errShort 报文长度不足
+var errShort = errors.New("truncated datagram")
+
+// reader 按网络字节序顺序读取报文，越界后所有读取返回零值并记录 errShort
+type reader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *reader) remaining() int {
+	return len(r.buf) - r.off
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.remaining() < n {
+		r.err = errShort
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// sub 截取接下来 n 字节作为独立 reader
+func (r *reader) sub(n int) *reader {
+	b := r.bytes(n)
+	if b == nil {
+		return &reader{err: r.err}
+	}
+	return &reader{buf: b}
+}
+
+// uintN 将 1-8 字节大端整数转换为 uint64
+func uintN(b []byte) uint64 {
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v
+}
+
+// ipString 4 或 16 字节地址转换为字符串
+func ipString(b []byte) string {
+	if len(b) != 4 && len(b) != 16 {
+		return ""
+	}
+	return net.IP(append([]byte{}, b...)).String()
+}
@@ -0,0 +1,183 @@
+package telemetry
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// sFlow v5 样本和记录类型（企业号 0）
+const (
+	sflowFlowSample         = 1
+	sflowExpandedFlowSample = 3
+	sflowRawHeader          = 1
+	sflowIPv4Data           = 3
+	sflowIPv6Data           = 4
+	sflowExtendedSwitch     = 1001
+	sflowHeaderEthernet     = 1
+)
+
+// decodeSFlow 解码 sFlow v5 报文中的流样本，计数样本忽略
+func decodeSFlow(data []byte) ([]FlowRecord, error) {
+	r := &reader{buf: data}
+	r.u32() // version
+	switch r.u32() {
+	case 1:
+		r.bytes(4)
+	case 2:
+		r.bytes(16)
+	default:
+		return nil, fmt.Errorf("unsupported agent address type")
+	}
+	r.u32() // sub agent id
+	r.u32() // sequence
+	r.u32() // uptime
+	n := int(r.u32())
+	var records []FlowRecord
+	for i := 0; i < n && r.err == nil; i++ {
+		format := r.u32()
+		sample := r.sub(int(r.u32()))
+		if format>>12 != 0 {
+			continue
+		}
+		switch format & 0xfff {
+		case sflowFlowSample, sflowExpandedFlowSample:
+			if rec, ok := decodeSFlowSample(sample, format&0xfff == sflowExpandedFlowSample); ok {
+				records = append(records, rec)
+			}
+		}
+		if sample.err != nil {
+			return records, sample.err
+		}
+	}
+	return records, r.err
+}
+
+// decodeSFlowSample 解码一个（扩展）流样本，记录的字节和报文数按采样率放大
+func decodeSFlowSample(r *reader, expanded bool) (FlowRecord, bool) {
+	rec := FlowRecord{IfIndex: true}
+	r.u32() // sequence
+	if expanded {
+		r.u32() // source id type
+		r.u32() // source id index
+	} else {
+		r.u32()
+	}
+	rate := r.u32()
+	r.u32() // sample pool
+	r.u32() // drops
+	if expanded {
+		if r.u32() == 0 {
+			rec.InPort = r.u32()
+		} else {
+			r.u32()
+		}
+		if r.u32() == 0 {
+			rec.OutPort = r.u32()
+		} else {
+			r.u32()
+		}
+	} else {
+		// 高 2 位为格式，0 表示单个接口
+		if in := r.u32(); in>>30 == 0 {
+			rec.InPort = in
+		}
+		if out := r.u32(); out>>30 == 0 {
+			rec.OutPort = out
+		}
+	}
+	if rate == 0 {
+		rate = 1
+	}
+	rec.Packets = uint64(rate)
+	found := false
+	n := int(r.u32())
+	for i := 0; i < n && r.err == nil; i++ {
+		format := r.u32()
+		data := r.sub(int(r.u32()))
+		if format>>12 != 0 {
+			continue
+		}
+		switch format & 0xfff {
+		case sflowRawHeader:
+			proto := data.u32()
+			frameLen := data.u32()
+			data.u32() // stripped
+			header := data.bytes(int(data.u32()))
+			rec.Bytes = uint64(frameLen) * uint64(rate)
+			if proto == sflowHeaderEthernet && data.err == nil {
+				parseEthernet(header, &rec)
+			}
+			found = true
+		case sflowIPv4Data, sflowIPv6Data:
+			length := data.u32()
+			proto := data.u32()
+			addrLen := 4
+			if format&0xfff == sflowIPv6Data {
+				addrLen = 16
+			}
+			src, dst := data.bytes(addrLen), data.bytes(addrLen)
+			sport, dport := data.u32(), data.u32()
+			if data.err == nil && rec.SrcAddr == "" {
+				rec.SrcAddr, rec.DstAddr = ipString(src), ipString(dst)
+				rec.IPProto = uint8(proto)
+				rec.SrcPort, rec.DstPort = uint16(sport), uint16(dport)
+				if rec.Bytes == 0 {
+					rec.Bytes = uint64(length) * uint64(rate)
+				}
+			}
+			found = true
+		case sflowExtendedSwitch:
+			if vlan := data.u32(); vlan != 0 && data.err == nil {
+				rec.VLAN = uint16(vlan)
+			}
+		}
+	}
+	return rec, found && r.err == nil
+}
+
+// parseEthernet 从以太网帧头解析 VLAN、IP 地址和四层端口；已有 VLAN（来自 extended switch）时不覆盖
+func parseEthernet(b []byte, rec *FlowRecord) {
+	if len(b) < 14 {
+		return
+	}
+	ethType := binary.BigEndian.Uint16(b[12:14])
+	off := 14
+	for (ethType == 0x8100 || ethType == 0x88a8) && len(b) >= off+4 {
+		if rec.VLAN == 0 {
+			rec.VLAN = binary.BigEndian.Uint16(b[off:off+2]) & 0x0fff
+		}
+		ethType = binary.BigEndian.Uint16(b[off+2 : off+4])
+		off += 4
+	}
+	ip := b[off:]
+	var l4 []byte
+	switch ethType {
+	case 0x0800:
+		if len(ip) < 20 {
+			return
+		}
+		ihl := int(ip[0]&0x0f) * 4
+		rec.IPProto = ip[9]
+		rec.SrcAddr, rec.DstAddr = ipString(ip[12:16]), ipString(ip[16:20])
+		// 非首分片没有四层头
+		if binary.BigEndian.Uint16(ip[6:8])&0x1fff == 0 && len(ip) >= ihl {
+			l4 = ip[ihl:]
+		}
+	case 0x86dd:
+		if len(ip) < 40 {
+			return
+		}
+		rec.IPProto = ip[6]
+		rec.SrcAddr, rec.DstAddr = ipString(ip[8:24]), ipString(ip[24:40])
+		l4 = ip[40:]
+	default:
+		return
+	}
+	switch rec.IPProto {
+	case 6, 17, 132:
+		if len(l4) >= 4 {
+			rec.SrcPort = binary.BigEndian.Uint16(l4[0:2])
+			rec.DstPort = binary.BigEndian.Uint16(l4[2:4])
+		}
+	}
+}